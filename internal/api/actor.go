@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// Cada sala tiene una goroutine que es la única que lee o modifica su estado.
+// El resto del código le manda funciones con do y espera a que se ejecuten, en
+// el orden en que llegaron. Las funciones que corren en la sala no deben llamar
+// a do (se bloquearían) ni esperar a la red o a la base de datos: para guardar
+// se copia el estado con snapshot y se guarda afuera.
+
+var errRoomClosed = errors.New("room closed")
+
+// Tiempo sin cambios tras el cual se guarda la sala
+var autosaveDelay = 5 * time.Minute
+
+func (r *RoomData) start() {
+	go r.run()
+}
+
+func (r *RoomData) run() {
+	for {
+		select {
+		case fn := <-r.commands:
+			fn()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// stop termina la goroutine de la sala. Los do pendientes devuelven errRoomClosed
+func (r *RoomData) stop() {
+	r.stopOnce.Do(func() {
+		close(r.quit)
+	})
+}
+
+// stopped indica si la sala ya no acepta comandos
+func (r *RoomData) stopped() bool {
+	select {
+	case <-r.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// do ejecuta fn en la goroutine de la sala y espera a que termine. Si fn entra
+// en pánico, el pánico se repite en quien llamó para no tirar la sala
+func (r *RoomData) do(fn func()) error {
+	done := make(chan interface{}, 1)
+
+	closed := false
+	cmd := func() {
+		defer func() {
+			done <- recover()
+		}()
+		// La sala pudo detenerse mientras el comando esperaba, ver closeIfIdle
+		if r.stopped() {
+			closed = true
+			return
+		}
+		fn()
+	}
+
+	select {
+	case r.commands <- cmd:
+	case <-r.quit:
+		return errRoomClosed
+	}
+
+	// Una vez recibido el comando siempre se ejecuta, aunque fn detenga la sala
+	if p := <-done; p != nil {
+		panic(p)
+	}
+	if closed {
+		return errRoomClosed
+	}
+	return nil
+}
+
+// snapshot copia el estado de la sala para guardarlo fuera de su goroutine
+func (r *RoomData) snapshot() *models.Project {
+	p := r.toProject()
+	return p.Clone()
+}
+
+// stopRoom deja de aceptar comandos y devuelve el último estado de la sala
+// para guardarlo. Se llama desde la goroutine de la sala, así ningún cambio
+// queda fuera de la copia
+func (r *RoomData) stopRoom() *models.Project {
+	if r.saveTimer != nil {
+		r.saveTimer.Stop()
+	}
+	r.stop()
+	return r.snapshot()
+}
+
+// closeIfIdle guarda y cierra la sala si no quedan usuarios conectados. Se usa
+// al salir el último usuario y después de los pedidos REST que abren la sala
+func (a *API) closeIfIdle(roomID string, room *RoomData, author string) {
+	var snapshot *models.Project
+	err := room.do(func() {
+		if len(room.Active) == 0 {
+			snapshot = room.stopRoom()
+		}
+	})
+	if err != nil || snapshot == nil {
+		return
+	}
+
+	// Si no se pudo guardar, al volver a abrirla se repite el registro de operaciones
+	if err := a.saveWithRevision(*snapshot, author, models.RevisionClose); err != nil {
+		log.Println("Error al guardar la sala:", err)
+	} else {
+		log.Println("Project saved: ", roomID)
+	}
+
+	a.dropRoom(roomID, room)
+}
+
+// dropRoom saca de memoria una sala detenida y suelta su lease. Quien estaba
+// esperando para abrirla de nuevo (ver instanceRoom) ya puede hacerlo
+func (a *API) dropRoom(roomID string, room *RoomData) {
+	rooms.CompareAndDelete(roomID, room)
+	if err := a.repo.ReleaseRoomLease(context.Background(), roomID, a.instanceID); err != nil {
+		log.Println("Error soltando la sala: ", err)
+	}
+	room.closeOnce.Do(func() {
+		close(room.closed)
+	})
+}
+
+// activeUsers devuelve los usuarios conectados
+func (r *RoomData) activeUsers() []map[string]string {
+	users := make([]map[string]string, 0, len(r.Active))
+	for _, userConn := range r.Active {
+		users = append(users, map[string]string{
+			"email":   userConn.Email,
+			"editing": userConn.Editing,
+			"color":   userConn.Color,
+		})
+	}
+	return users
+}
+
+// autosave cuenta una edición. Se guarda al llegar a roomActionsThreshold
+// ediciones o tras autosaveDelay sin cambios
+func (a *API) autosave(proyect *RoomData, user string) {
+	proyect.actionsCounter++
+
+	if proyect.actionsCounter >= roomActionsThreshold {
+		proyect.actionsCounter = 0
+		go a.saveProject(proyect.snapshot(), user, models.RevisionAutosave)
+	}
+
+	if proyect.saveTimer != nil {
+		proyect.saveTimer.Stop()
+	}
+
+	proyect.saveTimer = time.AfterFunc(autosaveDelay, func() {
+		var snapshot *models.Project
+		err := proyect.do(func() {
+			proyect.actionsCounter = 0
+			snapshot = proyect.snapshot()
+		})
+		if err != nil {
+			return
+		}
+
+		a.saveProject(snapshot, user, models.RevisionAutosave)
+	})
+}
+
+// saveProject guarda una copia de la sala y registra el error si falla
+func (a *API) saveProject(project *models.Project, author string, reason string) {
+	if err := a.saveWithRevision(*project, author, reason); err != nil {
+		log.Println("Error guardando el proyecto: ", err)
+		return
+	}
+	log.Println("Proyecto guardado: ", project.ID)
+}
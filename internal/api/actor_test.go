@@ -0,0 +1,240 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+func startedRoom(t *testing.T) *RoomData {
+	t.Helper()
+
+	room := newRoomData(&models.Project{})
+	room.start()
+	t.Cleanup(room.stop)
+	return room
+}
+
+func TestDoRunsInOrder(t *testing.T) {
+	room := startedRoom(t)
+
+	var wg sync.WaitGroup
+	count := 0
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := room.do(func() { count++ }); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var got int
+	room.do(func() { got = count })
+	if got != 100 {
+		t.Errorf("count = %d, want 100", got)
+	}
+}
+
+func TestDoRepanics(t *testing.T) {
+	room := startedRoom(t)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recover() = %v, want boom", r)
+			}
+		}()
+		room.do(func() { panic("boom") })
+	}()
+
+	// La sala sigue funcionando
+	if err := room.do(func() {}); err != nil {
+		t.Errorf("do() after panic = %v", err)
+	}
+}
+
+func TestDoOnStoppedRoom(t *testing.T) {
+	room := startedRoom(t)
+	room.stop()
+
+	ran := false
+	if err := room.do(func() { ran = true }); !errors.Is(err, errRoomClosed) {
+		t.Errorf("do() = %v, want %v", err, errRoomClosed)
+	}
+	if ran {
+		t.Error("fn ran on a stopped room")
+	}
+}
+
+func TestDoStopsInside(t *testing.T) {
+	room := startedRoom(t)
+
+	// Los comandos que esperan mientras la sala se detiene no se ejecutan
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	after := 0
+
+	var snapshot *models.Project
+	err := room.do(func() {
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if room.do(func() { after++ }) == nil {
+					mu.Lock()
+					defer mu.Unlock()
+					t.Error("do() succeeded after the room stopped")
+				}
+			}()
+		}
+		time.Sleep(10 * time.Millisecond)
+		snapshot = room.stopRoom()
+	})
+	wg.Wait()
+
+	if err != nil {
+		t.Fatalf("do() = %v, want the command that stops the room to finish", err)
+	}
+	if snapshot == nil {
+		t.Fatal("no snapshot")
+	}
+	if after != 0 {
+		t.Errorf("%d commands ran after stopRoom", after)
+	}
+}
+
+func TestAutosaveOnStoppedRoom(t *testing.T) {
+	repo := newFakeRepo()
+	a := newTestAPI(t, repo, "a")
+	room := startedRoom(t)
+
+	delay := autosaveDelay
+	autosaveDelay = 10 * time.Millisecond
+	defer func() { autosaveDelay = delay }()
+
+	room.do(func() { a.autosave(room, "a@test.com") })
+	room.stop()
+
+	time.Sleep(50 * time.Millisecond)
+	if n := repo.saveCount(); n != 0 {
+		t.Errorf("saved %d times after the room stopped", n)
+	}
+}
+
+func TestPresenceOnStoppedRoom(t *testing.T) {
+	room := startedRoom(t)
+
+	interval := presenceInterval
+	presenceInterval = 20 * time.Millisecond
+	defer func() { presenceInterval = interval }()
+
+	conn := "c1"
+	room.do(func() {
+		room.Active[conn] = &UserConnection{Email: "a@test.com", Presence: &presence{sentAt: time.Now()}}
+		if err := room.setPresence(conn, []byte(`{}`)); err != nil {
+			t.Error(err)
+		}
+	})
+	room.stop()
+
+	// El envío pendiente no debe bloquearse ni entrar en pánico
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestCloseIfIdle(t *testing.T) {
+	repo := newFakeRepo()
+	a := newTestAPI(t, repo, "a")
+	roomID := repo.addProject(models.Project{})
+
+	room, err := a.instanceRoom(context.Background(), roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo.lease(roomID); !ok {
+		t.Fatal("opening the room did not take its lease")
+	}
+
+	// Con un usuario conectado no se cierra
+	room.do(func() { room.Active["c1"] = &UserConnection{Email: "a@test.com"} })
+	a.closeIfIdle(roomID, room, "a@test.com")
+	if room.stopped() {
+		t.Fatal("closed a room with users")
+	}
+
+	room.do(func() {
+		delete(room.Active, "c1")
+		room.ProjectInfo.Name = "editado"
+	})
+	a.closeIfIdle(roomID, room, "a@test.com")
+
+	if !room.stopped() {
+		t.Fatal("the idle room was not stopped")
+	}
+	if _, ok := rooms.Load(roomID); ok {
+		t.Error("the idle room is still in memory")
+	}
+	if _, ok := repo.lease(roomID); ok {
+		t.Error("the lease was not released")
+	}
+	if name := repo.project(roomID).ProjectInfo.Name; name != "editado" {
+		t.Errorf("saved name = %q, want the last change", name)
+	}
+	if err := room.do(func() {}); !errors.Is(err, errRoomClosed) {
+		t.Errorf("do() = %v, want %v", err, errRoomClosed)
+	}
+}
+
+func TestInstanceRoomWaitsForClose(t *testing.T) {
+	repo := newFakeRepo()
+	a := newTestAPI(t, repo, "a")
+	roomID := repo.addProject(models.Project{})
+
+	room, err := a.instanceRoom(context.Background(), roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Se detiene pero todavía no se guardó
+	var snapshot *models.Project
+	room.do(func() {
+		room.ProjectInfo.Name = "editado"
+		snapshot = room.stopRoom()
+	})
+
+	reopened := make(chan *RoomData)
+	go func() {
+		r, err := a.instanceRoom(context.Background(), roomID)
+		if err != nil {
+			t.Error(err)
+		}
+		reopened <- r
+	}()
+
+	select {
+	case <-reopened:
+		t.Fatal("the room was reopened before it was saved")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	repo.SaveRoom(context.Background(), *snapshot)
+	a.dropRoom(roomID, room)
+
+	r := <-reopened
+	if r == nil || r == room {
+		t.Fatal("the room was not opened again")
+	}
+
+	var name string
+	r.do(func() { name = r.ProjectInfo.Name })
+	if name != "editado" {
+		t.Errorf("reopened name = %q, want the saved state", name)
+	}
+}
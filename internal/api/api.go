@@ -1,8 +1,12 @@
 package api
 
 import (
+	"sync/atomic"
+
+	"github.com/ProyectoT/api/internal/pubsub"
 	"github.com/ProyectoT/api/internal/repository"
 	"github.com/ProyectoT/api/internal/service"
+	"github.com/ProyectoT/api/settings"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -12,19 +16,29 @@ type API struct {
 	serv          service.Service
 	repo          repository.Repository
 	dataValidator *validator.Validate
+	broker        pubsub.Broker
+	instanceID    string
+	instanceURL   string
+	closing       atomic.Bool // se está apagando, ver shutdown.go
 }
 
-func New(serv service.Service, repo repository.Repository) *API {
+func New(serv service.Service, repo repository.Repository, s *settings.Settings, broker pubsub.Broker) *API {
 	return &API{
 		serv:          serv,
 		repo:          repo,
 		dataValidator: validator.New(),
+		broker:        broker,
+		instanceID:    s.InstanceID,
+		instanceURL:   s.InstanceURL,
 	}
 }
 
 func (a *API) Start(e *echo.Echo, address string) error {
 	a.RegisterRoutes(e)
 
+	// Un pánico en la sala se repite en quien llamó a do (ver actor.go); así
+	// los pedidos REST responden con un error en vez de cortar la conexión
+	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     []string{"http://localhost:*", "http://127.0.0.1:*", "https://stratascope.inf.uct.cl", "http://localhost*"},
 		AllowMethods:     []string{echo.GET, echo.POST, echo.DELETE},
@@ -0,0 +1,587 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ProyectoT/api/internal/api/dtos"
+	"github.com/ProyectoT/api/internal/models"
+)
+
+var (
+	errUnknownAction      = errors.New("unknown action")
+	errInvalidData        = errors.New("invalid data")
+	errInvalidIndex       = errors.New("index out of range")
+	errColumnExists       = errors.New("the column already exists")
+	errColumnNotRemovable = errors.New("the column cannot be deleted")
+	errServerOnly         = errors.New("action not allowed from the client")
+	errTooManyRows        = errors.New("too many rows")
+)
+
+// maxImportRows limita las filas que agrega un importRows
+const maxImportRows = 10000
+
+// applyOperation aplica una acción de edición sobre la sala y avisa a los
+// usuarios conectados. Devuelve un error si la acción no es de edición o no se
+// pudo aplicar. Los IDs que se generan quedan en op.Ref para poder repetirla
+// igual desde el registro de operaciones. Se debe llamar desde la goroutine de la sala
+func applyOperation(proyect *RoomData, op *models.Operation) error {
+
+	switch op.Action {
+
+	case "undo", "redo":
+		return applyHistory(proyect, op)
+
+	case "batch":
+		return applyBatch(proyect, op)
+	}
+
+	cmd, err := buildCommand(proyect, op)
+	if err != nil {
+		return err
+	}
+
+	if err := proyect.checkLease(op.Conn, cmd, false); err != nil {
+		return err
+	}
+
+	return performAction(proyect, op.User, Action{Type: op.Action, Command: cmd})
+}
+
+// buildCommand arma el comando de una acción de edición a partir del estado
+// actual de la sala, sin aplicarlo
+func buildCommand(proyect *RoomData, op *models.Operation) (command, error) {
+
+	switch op.Action {
+
+	case "deletetokenLink":
+
+		if proyect.Shared.Pass == "" {
+			return nil, errNotFound
+		}
+
+		return &deleteTokenCommand{Pass: proyect.Shared.Pass}, nil
+
+	case "infoP":
+		var dataP dtos.EditInfoProject
+		err := json.Unmarshal(op.Data, &dataP)
+		if err != nil {
+			log.Println("Error", err)
+			return nil, errInvalidData
+		}
+
+		info := proyect.ProjectInfo
+
+		return &infoCommand{
+			Old: dtos.EditInfoProject{
+				Name:        info.Name,
+				Location:    info.Location,
+				Visible:     info.Visible,
+				Description: info.Description,
+			},
+			New: dataP,
+		}, nil
+
+	case "añadir":
+
+		var addData dtos.Add
+		err := json.Unmarshal(op.Data, &addData)
+		if err != nil {
+			log.Println("Error al deserializar: ", err)
+			return nil, errInvalidData
+		}
+
+		if addData.RowIndex < -1 || addData.RowIndex > len(proyect.Data) {
+			return nil, errInvalidIndex
+		}
+
+		row := models.NewShape()
+		row.ID = operationRef(op)
+		if addData.Height != 0 {
+			row.Litologia.Height = addData.Height
+		}
+
+		return &addRowCommand{Row: row, Index: addData.RowIndex}, nil
+
+	case "drop":
+		var drop dtos.Drop
+		err := json.Unmarshal(op.Data, &drop)
+		if err != nil {
+			log.Println("Error al deserializar: ", err)
+			return nil, errInvalidData
+		}
+
+		id, err := proyect.rowID(drop.ActiveId)
+		if err != nil {
+			return nil, err
+		}
+		if drop.OverId < 0 || drop.OverId >= len(proyect.Data) {
+			return nil, errInvalidIndex
+		}
+
+		return &moveRowCommand{
+			ID:         id,
+			ActiveId:   drop.ActiveId,
+			OverId:     drop.OverId,
+			PrevBefore: proyect.prevRowID(drop.ActiveId),
+		}, nil
+
+	case "addCircle":
+
+		var addCircleData dtos.AddCircle
+		err := json.Unmarshal(op.Data, &addCircleData)
+		if err != nil {
+			log.Println("Error al deserializar: ", err)
+			return nil, errInvalidData
+		}
+
+		id, err := proyect.rowID(addCircleData.RowIndex)
+		if err != nil {
+			return nil, err
+		}
+		if addCircleData.InsertIndex < 0 || addCircleData.InsertIndex > len(proyect.Data[addCircleData.RowIndex].Litologia.Circles) {
+			return nil, errInvalidIndex
+		}
+
+		return &addCircleCommand{
+			RowID:  id,
+			Index:  addCircleData.InsertIndex,
+			Circle: models.NewCircle(addCircleData.Point),
+		}, nil
+
+	case "addFosil":
+
+		var fosil models.Fosil
+		err := json.Unmarshal(op.Data, &fosil)
+		if err != nil {
+			log.Println("Error", err)
+			return nil, errInvalidData
+		}
+
+		if fosil.Upper < 0 || fosil.Lower < 0 {
+			return nil, errInvalidData
+		}
+
+		return &addFosilCommand{ID: operationRef(op), Fosil: fosil}, nil
+
+	case "addMuestra":
+
+		var muestra models.Muestra
+		err := json.Unmarshal(op.Data, &muestra)
+		if err != nil {
+			log.Println("Error", err)
+			return nil, errInvalidData
+		}
+
+		if muestra.Upper < 0 || muestra.Lower < 0 {
+			return nil, errInvalidData
+		}
+
+		return &addMuestraCommand{ID: operationRef(op), Muestra: muestra}, nil
+
+	case "addFacie":
+
+		var facie dtos.Facie
+		err := json.Unmarshal(op.Data, &facie)
+		if err != nil {
+			log.Println("Error", err)
+			return nil, errInvalidData
+		}
+
+		return &addFacieCommand{Facie: facie.Facie}, nil
+
+	case "addFacieSection":
+
+		var f dtos.AddFacieSection
+		err := json.Unmarshal(op.Data, &f)
+		if err != nil {
+			log.Println("Error", err)
+			return nil, errInvalidData
+		}
+
+		return &addFacieSectionCommand{
+			Facie:   f.Facie,
+			Index:   f.Index,
+			Section: models.FaciesSection{Y1: f.Y1, Y2: f.Y2},
+		}, nil
+
+	case "editCircle":
+
+		var editCircles dtos.EditCircle
+		err := json.Unmarshal(op.Data, &editCircles)
+		if err != nil {
+			log.Println("Error al deserializar: ", err)
+			return nil, errInvalidData
+		}
+
+		id, err := proyect.rowID(editCircles.RowIndex)
+		if err != nil {
+			return nil, err
+		}
+		circles := proyect.Data[editCircles.RowIndex].Litologia.Circles
+		if editCircles.EditIndex < 0 || editCircles.EditIndex >= len(circles) {
+			return nil, errInvalidIndex
+		}
+
+		oldCircle := circles[editCircles.EditIndex]
+		newCircle := oldCircle
+		newCircle.X = editCircles.X
+		newCircle.Name = editCircles.Name
+
+		return &editCircleCommand{RowID: id, Index: editCircles.EditIndex, Old: oldCircle, New: newCircle}, nil
+
+	case "editText":
+
+		var editTextData dtos.EditText
+		err := json.Unmarshal(op.Data, &editTextData)
+		if err != nil {
+			log.Println("Error al deserializar: ", err)
+			return nil, errInvalidData
+		}
+
+		id, err := proyect.rowID(editTextData.RowIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		return &editTextCommand{
+			RowID: id,
+			Key:   editTextData.Key,
+			Old:   proyect.Data[editTextData.RowIndex].Columns[editTextData.Key],
+			New:   editTextData.Value,
+		}, nil
+
+	case "editPolygon":
+
+		var polygon dtos.EditPolygon
+		err := json.Unmarshal(op.Data, &polygon)
+		if err != nil {
+			log.Println("Error deserializando el polygon:", err)
+			return nil, errInvalidData
+		}
+
+		id, err := proyect.rowID(polygon.RowIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		return &editPolygonCommand{
+			RowID:  id,
+			Column: polygon.Column,
+			Old:    GetFieldString(proyect.Data[polygon.RowIndex].Litologia, polygon.Column),
+			Value:  polygon.Value,
+		}, nil
+
+	case "editFosil":
+
+		var fosil dtos.EditFosil
+		err := json.Unmarshal(op.Data, &fosil)
+		if err != nil {
+			log.Println("Error deserializando fósil:", err)
+			return nil, errInvalidData
+		}
+
+		oldFosil, exists := proyect.Fosil[fosil.IdFosil]
+		if !exists {
+			return nil, errNotFound
+		}
+
+		return &editFosilCommand{
+			ID:  fosil.IdFosil,
+			Old: oldFosil,
+			New: models.NewFosil(fosil.Upper, fosil.Lower, fosil.FosilImg, fosil.X),
+		}, nil
+
+	case "editMuestra":
+
+		var muestra dtos.EditMuestra
+		err := json.Unmarshal(op.Data, &muestra)
+		if err != nil {
+			log.Println("Error deserializando muestra:", err)
+			return nil, errInvalidData
+		}
+
+		oldMuestra, exists := proyect.Muestras[muestra.IdMuestra]
+		if !exists {
+			return nil, errNotFound
+		}
+
+		return &editMuestraCommand{
+			ID:  muestra.IdMuestra,
+			Old: oldMuestra,
+			New: models.NewMuestra(muestra.Upper, muestra.Lower, muestra.MuestraText, muestra.X),
+		}, nil
+
+	case "delete":
+
+		var deleteData dtos.Delete
+		err := json.Unmarshal(op.Data, &deleteData)
+		if err != nil {
+			log.Println("Error al deserializar: ", err)
+			return nil, errInvalidData
+		}
+
+		id, err := proyect.rowID(deleteData.RowIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		return &deleteRowCommand{ID: id}, nil
+
+	case "deleteCircle":
+
+		var delCircle dtos.DeleteCircle
+		err := json.Unmarshal(op.Data, &delCircle)
+		if err != nil {
+			log.Println("Error al deserializar: ", err)
+			return nil, errInvalidData
+		}
+
+		id, err := proyect.rowID(delCircle.RowIndex)
+		if err != nil {
+			return nil, err
+		}
+		circles := proyect.Data[delCircle.RowIndex].Litologia.Circles
+		if delCircle.DeleteIndex < 0 || delCircle.DeleteIndex >= len(circles) {
+			return nil, errInvalidIndex
+		}
+
+		return &deleteCircleCommand{RowID: id, Index: delCircle.DeleteIndex, Circle: circles[delCircle.DeleteIndex]}, nil
+
+	case "deleteFosil":
+
+		var fosilID dtos.DeleteFosil
+		err := json.Unmarshal(op.Data, &fosilID)
+		if err != nil {
+			log.Println("Error deserializando fósil:", err)
+			return nil, errInvalidData
+		}
+
+		fosil, exists := proyect.Fosil[fosilID.IdFosil]
+		if !exists {
+			return nil, errNotFound
+		}
+
+		return &deleteFosilCommand{ID: fosilID.IdFosil, Fosil: fosil}, nil
+
+	case "deleteMuestra":
+
+		var muestraID dtos.DeleteMuestra
+		err := json.Unmarshal(op.Data, &muestraID)
+		if err != nil {
+			log.Println("Error deserializando fósil:", err)
+			return nil, errInvalidData
+		}
+
+		muestra, exists := proyect.Muestras[muestraID.IdMuestra]
+		if !exists {
+			return nil, errNotFound
+		}
+
+		return &deleteMuestraCommand{ID: muestraID.IdMuestra, Muestra: muestra}, nil
+
+	case "addLog":
+
+		// Solo la genera HandleImportLAS, que valida el archivo con importer.ReadLAS
+		if op.Conn != "" {
+			return nil, errServerOnly
+		}
+
+		var newLog dtos.AddLog
+		err := json.Unmarshal(op.Data, &newLog)
+		if err != nil {
+			log.Println("Error", err)
+			return nil, errInvalidData
+		}
+
+		id := newLog.IdLog
+		if id == "" {
+			id = operationRef(op)
+		}
+
+		return &addLogCommand{ID: id, Log: newLog.Value}, nil
+
+	case "importRows":
+
+		// Solo la genera HandleImportToRoom, que valida las filas antes
+		if op.Conn != "" {
+			return nil, errServerOnly
+		}
+
+		var rows dtos.ImportRows
+		err := json.Unmarshal(op.Data, &rows)
+		if err != nil {
+			log.Println("Error", err)
+			return nil, errInvalidData
+		}
+
+		// Las columnas nuevas se agregan al aplicar el comando, así se deshacen con él
+		var columns []models.Column
+		for _, name := range rows.Columns {
+			if strings.TrimSpace(name) == "" || hasColumn(proyect.Config.Columns, name) || hasColumn(columns, name) {
+				continue
+			}
+			columns = append(columns, models.Column{Name: name, Visible: true, Removable: true})
+		}
+
+		if len(rows.Rows) > maxImportRows {
+			return nil, errTooManyRows
+		}
+
+		ref := operationRef(op)
+		for i := range rows.Rows {
+			rows.Rows[i].ID = fmt.Sprintf("%s-%d", ref, i)
+			if rows.Rows[i].Columns == nil {
+				rows.Rows[i].Columns = map[string]interface{}{}
+			}
+		}
+
+		return &importRowsCommand{Rows: rows.Rows, Columns: columns}, nil
+
+	case "deleteLog":
+
+		var logID dtos.DeleteLog
+		err := json.Unmarshal(op.Data, &logID)
+		if err != nil {
+			log.Println("Error", err)
+			return nil, errInvalidData
+		}
+
+		if _, exists := proyect.Logs[logID.IdLog]; !exists {
+			return nil, errNotFound
+		}
+
+		return &deleteLogCommand{ID: logID.IdLog}, nil
+
+	case "deleteFacie":
+
+		var facie dtos.Facie
+		err := json.Unmarshal(op.Data, &facie)
+		if err != nil {
+			log.Println("Error", err)
+			return nil, errInvalidData
+		}
+
+		return &deleteFacieCommand{Facie: facie.Facie}, nil
+
+	case "deleteFacieSection":
+
+		var f dtos.DeleteFacieSection
+		err := json.Unmarshal(op.Data, &f)
+		if err != nil {
+			log.Println("Error", err)
+			return nil, errInvalidData
+		}
+
+		sections, exists := proyect.Facies[f.Facie]
+		if !exists {
+			return nil, errNotFound
+		}
+		if f.Index < 0 || f.Index >= len(sections) {
+			return nil, errInvalidIndex
+		}
+
+		return &deleteFacieSectionCommand{Facie: f.Facie, Index: f.Index, Section: sections[f.Index]}, nil
+
+	case "addColumn":
+
+		var column models.Column
+		err := json.Unmarshal(op.Data, &column)
+		if err != nil {
+			log.Println("Error deserializando columna:", err)
+			return nil, errInvalidData
+		}
+
+		if strings.TrimSpace(column.Name) == "" {
+			return nil, errInvalidData
+		}
+
+		column.Visible = true
+		column.Removable = true
+
+		return &addColumnCommand{Column: column}, nil
+
+	case "deleteColumn":
+
+		var column models.Column
+		err := json.Unmarshal(op.Data, &column)
+		if err != nil {
+			log.Println("Error deserializando columna:", err)
+			return nil, errInvalidData
+		}
+
+		index := columnIndex(proyect.Config.Columns, column.Name)
+		if index == -1 {
+			return nil, errNotFound
+		}
+		if !proyect.Config.Columns[index].Removable {
+			return nil, errColumnNotRemovable
+		}
+
+		return &deleteColumnCommand{Column: proyect.Config.Columns[index]}, nil
+
+	case "isInverted":
+
+		var inverted dtos.IsInverted
+		err := json.Unmarshal(op.Data, &inverted)
+		if err != nil {
+			log.Println("Error deserializando columna:", err)
+			return nil, errInvalidData
+		}
+
+		return &invertCommand{Old: proyect.Config.IsInverted, New: inverted.IsInverted}, nil
+
+	case "toggleColumn":
+		var column dtos.Column
+		err := json.Unmarshal(op.Data, &column)
+		if err != nil {
+			log.Println("Error deserializando columna:", err)
+			return nil, errInvalidData
+		}
+
+		index := columnIndex(proyect.Config.Columns, column.Column)
+		if index == -1 {
+			return nil, errNotFound
+		}
+
+		return &toggleColumnCommand{Column: column.Column, Visible: proyect.Config.Columns[index].Visible}, nil
+
+	case "MoveColumn":
+		var drop dtos.Drop
+		err := json.Unmarshal(op.Data, &drop)
+		if err != nil {
+			log.Println("Error al deserializar: ", err)
+			return nil, errInvalidData
+		}
+
+		columns := proyect.Config.Columns
+
+		if drop.ActiveId < 0 || drop.ActiveId >= len(columns) || drop.OverId < 0 || drop.OverId >= len(columns) {
+			log.Println("Índice fuera de los límites")
+			return nil, errInvalidIndex
+		}
+
+		return &moveColumnCommand{Column: columns[drop.ActiveId].Name, From: drop.ActiveId, To: drop.OverId}, nil
+
+	default:
+		return nil, errUnknownAction
+	}
+}
+
+// hasColumn indica si ya existe una columna con ese nombre
+func hasColumn(columns []models.Column, name string) bool {
+	return columnIndex(columns, name) != -1
+}
+
+// columnIndex devuelve la posición de la columna o -1 si no existe
+func columnIndex(columns []models.Column, name string) int {
+	for i, col := range columns {
+		if col.Name == name {
+			return i
+		}
+	}
+	return -1
+}
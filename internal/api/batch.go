@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/ProyectoT/api/internal/api/dtos"
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// batchLimit es la cantidad máxima de acciones en un batch
+var batchLimit = 500
+
+var errInvalidBatch = fmt.Errorf("%w: batch must contain between 1 and %d edit actions", errInvalidData, batchLimit)
+
+// batchCommand agrupa varias acciones en un solo paso del historial. Se
+// aplican todas o ninguna y los usuarios reciben un solo mensaje
+type batchCommand struct {
+	Actions []Action
+}
+
+// applyBatch aplica las acciones en orden. Cada una se arma sobre el estado que
+// dejó la anterior, así los índices que manda el cliente se refieren a ese
+// estado. Si alguna falla se revierten las ya aplicadas y no se envía nada
+func applyBatch(proyect *RoomData, op *models.Operation) error {
+	var batch dtos.Batch
+	if err := json.Unmarshal(op.Data, &batch); err != nil {
+		log.Println("Error al deserializar: ", err)
+		return errInvalidData
+	}
+
+	if len(batch.Actions) == 0 || len(batch.Actions) > batchLimit {
+		return errInvalidBatch
+	}
+
+	ref := operationRef(op)
+	cmd := &batchCommand{}
+
+	// Si una acción falla (o entra en pánico) se revierten las ya aplicadas
+	applied := false
+	proyect.beginBatch()
+	defer func() {
+		if !applied {
+			cmd.rollback(proyect, len(cmd.Actions))
+		}
+		proyect.endBatch(applied)
+	}()
+
+	for i, action := range batch.Actions {
+		switch action.Action {
+		case "undo", "redo", "batch":
+			return fmt.Errorf("action %d (%s): %w", i, action.Action, errUnknownAction)
+		}
+
+		// Los IDs que genere cada acción salen del ref del batch para poder repetirlo
+		sub := models.Operation{
+			ProjectID: op.ProjectID,
+			User:      op.User,
+			Action:    action.Action,
+			Data:      action.Data,
+			Ref:       fmt.Sprintf("%s-%d", ref, i),
+			Conn:      op.Conn,
+		}
+
+		c, err := buildCommand(proyect, &sub)
+		if err == nil {
+			err = proyect.checkLease(op.Conn, c, false)
+		}
+		if err == nil {
+			err = c.Execute(proyect)
+		}
+		if err != nil {
+			return fmt.Errorf("action %d (%s): %w", i, action.Action, err)
+		}
+
+		cmd.Actions = append(cmd.Actions, Action{Type: action.Action, Command: c})
+	}
+
+	applied = true
+	pushAction(proyect, op.User, Action{Type: op.Action, Command: cmd})
+	return nil
+}
+
+func (c *batchCommand) Execute(room *RoomData) error {
+	started := room.beginBatch()
+
+	for i, action := range c.Actions {
+		if err := action.Command.Execute(room); err != nil {
+			c.rollback(room, i)
+			if started {
+				room.endBatch(false)
+			}
+			return err
+		}
+	}
+
+	if started {
+		room.endBatch(true)
+	}
+	return nil
+}
+
+func (c *batchCommand) Undo(room *RoomData) error {
+	started := room.beginBatch()
+
+	for i := len(c.Actions) - 1; i >= 0; i-- {
+		if err := c.Actions[i].Command.Undo(room); err != nil {
+			// Se vuelven a aplicar las que ya se habían deshecho
+			for j := i + 1; j < len(c.Actions); j++ {
+				if err := c.Actions[j].Command.Execute(room); err != nil {
+					log.Println("Error restaurando el batch: ", err)
+				}
+			}
+			if started {
+				room.endBatch(false)
+			}
+			return err
+		}
+	}
+
+	if started {
+		room.endBatch(true)
+	}
+	return nil
+}
+
+// rollback deshace, de la última a la primera, las n primeras acciones
+func (c *batchCommand) rollback(room *RoomData, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if err := c.Actions[i].Command.Undo(room); err != nil {
+			log.Println("Error revirtiendo el batch: ", err)
+		}
+	}
+}
+
+func (c *batchCommand) MarshalJSON() ([]byte, error) {
+	size := 0
+	return json.Marshal(struct {
+		Actions []models.Command `json:"actions"`
+	}{encodeActions(c.Actions, &size)})
+}
+
+func (c *batchCommand) UnmarshalJSON(data []byte) error {
+	var encoded struct {
+		Actions []models.Command `json:"actions"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+
+	c.Actions = decodeActions(encoded.Actions)
+	return nil
+}
+
+// beginBatch empieza a juntar los mensajes para los usuarios. Devuelve false
+// si ya se estaban juntando
+func (r *RoomData) beginBatch() bool {
+	if r.batching {
+		return false
+	}
+
+	r.batching = true
+	r.pending = nil
+	return true
+}
+
+// endBatch envía los mensajes juntados como uno solo, o los descarta si el
+// batch no se aplicó
+func (r *RoomData) endBatch(send bool) {
+	pending := r.pending
+	r.batching = false
+	r.pending = nil
+
+	if !send || len(pending) == 0 {
+		return
+	}
+
+	sendSocketMessage(map[string]interface{}{
+		"action":  "batch",
+		"actions": pending,
+	}, r, "batch")
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+func testRoom() *RoomData {
+	project := &models.Project{
+		Fosil:    map[string]models.Fosil{},
+		Facies:   map[string][]models.FaciesSection{"arena": {{Y1: 0, Y2: 10}}},
+		Muestras: map[string]models.Muestra{},
+		Logs:     map[string]models.WellLog{},
+		Config: models.Config{Columns: []models.Column{
+			{Name: "Espesor", Visible: true},
+			{Name: "Notas", Visible: true, Removable: true},
+		}},
+	}
+	for i := 0; i < 3; i++ {
+		row := models.NewShape()
+		row.Columns["Espesor"] = float64(10 * (i + 1))
+		project.Data = append(project.Data, row)
+	}
+
+	return newRoomData(project)
+}
+
+func batchOperation(t *testing.T, actions ...interface{}) *models.Operation {
+	t.Helper()
+
+	batch := map[string]interface{}{"actions": []interface{}{}}
+	for i := 0; i < len(actions); i += 2 {
+		batch["actions"] = append(batch["actions"].([]interface{}), map[string]interface{}{
+			"action": actions[i],
+			"data":   actions[i+1],
+		})
+	}
+
+	data, err := json.Marshal(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.Operation{User: "a@test.com", Action: "batch", Data: data}
+}
+
+func TestBatchRollback(t *testing.T) {
+	room := testRoom()
+	before := room.snapshot()
+
+	op := batchOperation(t,
+		"añadir", map[string]interface{}{"rowIndex": 0},
+		"editText", map[string]interface{}{"key": "Espesor", "value": "5", "rowIndex": 1},
+		"addColumn", map[string]interface{}{"name": "Edad"},
+		"deleteColumn", map[string]interface{}{"name": "Notas"},
+		"drop", map[string]interface{}{"activeId": 0, "overId": 3},
+		"delete", map[string]interface{}{"rowIndex": 2},
+		"addFacieSection", map[string]interface{}{"facie": "arena", "index": 0, "y1": 20, "y2": 30},
+		"isInverted", map[string]interface{}{"isInverted": true},
+		// Falla: la capa no existe
+		"editText", map[string]interface{}{"key": "Espesor", "value": "1", "rowIndex": 99},
+	)
+
+	err := applyOperation(room, op)
+	if !errors.Is(err, errLayerNotFound) {
+		t.Fatalf("applyOperation() = %v, want %v", err, errLayerNotFound)
+	}
+
+	after := room.snapshot()
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("the batch was not rolled back\nbefore: %+v\nafter:  %+v", before, after)
+	}
+	if cell := room.Data[1].Columns["Espesor"]; cell != float64(20) {
+		t.Errorf("cell = %#v, want float64(20)", cell)
+	}
+
+	if room.streamSeq != 0 || room.pending != nil || room.batching {
+		t.Errorf("messages were sent: seq %d, pending %d, batching %v", room.streamSeq, len(room.pending), room.batching)
+	}
+	if len(room.undoStacks[op.User]) != 0 {
+		t.Errorf("undo stack has %d actions, want 0", len(room.undoStacks[op.User]))
+	}
+}
+
+func TestBatchUndo(t *testing.T) {
+	room := testRoom()
+	before := room.snapshot()
+
+	op := batchOperation(t,
+		"añadir", map[string]interface{}{"rowIndex": -1},
+		"editText", map[string]interface{}{"key": "Espesor", "value": "5", "rowIndex": 0},
+		"deleteColumn", map[string]interface{}{"name": "Notas"},
+	)
+
+	if err := applyOperation(room, op); err != nil {
+		t.Fatal(err)
+	}
+	if room.streamSeq != 1 {
+		t.Errorf("seq = %d, want a single message", room.streamSeq)
+	}
+	if len(room.Data) != 4 || room.Data[0].Columns["Espesor"] != "5" {
+		t.Fatalf("the batch was not applied: %+v", room.Data)
+	}
+
+	if err := applyOperation(room, &models.Operation{User: op.User, Action: "undo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	after := room.snapshot()
+	after.History = before.History
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("undo did not restore the project\nbefore: %+v\nafter:  %+v", before, after)
+	}
+}
+
+func TestImportRowsFromServerOnly(t *testing.T) {
+	room := testRoom()
+	data := json.RawMessage(`{"columns":["Edad"],"rows":[{"Columns":null}]}`)
+
+	op := &models.Operation{User: "a@test.com", Action: "importRows", Data: data, Conn: "conn-1"}
+	if err := applyOperation(room, op); !errors.Is(err, errServerOnly) {
+		t.Fatalf("applyOperation() from a client = %v, want %v", err, errServerOnly)
+	}
+
+	op = &models.Operation{User: "a@test.com", Action: "importRows", Data: data}
+	if err := applyOperation(room, op); err != nil {
+		t.Fatalf("applyOperation() = %v", err)
+	}
+	if len(room.Data) != 4 || room.Data[3].Columns == nil {
+		t.Fatalf("imported row has no columns: %+v", room.Data)
+	}
+
+	// Antes entraba en pánico con el mapa nil
+	edit := &models.Operation{User: "a@test.com", Action: "editText", Data: json.RawMessage(`{"key":"Edad","value":"x","rowIndex":3}`)}
+	if err := applyOperation(room, edit); err != nil {
+		t.Fatalf("editText on the imported row = %v", err)
+	}
+}
+
+func TestAddLogFromServerOnly(t *testing.T) {
+	room := testRoom()
+	data := json.RawMessage(`{"idLog":"log-1","value":{"depth":[0,1]}}`)
+
+	op := &models.Operation{User: "a@test.com", Action: "addLog", Data: data, Conn: "conn-1"}
+	if err := applyOperation(room, op); !errors.Is(err, errServerOnly) {
+		t.Fatalf("applyOperation() from a client = %v, want %v", err, errServerOnly)
+	}
+	if len(room.Logs) != 0 {
+		t.Fatalf("logs = %d, want 0", len(room.Logs))
+	}
+
+	op = &models.Operation{User: "a@test.com", Action: "addLog", Data: data}
+	if err := applyOperation(room, op); err != nil {
+		t.Fatalf("applyOperation() = %v", err)
+	}
+	if _, ok := room.Logs["log-1"]; !ok {
+		t.Fatal("the log was not added")
+	}
+}
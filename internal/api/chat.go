@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ProyectoT/api/internal/api/dtos"
+	"github.com/ProyectoT/api/internal/entity"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Chat de la sala. Solo lo usan los miembros del proyecto: los mensajes se
+// envían únicamente a sus conexiones, fuera de la secuencia de stream.go (los
+// lectores de un proyecto público no deben recibirlos con resync), y se
+// guardan en la colección messages. Al entrar o reconectarse se recibe la
+// última página y la cantidad de mensajes sin leer; las anteriores se piden
+// por REST.
+
+var (
+	chatMessageLimit = 2000 // caracteres por mensaje
+	chatPageSize     = 50
+)
+
+var (
+	errNotMember      = errors.New("only project members can use the chat")
+	errInvalidMessage = fmt.Errorf("message must not be empty or longer than %d characters", chatMessageLimit)
+)
+
+type MessagesResponse struct {
+	Messages []entity.Message `json:"messages"`
+	Unread   int64            `json:"unread"`
+	More     bool             `json:"more"` // hay mensajes anteriores
+}
+
+// chat envía el mensaje a la sala y lo guarda en segundo plano. Se debe
+// llamar desde la goroutine de la sala
+func (a *API) chat(proyect *RoomData, user string, data json.RawMessage) error {
+	if !isMember(proyect.ProjectInfo, user) {
+		return errNotMember
+	}
+
+	var chat dtos.Chat
+	if err := json.Unmarshal(data, &chat); err != nil {
+		log.Println("Error al deserializar: ", err)
+		return errInvalidData
+	}
+
+	text := strings.TrimSpace(chat.Message)
+	if text == "" || len([]rune(text)) > chatMessageLimit {
+		return errInvalidMessage
+	}
+
+	message := entity.Message{
+		ID:        primitive.NewObjectID(),
+		Room:      proyect.ID.Hex(),
+		Author:    user,
+		Message:   text,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	proyect.sendMembers(map[string]interface{}{"action": "chat", "message": message})
+
+	go func() {
+		ctx := context.Background()
+		if err := a.repo.SaveMessage(ctx, message); err != nil {
+			log.Println("Error guardando el mensaje: ", err)
+			return
+		}
+		if err := a.repo.MarkMessagesRead(ctx, message.Room, user, message.ID.Hex()); err != nil {
+			log.Println("Error guardando los mensajes leídos: ", err)
+		}
+	}()
+
+	return nil
+}
+
+// sendMembers envía el mensaje solo a las conexiones de los miembros del
+// proyecto, sin numerarlo. Se debe llamar desde la goroutine de la sala
+func (r *RoomData) sendMembers(msgData map[string]interface{}) {
+	jsonMsg, err := json.Marshal(msgData)
+	if err != nil {
+		log.Println("Error serializing message:", err)
+		return
+	}
+
+	for _, client := range r.Active {
+		if isMember(r.ProjectInfo, client.Email) {
+			client.Conn.Send(jsonMsg)
+		}
+	}
+}
+
+// chatRead guarda hasta qué mensaje leyó el usuario
+func (a *API) chatRead(proyect *RoomData, user string, data json.RawMessage) error {
+	if !isMember(proyect.ProjectInfo, user) {
+		return errNotMember
+	}
+
+	var read dtos.ChatRead
+	if err := json.Unmarshal(data, &read); err != nil || !primitive.IsValidObjectID(read.ID) {
+		return errInvalidData
+	}
+
+	roomID := proyect.ID.Hex()
+
+	go func() {
+		if err := a.repo.MarkMessagesRead(context.Background(), roomID, user, read.ID); err != nil {
+			log.Println("Error guardando los mensajes leídos: ", err)
+		}
+	}()
+
+	return nil
+}
+
+// sendChatHistory envía al usuario que entra los últimos mensajes y cuántos
+// no leyó. Se llama fuera de la goroutine de la sala
+func (a *API) sendChatHistory(client *Client, roomID string, user string) {
+	ctx := context.Background()
+
+	response, err := a.messages(ctx, roomID, user, "", chatPageSize)
+	if err != nil {
+		log.Println("Error leyendo el chat: ", err)
+		return
+	}
+
+	client.SendJSON(map[string]interface{}{
+		"action":   "chatHistory",
+		"messages": response.Messages,
+		"unread":   response.Unread,
+		"more":     response.More,
+	})
+}
+
+func (a *API) messages(ctx context.Context, roomID string, user string, before string, limit int) (*MessagesResponse, error) {
+	messages, err := a.repo.GetMessages(ctx, roomID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := a.repo.CountUnreadMessages(ctx, roomID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MessagesResponse{Messages: messages, Unread: unread, More: len(messages) == limit}, nil
+}
+
+// HandleGetMessages devuelve los mensajes del chat anteriores a ?before= y la
+// cantidad sin leer
+func (a *API) HandleGetMessages(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+
+	info, err := a.projectInfo(ctx, roomID)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	if !isMember(*info, user) {
+		return a.handleError(c, http.StatusForbidden, "Access denied")
+	}
+
+	before := c.QueryParam("before")
+	if before != "" && !primitive.IsValidObjectID(before) {
+		return a.handleError(c, http.StatusBadRequest, "Invalid message id")
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit < 1 || limit > 200 {
+		limit = chatPageSize
+	}
+
+	response, err := a.messages(ctx, roomID, user, before, limit)
+	if err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Error getting messages")
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// HandleReadMessages marca como leídos los mensajes hasta el indicado
+func (a *API) HandleReadMessages(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+
+	var read dtos.ChatRead
+	if err := c.Bind(&read); err != nil || !primitive.IsValidObjectID(read.ID) {
+		return a.handleError(c, http.StatusBadRequest, "Invalid request")
+	}
+
+	info, err := a.projectInfo(ctx, roomID)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	if !isMember(*info, user) {
+		return a.handleError(c, http.StatusForbidden, "Access denied")
+	}
+
+	if err := a.repo.MarkMessagesRead(ctx, roomID, user, read.ID); err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Error saving read messages")
+	}
+
+	return c.JSON(http.StatusOK, responseMessage{Message: "Messages marked as read"})
+}
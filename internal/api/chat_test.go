@@ -0,0 +1,45 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChatOnlyReachesMembers(t *testing.T) {
+	a := newTestAPI(t, newFakeRepo(), "a")
+
+	room := testRoom()
+	room.ProjectInfo.Visible = true
+	room.ProjectInfo.Members.Owner = "owner@test.com"
+	room.ProjectInfo.Members.Readers = []string{"reader@test.com"}
+
+	owner := testUser("owner@test.com")
+	reader := testUser("reader@test.com")
+	public := testUser("public@test.com")
+	room.Active["owner"] = owner
+	room.Active["reader"] = reader
+	room.Active["public"] = public
+
+	if err := a.chat(room, "public@test.com", []byte(`{"message":"hola"}`)); err != errNotMember {
+		t.Errorf("chat() by a visitor = %v, want %v", err, errNotMember)
+	}
+
+	if err := a.chat(room, "owner@test.com", []byte(`{"message":"hola"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, u := range map[string]*UserConnection{"owner": owner, "reader": reader} {
+		messages := received(u)
+		if len(messages) != 1 || !strings.Contains(messages[0], `"hola"`) {
+			t.Errorf("%s received %q, want the message", name, messages)
+		}
+	}
+	if messages := received(public); len(messages) != 0 {
+		t.Errorf("a visitor received %q", messages)
+	}
+
+	// No queda en la secuencia que se reenvía con resync
+	if room.streamSeq != 0 {
+		t.Errorf("seq = %d, want the chat outside the stream", room.streamSeq)
+	}
+}
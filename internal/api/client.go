@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Cada conexión tiene una goroutine que es la única que escribe en el socket.
+// Los mensajes se dejan en una cola y nunca se espera al cliente, así uno lento
+// no frena a la sala. Si la cola se llena el cliente se desconecta; al volver
+// se pone al día con resync (ver stream.go), por eso la cola tiene que ser más
+// grande que streamBufferSize
+var (
+	sendQueueSize = 1024
+	writeWait     = 10 * time.Second
+	pingPeriod    = 10 * time.Second
+)
+
+var errClientClosed = errors.New("connection closed")
+
+type Client struct {
+	conn *websocket.Conn
+	send chan []byte
+	done chan struct{}
+	once sync.Once
+	wait time.Duration // writeWait al crear la conexión
+}
+
+func newClient(conn *websocket.Conn) *Client {
+	c := &Client{
+		conn: conn,
+		send: make(chan []byte, sendQueueSize),
+		done: make(chan struct{}),
+		wait: writeWait,
+	}
+
+	go c.writeLoop()
+
+	return c
+}
+
+// Send deja el mensaje en la cola. Devuelve false si la conexión está cerrada
+// o si el cliente se atrasó y se desconectó
+func (c *Client) Send(msg []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		log.Println("Cliente atrasado, se desconecta: ", c.conn.RemoteAddr())
+		c.Close()
+		return false
+	}
+}
+
+func (c *Client) SendJSON(v interface{}) error {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if !c.Send(msg) {
+		return errClientClosed
+	}
+	return nil
+}
+
+// Close cierra la conexión después de enviar lo que quedó en la cola
+func (c *Client) Close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *Client) writeLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.wait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.Close()
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.wait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.Close()
+				return
+			}
+
+		case <-c.done:
+			c.flush()
+			return
+		}
+	}
+}
+
+// flush envía los mensajes pendientes, con un plazo total de writeWait
+func (c *Client) flush() {
+	c.conn.SetWriteDeadline(time.Now().Add(c.wait))
+
+	for {
+		select {
+		case msg := <-c.send:
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		default:
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testClient conecta un Client del servidor con una conexión del lado del
+// navegador, que se devuelve para leer lo que llega
+func testClient(t *testing.T) (*Client, *websocket.Conn) {
+	t.Helper()
+
+	clients := make(chan *Client, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		clients <- newClient(conn)
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	client := <-clients
+	t.Cleanup(client.Close)
+	return client, peer
+}
+
+func TestClientSendsInOrder(t *testing.T) {
+	client, peer := testClient(t)
+
+	for i := 0; i < 100; i++ {
+		if !client.Send([]byte(fmt.Sprint(i))) {
+			t.Fatalf("Send(%d) = false", i)
+		}
+	}
+
+	peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i := 0; i < 100; i++ {
+		_, msg, err := peer.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg) != fmt.Sprint(i) {
+			t.Fatalf("message %d = %s", i, msg)
+		}
+	}
+}
+
+func TestClientCloseFlushes(t *testing.T) {
+	client, peer := testClient(t)
+
+	for i := 0; i < 10; i++ {
+		client.Send([]byte(fmt.Sprint(i)))
+	}
+	client.Close()
+
+	if client.Send([]byte("after")) {
+		t.Error("Send() after Close() = true")
+	}
+	if err := client.SendJSON(map[string]string{"action": "after"}); err != errClientClosed {
+		t.Errorf("SendJSON() after Close() = %v, want %v", err, errClientClosed)
+	}
+
+	peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i := 0; i < 10; i++ {
+		_, msg, err := peer.ReadMessage()
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if string(msg) != fmt.Sprint(i) {
+			t.Fatalf("message %d = %s", i, msg)
+		}
+	}
+
+	// Después de la cola llega el cierre normal
+	_, _, err := peer.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("ReadMessage() = %v, want a normal close", err)
+	}
+}
+
+func TestClientOverflowCloses(t *testing.T) {
+	size, wait := sendQueueSize, writeWait
+	sendQueueSize, writeWait = 4, 100*time.Millisecond
+	defer func() { sendQueueSize, writeWait = size, wait }()
+
+	// El navegador no lee: la escritura se bloquea y la cola se llena
+	client, _ := testClient(t)
+	msg := bytes.Repeat([]byte("x"), 1<<20)
+
+	overflowed := false
+	for i := 0; i < 1000; i++ {
+		if !client.Send(msg) {
+			overflowed = true
+			break
+		}
+	}
+	if !overflowed {
+		t.Fatal("the queue never overflowed")
+	}
+
+	select {
+	case <-client.done:
+	default:
+		t.Error("the client was not closed after the overflow")
+	}
+	if client.Send([]byte("after")) {
+		t.Error("Send() after the overflow = true")
+	}
+}
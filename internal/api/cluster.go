@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+// Con varias instancias de la API cada sala está abierta en una sola: la que
+// tiene su lease en room_leases. Las demás redirigen a los clientes a esa
+// instancia y le avisan por el broker (ver pubsub) los cambios que hacen
+// directamente en la base de datos: miembros, comentarios y borrado. Si una
+// instancia pierde el lease (por ejemplo tras una pausa larga), lo que guarde
+// después se rechaza: la sala se guarda con la época del lease y la instancia
+// que la toma anota una mayor en el proyecto.
+
+var (
+	roomLeaseTTL   = 30 * time.Second
+	roomLeaseRenew = 10 * time.Second
+
+	roomEventQueueSize = 256
+)
+
+// roomElsewhereError indica que la sala está abierta en otra instancia
+type roomElsewhereError struct {
+	URL string // dirección de la instancia dueña
+}
+
+func (e *roomElsewhereError) Error() string {
+	return "room is open on another instance"
+}
+
+type RedirectMessage struct {
+	Action  string `json:"action"`
+	Message string `json:"message"`
+	URL     string `json:"url"`
+}
+
+// Eventos que se mandan a la instancia dueña de la sala
+const (
+	roomEventBroadcast = "broadcast" // enviar Message a toda la sala
+	roomEventDirect    = "direct"    // enviar Message a las conexiones de los miembros en Users, o de todos con AllMembers
+	roomEventMembers   = "members"   // los miembros cambiaron en la base de datos
+	roomEventDeleted   = "deleted"   // el proyecto se eliminó
+)
+
+type roomEvent struct {
+	Type       string          `json:"type"`
+	Message    json.RawMessage `json:"message,omitempty"`
+	Users      []string        `json:"users,omitempty"`
+	AllMembers bool            `json:"allMembers,omitempty"` // direct a todos los miembros conectados
+	Members    *models.Members `json:"members,omitempty"`
+}
+
+func roomTopic(roomID string) string {
+	return "room." + roomID
+}
+
+// acquireRoom toma o renueva el lease de la sala para esta instancia y
+// devuelve su época
+func (a *API) acquireRoom(ctx context.Context, roomID string) (int64, error) {
+	lease, err := a.repo.AcquireRoomLease(ctx, roomID, a.instanceID, a.instanceURL, roomLeaseTTL)
+	if err != nil {
+		return 0, err
+	}
+
+	if lease.Owner != a.instanceID {
+		return 0, &roomElsewhereError{URL: lease.URL}
+	}
+	return lease.Epoch, nil
+}
+
+// holdRoom recibe los eventos de la sala y renueva su lease mientras esté
+// abierta. Si lo pierde, la sala se cierra y los clientes vuelven a entrar
+// por la instancia que lo tomó
+func (a *API) holdRoom(roomID string, room *RoomData) {
+	// El broker no espera a la sala: los eventos se encolan y los aplica la
+	// goroutine de abajo
+	events := make(chan []byte, roomEventQueueSize)
+	unsubscribe, err := a.broker.Subscribe(roomTopic(roomID), func(msg []byte) {
+		select {
+		case events <- msg:
+		default:
+			log.Println("Cola de eventos llena, se descarta el evento de la sala ", roomID)
+		}
+	})
+	if err != nil {
+		log.Println("Error suscribiendo la sala: ", err)
+		unsubscribe = func() {}
+	}
+
+	go func() {
+		ticker := time.NewTicker(roomLeaseRenew)
+		defer ticker.Stop()
+		defer unsubscribe()
+
+		renewed := time.Now()
+
+		for {
+			select {
+			case msg := <-events:
+				a.handleRoomEvent(roomID, room, msg)
+
+			case <-ticker.C:
+				epoch, err := a.acquireRoom(context.Background(), roomID)
+
+				var elsewhere *roomElsewhereError
+				switch {
+				case err == nil && epoch == room.epoch:
+					renewed = time.Now()
+					continue
+				case err == nil:
+					// Venció y se volvió a tomar: otra instancia pudo abrirla mientras tanto
+					log.Println("La sala ", roomID, " se volvió a tomar, se cierra")
+				case errors.As(err, &elsewhere):
+					log.Println("La sala ", roomID, " pasó a otra instancia")
+				case time.Since(renewed) < roomLeaseTTL:
+					log.Println("Error renovando la sala: ", err)
+					continue
+				default:
+					log.Println("No se pudo renovar la sala ", roomID, ", se cierra: ", err)
+				}
+
+				a.closeRoom(roomID, room, closeMoved)
+				return
+
+			case <-room.quit:
+				// El lease lo suelta dropRoom después de guardar la sala
+				return
+			}
+		}
+	}()
+}
+
+// closeRoom desconecta a los usuarios, guarda la sala y la saca de memoria. Si
+// la sala pasó a otra instancia el guardado se rechaza, y lo que no se guardó
+// lo recupera esa instancia con el registro de operaciones
+func (a *API) closeRoom(roomID string, room *RoomData, reason string) {
+	var snapshot *models.Project
+	err := room.do(func() {
+		if err := room.disconnectUsers(closeMessage(reason)); err != nil {
+			log.Println("Disconnect: ", err)
+		}
+		snapshot = room.stopRoom()
+	})
+	if err != nil {
+		return // ya la cerró otro
+	}
+
+	if reason != closeDeleted {
+		if err := a.repo.SaveRoom(context.Background(), *snapshot); err != nil {
+			log.Println("Error al guardar la sala ", roomID, ": ", err)
+		}
+	}
+
+	a.dropRoom(roomID, room)
+}
+
+func (a *API) handleRoomEvent(roomID string, room *RoomData, msg []byte) {
+	var event roomEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		log.Println("Error al deserializar: ", err)
+		return
+	}
+
+	switch event.Type {
+
+	case roomEventBroadcast:
+		var msgData map[string]interface{}
+		if err := json.Unmarshal(event.Message, &msgData); err != nil {
+			log.Println("Error al deserializar: ", err)
+			return
+		}
+		action, _ := msgData["action"].(string)
+		room.do(func() {
+			sendSocketMessage(msgData, room, action)
+		})
+
+	case roomEventDirect:
+		// Fuera de la secuencia de stream.go: los que no son miembros no deben
+		// recibirlo con resync
+		room.do(func() {
+			for _, u := range room.Active {
+				if !isMember(room.ProjectInfo, u.Email) {
+					continue
+				}
+				if event.AllMembers || contains(event.Users, u.Email) {
+					u.Conn.Send(event.Message)
+				}
+			}
+		})
+
+	case roomEventMembers:
+		if event.Members == nil {
+			return
+		}
+		room.do(func() {
+			room.ProjectInfo.Members = *event.Members
+		})
+
+	case roomEventDeleted:
+		a.closeRoom(roomID, room, closeDeleted)
+	}
+}
+
+// publishRoom manda el evento a la instancia que tiene abierta la sala, si hay una
+func (a *API) publishRoom(roomID string, event roomEvent) {
+	msg, err := json.Marshal(event)
+	if err != nil {
+		log.Println("Error serializing message:", err)
+		return
+	}
+
+	if err := a.broker.Publish(context.Background(), roomTopic(roomID), msg); err != nil {
+		log.Println("Error publicando el evento de la sala: ", err)
+	}
+}
+
+// publishMembers avisa a la sala que sus miembros cambiaron en la base de datos
+func (a *API) publishMembers(ctx context.Context, roomID string) {
+	members, err := a.repo.GetMembers(ctx, roomID)
+	if err != nil {
+		log.Println("Error leyendo los miembros: ", err)
+		return
+	}
+
+	a.publishRoom(roomID, roomEvent{Type: roomEventMembers, Members: members})
+}
+
+// handleRoomError responde a los errores de instanceRoom. Si la sala está
+// abierta en otra instancia se redirige el pedido a ella
+func (a *API) handleRoomError(c echo.Context, err error) error {
+	var elsewhere *roomElsewhereError
+	if errors.As(err, &elsewhere) {
+		if elsewhere.URL == "" {
+			return a.handleError(c, http.StatusServiceUnavailable, err.Error())
+		}
+		return c.Redirect(http.StatusTemporaryRedirect, elsewhere.URL+c.Request().URL.RequestURI())
+	}
+
+	return a.handleError(c, http.StatusNotFound, "Room not found")
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/ProyectoT/api/internal/repository"
+)
+
+func TestLeaseRenewKeepsEpoch(t *testing.T) {
+	repo := newFakeRepo()
+	a := newTestAPI(t, repo, "a")
+	b := newTestAPI(t, repo, "b")
+	roomID := repo.addProject(models.Project{})
+
+	epoch, err := a.acquireRoom(context.Background(), roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed, err := a.acquireRoom(context.Background(), roomID); err != nil || renewed != epoch {
+		t.Fatalf("renew = %d, %v, want epoch %d", renewed, err, epoch)
+	}
+
+	var elsewhere *roomElsewhereError
+	if _, err := b.acquireRoom(context.Background(), roomID); !errors.As(err, &elsewhere) || elsewhere.URL != a.instanceURL {
+		t.Fatalf("acquire by another instance = %v, want a redirect to %s", err, a.instanceURL)
+	}
+
+	// Si vence, volver a tomarla cambia la época aunque sea la misma instancia
+	repo.expireLease(roomID)
+	if again, err := a.acquireRoom(context.Background(), roomID); err != nil || again == epoch {
+		t.Fatalf("acquire after expiry = %d, %v, want a new epoch", again, err)
+	}
+}
+
+func TestLeaseTakeoverFencesSaves(t *testing.T) {
+	repo := newFakeRepo()
+	a := newTestAPI(t, repo, "a")
+	b := newTestAPI(t, repo, "b")
+	roomID := repo.addProject(models.Project{})
+
+	roomA, err := a.instanceRoom(context.Background(), roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomA.do(func() { roomA.ProjectInfo.Name = "a" })
+
+	// a deja de renovar y b toma la sala. Las dos instancias comparten el
+	// mapa de salas en la prueba, así que se saca la de a a mano
+	repo.expireLease(roomID)
+	rooms.Delete(roomID)
+
+	roomB, err := b.instanceRoom(context.Background(), roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roomB == roomA || roomB.epoch <= roomA.epoch {
+		t.Fatalf("takeover epoch = %d, want more than %d", roomB.epoch, roomA.epoch)
+	}
+
+	var snapshot *models.Project
+	roomA.do(func() { snapshot = roomA.snapshot() })
+	if err := repo.SaveRoom(context.Background(), *snapshot); !errors.Is(err, repository.ErrFenced) {
+		t.Fatalf("save by the old owner = %v, want %v", err, repository.ErrFenced)
+	}
+
+	// Al cerrarse, a no pisa lo de b ni le suelta el lease
+	saves := repo.saveCount()
+	a.closeRoom(roomID, roomA, closeMoved)
+	if repo.saveCount() != saves {
+		t.Error("the old owner saved the room after losing it")
+	}
+	if lease, ok := repo.lease(roomID); !ok || lease.Owner != "b" {
+		t.Errorf("lease = %+v, %v, want it held by b", lease, ok)
+	}
+	if loaded, ok := rooms.Load(roomID); !ok || loaded != roomB {
+		t.Error("closing the old room dropped the new one")
+	}
+
+	roomB.do(func() { roomB.ProjectInfo.Name = "b" })
+	a.closeIfIdle(roomID, roomB, "b@test.com")
+	if name := repo.project(roomID).ProjectInfo.Name; name != "b" {
+		t.Errorf("saved name = %q, want the new owner's change", name)
+	}
+}
+
+func TestCloseRoomSaves(t *testing.T) {
+	repo := newFakeRepo()
+	a := newTestAPI(t, repo, "a")
+	roomID := repo.addProject(models.Project{})
+
+	room, err := a.instanceRoom(context.Background(), roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := testUser("a@test.com")
+	room.do(func() {
+		room.Active["c1"] = user
+		room.ProjectInfo.Name = "editado"
+	})
+
+	a.closeRoom(roomID, room, closeMoved)
+
+	if name := repo.project(roomID).ProjectInfo.Name; name != "editado" {
+		t.Errorf("saved name = %q, want the last change", name)
+	}
+	if _, ok := repo.lease(roomID); ok {
+		t.Error("the lease was not released")
+	}
+	if _, ok := rooms.Load(roomID); ok {
+		t.Error("the closed room is still in memory")
+	}
+	if msgs := received(user); len(msgs) == 0 {
+		t.Error("the user was not told to reconnect")
+	}
+}
@@ -0,0 +1,791 @@
+package api
+
+import (
+	"github.com/ProyectoT/api/internal/api/dtos"
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// command es una acción del historial de deshacer. Sus campos guardan todo lo
+// que necesita para aplicarse y revertirse, así se puede guardar con el
+// proyecto como JSON. Ver undoChanges.go
+type command interface {
+	Execute(room *RoomData) error
+	Undo(room *RoomData) error
+}
+
+// newCommand devuelve un comando vacío del tipo de la acción, para decodificarlo
+func newCommand(action string) command {
+	switch action {
+	case "deletetokenLink":
+		return &deleteTokenCommand{}
+	case "infoP":
+		return &infoCommand{}
+	case "añadir":
+		return &addRowCommand{}
+	case "delete":
+		return &deleteRowCommand{}
+	case "drop":
+		return &moveRowCommand{}
+	case "importRows":
+		return &importRowsCommand{}
+	case "addCircle":
+		return &addCircleCommand{}
+	case "deleteCircle":
+		return &deleteCircleCommand{}
+	case "editCircle":
+		return &editCircleCommand{}
+	case "editText":
+		return &editTextCommand{}
+	case "editPolygon":
+		return &editPolygonCommand{}
+	case "addFosil":
+		return &addFosilCommand{}
+	case "editFosil":
+		return &editFosilCommand{}
+	case "deleteFosil":
+		return &deleteFosilCommand{}
+	case "addMuestra":
+		return &addMuestraCommand{}
+	case "editMuestra":
+		return &editMuestraCommand{}
+	case "deleteMuestra":
+		return &deleteMuestraCommand{}
+	case "addFacie":
+		return &addFacieCommand{}
+	case "deleteFacie":
+		return &deleteFacieCommand{}
+	case "addFacieSection":
+		return &addFacieSectionCommand{}
+	case "deleteFacieSection":
+		return &deleteFacieSectionCommand{}
+	case "addLog":
+		return &addLogCommand{}
+	case "deleteLog":
+		return &deleteLogCommand{}
+	case "addColumn":
+		return &addColumnCommand{}
+	case "deleteColumn":
+		return &deleteColumnCommand{}
+	case "isInverted":
+		return &invertCommand{}
+	case "toggleColumn":
+		return &toggleColumnCommand{}
+	case "MoveColumn":
+		return &moveColumnCommand{}
+	case "batch":
+		return &batchCommand{}
+	default:
+		return nil
+	}
+}
+
+// Proyecto
+
+type deleteTokenCommand struct {
+	Pass string `json:"pass"`
+}
+
+func (c *deleteTokenCommand) Execute(room *RoomData) error {
+	if room.Shared.Pass != c.Pass {
+		return errConflict
+	}
+	removeSharedPass(room)
+	return nil
+}
+
+func (c *deleteTokenCommand) Undo(room *RoomData) error {
+	if room.Shared.Pass != "" {
+		return errConflict
+	}
+	restoreSharedPass(room, c.Pass)
+	return nil
+}
+
+type infoCommand struct {
+	Old dtos.EditInfoProject `json:"old"`
+	New dtos.EditInfoProject `json:"new"`
+}
+
+func (c *infoCommand) set(room *RoomData, from dtos.EditInfoProject, to dtos.EditInfoProject) error {
+	info := room.ProjectInfo
+	if info.Name != from.Name || info.Location != from.Location || info.Visible != from.Visible || info.Description != from.Description {
+		return errConflict
+	}
+	room.editInfoProject(to)
+	return nil
+}
+
+func (c *infoCommand) Execute(room *RoomData) error { return c.set(room, c.Old, c.New) }
+func (c *infoCommand) Undo(room *RoomData) error    { return c.set(room, c.New, c.Old) }
+
+// Capas
+
+type addRowCommand struct {
+	Row   models.DataInfo `json:"row"`
+	Index int             `json:"index"` // posición pedida por el cliente, -1 al final
+	Prev  string          `json:"prev"`  // capa de arriba al deshacer
+	Added bool            `json:"added"`
+}
+
+func (c *addRowCommand) Execute(room *RoomData) error {
+	if !c.Added {
+		añadir(room, dtos.Add{RowIndex: c.Index}, c.Row)
+		c.Added = true
+		return nil
+	}
+	return insertRow(room, c.Prev, c.Index, c.Row)
+}
+
+func (c *addRowCommand) Undo(room *RoomData) error {
+	row, prev, _, err := removeRow(room, c.Row.ID)
+	if err != nil {
+		return err
+	}
+	c.Row, c.Prev = row, prev
+	return nil
+}
+
+type deleteRowCommand struct {
+	ID    string          `json:"id"`
+	Row   models.DataInfo `json:"row"`
+	Prev  string          `json:"prev"`
+	Index int             `json:"index"`
+}
+
+func (c *deleteRowCommand) Execute(room *RoomData) error {
+	row, prev, index, err := removeRow(room, c.ID)
+	if err != nil {
+		return err
+	}
+	c.Row, c.Prev, c.Index = row, prev, index
+	return nil
+}
+
+func (c *deleteRowCommand) Undo(room *RoomData) error {
+	return insertRow(room, c.Prev, c.Index, c.Row)
+}
+
+type moveRowCommand struct {
+	ID         string `json:"id"`
+	ActiveId   int    `json:"activeId"`
+	OverId     int    `json:"overId"`
+	PrevBefore string `json:"prevBefore"`
+	PrevAfter  string `json:"prevAfter"`
+	Moved      bool   `json:"moved"`
+}
+
+func (c *moveRowCommand) Execute(room *RoomData) error {
+	if !c.Moved {
+		layerDrop(room, c.ActiveId, c.OverId)
+		c.PrevAfter = room.prevRowID(room.rowIndex(c.ID))
+		c.Moved = true
+		return nil
+	}
+	return moveRow(room, c.ID, c.PrevAfter)
+}
+
+func (c *moveRowCommand) Undo(room *RoomData) error {
+	return moveRow(room, c.ID, c.PrevBefore)
+}
+
+type importRowsCommand struct {
+	Rows    []models.DataInfo `json:"rows"`
+	Columns []models.Column   `json:"columns,omitempty"` // columnas que trae la planilla
+	Added   []string          `json:"added,omitempty"`   // las que agregó al aplicarse
+
+	Values map[string]map[string]interface{} `json:"values,omitempty"` // valores de las columnas al deshacer
+}
+
+func (c *importRowsCommand) Execute(room *RoomData) error {
+	c.Added = nil
+	for _, column := range c.Columns {
+		if hasColumn(room.Config.Columns, column.Name) {
+			continue
+		}
+		addColumn(room, len(room.Config.Columns), column, c.Values[column.Name])
+		c.Added = append(c.Added, column.Name)
+	}
+
+	added := 0
+	for _, row := range c.Rows {
+		if room.rowIndex(row.ID) == -1 {
+			añadir(room, dtos.Add{RowIndex: -1}, row)
+			added++
+		}
+	}
+	if added == 0 {
+		c.removeColumns(room)
+		return errConflict
+	}
+	return nil
+}
+
+func (c *importRowsCommand) Undo(room *RoomData) error {
+	removed := 0
+	for i, row := range c.Rows {
+		if current, _, _, err := removeRow(room, row.ID); err == nil {
+			c.Rows[i] = current
+			removed++
+		}
+	}
+	if removed == 0 {
+		return errLayerNotFound
+	}
+
+	c.removeColumns(room)
+	return nil
+}
+
+// removeColumns quita las columnas que agregó el comando, guardando los valores
+// que otros usuarios hayan cargado en ellas
+func (c *importRowsCommand) removeColumns(room *RoomData) {
+	c.Values = nil
+	for i := len(c.Added) - 1; i >= 0; i-- {
+		index, values := deleteColumn(room, c.Added[i])
+		if index == -1 || len(values) == 0 {
+			continue
+		}
+		if c.Values == nil {
+			c.Values = make(map[string]map[string]interface{})
+		}
+		c.Values[c.Added[i]] = values
+	}
+	c.Added = nil
+}
+
+type editTextCommand struct {
+	RowID string      `json:"rowId"`
+	Key   string      `json:"key"`
+	Old   interface{} `json:"old"` // valor original con su tipo, nil si la celda no existía
+	New   string      `json:"new"`
+}
+
+func (c *editTextCommand) set(room *RoomData, from interface{}, to interface{}) error {
+	i := room.rowIndex(c.RowID)
+	if i == -1 {
+		return errLayerNotFound
+	}
+	if cellValue(room.Data[i], c.Key) != cellText(from) {
+		return errConflict
+	}
+	editText(room, i, c.Key, to)
+	return nil
+}
+
+func (c *editTextCommand) Execute(room *RoomData) error { return c.set(room, c.Old, c.New) }
+func (c *editTextCommand) Undo(room *RoomData) error    { return c.set(room, c.New, c.Old) }
+
+type editPolygonCommand struct {
+	RowID  string      `json:"rowId"`
+	Column string      `json:"column"`
+	Old    string      `json:"old"`
+	Value  interface{} `json:"value"`
+	New    string      `json:"new"` // valor que quedó, para comprobarlo al deshacer
+}
+
+func (c *editPolygonCommand) set(room *RoomData, from string, value interface{}) (string, error) {
+	i := room.rowIndex(c.RowID)
+	if i == -1 {
+		return "", errLayerNotFound
+	}
+	if GetFieldString(room.Data[i].Litologia, c.Column) != from {
+		return "", errConflict
+	}
+	editPolygon(room, dtos.EditPolygon{RowIndex: i, Column: c.Column, Value: value})
+	return GetFieldString(room.Data[i].Litologia, c.Column), nil
+}
+
+func (c *editPolygonCommand) Execute(room *RoomData) error {
+	value, err := c.set(room, c.Old, c.Value)
+	if err != nil {
+		return err
+	}
+	c.New = value
+	return nil
+}
+
+func (c *editPolygonCommand) Undo(room *RoomData) error {
+	_, err := c.set(room, c.New, c.Old)
+	return err
+}
+
+// Puntos del polígono
+
+type addCircleCommand struct {
+	RowID  string             `json:"rowId"`
+	Index  int                `json:"index"`
+	Circle models.CircleStruc `json:"circle"`
+}
+
+func (c *addCircleCommand) Execute(room *RoomData) error {
+	i := room.rowIndex(c.RowID)
+	if i == -1 {
+		return errLayerNotFound
+	}
+	if c.Index > len(room.Data[i].Litologia.Circles) {
+		return errConflict
+	}
+	addCircle(room, dtos.AddCircle{RowIndex: i, InsertIndex: c.Index}, c.Circle)
+	return nil
+}
+
+func (c *addCircleCommand) Undo(room *RoomData) error {
+	i := room.rowIndex(c.RowID)
+	if i == -1 {
+		return errLayerNotFound
+	}
+	index := findCircle(room.Data[i].Litologia.Circles, c.Circle, c.Index)
+	if index == -1 {
+		return errConflict
+	}
+	deleteCircle(room, dtos.DeleteCircle{RowIndex: i, DeleteIndex: index})
+	return nil
+}
+
+type deleteCircleCommand struct {
+	RowID  string             `json:"rowId"`
+	Index  int                `json:"index"`
+	Circle models.CircleStruc `json:"circle"`
+}
+
+func (c *deleteCircleCommand) Execute(room *RoomData) error {
+	i := room.rowIndex(c.RowID)
+	if i == -1 {
+		return errLayerNotFound
+	}
+	index := findCircle(room.Data[i].Litologia.Circles, c.Circle, c.Index)
+	if index == -1 {
+		return errConflict
+	}
+	c.Index = index
+	deleteCircle(room, dtos.DeleteCircle{RowIndex: i, DeleteIndex: index})
+	return nil
+}
+
+func (c *deleteCircleCommand) Undo(room *RoomData) error {
+	i := room.rowIndex(c.RowID)
+	if i == -1 {
+		return errLayerNotFound
+	}
+	if c.Index > len(room.Data[i].Litologia.Circles) {
+		return errConflict
+	}
+	addCircle(room, dtos.AddCircle{RowIndex: i, InsertIndex: c.Index}, c.Circle)
+	return nil
+}
+
+type editCircleCommand struct {
+	RowID string             `json:"rowId"`
+	Index int                `json:"index"`
+	Old   models.CircleStruc `json:"old"`
+	New   models.CircleStruc `json:"new"`
+}
+
+func (c *editCircleCommand) set(room *RoomData, from models.CircleStruc, to models.CircleStruc) error {
+	i := room.rowIndex(c.RowID)
+	if i == -1 {
+		return errLayerNotFound
+	}
+	index := findCircle(room.Data[i].Litologia.Circles, from, c.Index)
+	if index == -1 {
+		return errConflict
+	}
+	c.Index = index
+	editCircle(room, dtos.EditCircle{RowIndex: i, EditIndex: index, X: to.X, Name: to.Name})
+	return nil
+}
+
+func (c *editCircleCommand) Execute(room *RoomData) error { return c.set(room, c.Old, c.New) }
+func (c *editCircleCommand) Undo(room *RoomData) error    { return c.set(room, c.New, c.Old) }
+
+// Fósiles
+
+type addFosilCommand struct {
+	ID    string       `json:"id"`
+	Fosil models.Fosil `json:"fosil"`
+}
+
+func (c *addFosilCommand) Execute(room *RoomData) error {
+	if _, exists := room.Fosil[c.ID]; exists {
+		return errConflict
+	}
+	addFosil(room, c.ID, c.Fosil)
+	return nil
+}
+
+func (c *addFosilCommand) Undo(room *RoomData) error {
+	current, exists := room.Fosil[c.ID]
+	if !exists {
+		return errNotFound
+	}
+	if current != c.Fosil {
+		return errConflict
+	}
+	deleteFosil(room, dtos.DeleteFosil{IdFosil: c.ID})
+	return nil
+}
+
+type editFosilCommand struct {
+	ID  string       `json:"id"`
+	Old models.Fosil `json:"old"`
+	New models.Fosil `json:"new"`
+}
+
+func (c *editFosilCommand) set(room *RoomData, from models.Fosil, to models.Fosil) error {
+	current, exists := room.Fosil[c.ID]
+	if !exists {
+		return errNotFound
+	}
+	if current != from {
+		return errConflict
+	}
+	editFosil(room, c.ID, to)
+	return nil
+}
+
+func (c *editFosilCommand) Execute(room *RoomData) error { return c.set(room, c.Old, c.New) }
+func (c *editFosilCommand) Undo(room *RoomData) error    { return c.set(room, c.New, c.Old) }
+
+type deleteFosilCommand struct {
+	ID    string       `json:"id"`
+	Fosil models.Fosil `json:"fosil"`
+}
+
+func (c *deleteFosilCommand) Execute(room *RoomData) error {
+	current, exists := room.Fosil[c.ID]
+	if !exists {
+		return errNotFound
+	}
+	if current != c.Fosil {
+		return errConflict
+	}
+	deleteFosil(room, dtos.DeleteFosil{IdFosil: c.ID})
+	return nil
+}
+
+func (c *deleteFosilCommand) Undo(room *RoomData) error {
+	if _, exists := room.Fosil[c.ID]; exists {
+		return errConflict
+	}
+	addFosil(room, c.ID, c.Fosil)
+	return nil
+}
+
+// Muestras
+
+type addMuestraCommand struct {
+	ID      string         `json:"id"`
+	Muestra models.Muestra `json:"muestra"`
+}
+
+func (c *addMuestraCommand) Execute(room *RoomData) error {
+	if _, exists := room.Muestras[c.ID]; exists {
+		return errConflict
+	}
+	addMuestra(room, c.ID, c.Muestra)
+	return nil
+}
+
+func (c *addMuestraCommand) Undo(room *RoomData) error {
+	current, exists := room.Muestras[c.ID]
+	if !exists {
+		return errNotFound
+	}
+	if current != c.Muestra {
+		return errConflict
+	}
+	deleteMuestra(room, dtos.DeleteMuestra{IdMuestra: c.ID})
+	return nil
+}
+
+type editMuestraCommand struct {
+	ID  string         `json:"id"`
+	Old models.Muestra `json:"old"`
+	New models.Muestra `json:"new"`
+}
+
+func (c *editMuestraCommand) set(room *RoomData, from models.Muestra, to models.Muestra) error {
+	current, exists := room.Muestras[c.ID]
+	if !exists {
+		return errNotFound
+	}
+	if current != from {
+		return errConflict
+	}
+	editMuestra(room, c.ID, to)
+	return nil
+}
+
+func (c *editMuestraCommand) Execute(room *RoomData) error { return c.set(room, c.Old, c.New) }
+func (c *editMuestraCommand) Undo(room *RoomData) error    { return c.set(room, c.New, c.Old) }
+
+type deleteMuestraCommand struct {
+	ID      string         `json:"id"`
+	Muestra models.Muestra `json:"muestra"`
+}
+
+func (c *deleteMuestraCommand) Execute(room *RoomData) error {
+	current, exists := room.Muestras[c.ID]
+	if !exists {
+		return errNotFound
+	}
+	if current != c.Muestra {
+		return errConflict
+	}
+	deleteMuestra(room, dtos.DeleteMuestra{IdMuestra: c.ID})
+	return nil
+}
+
+func (c *deleteMuestraCommand) Undo(room *RoomData) error {
+	if _, exists := room.Muestras[c.ID]; exists {
+		return errConflict
+	}
+	addMuestra(room, c.ID, c.Muestra)
+	return nil
+}
+
+// Facies
+
+type addFacieCommand struct {
+	Facie string `json:"facie"`
+}
+
+func (c *addFacieCommand) Execute(room *RoomData) error {
+	if _, exists := room.Facies[c.Facie]; exists {
+		return errConflict
+	}
+	addFacie(room, dtos.Facie{Facie: c.Facie}, nil)
+	return nil
+}
+
+func (c *addFacieCommand) Undo(room *RoomData) error {
+	sections, exists := room.Facies[c.Facie]
+	if !exists {
+		return errNotFound
+	}
+	if len(sections) > 0 {
+		return errConflict
+	}
+	deleteFacie(room, dtos.Facie{Facie: c.Facie})
+	return nil
+}
+
+type deleteFacieCommand struct {
+	Facie    string                 `json:"facie"`
+	Sections []models.FaciesSection `json:"sections"`
+}
+
+func (c *deleteFacieCommand) Execute(room *RoomData) error {
+	sections, exists := room.Facies[c.Facie]
+	if !exists {
+		return errNotFound
+	}
+	c.Sections = sections
+	deleteFacie(room, dtos.Facie{Facie: c.Facie})
+	return nil
+}
+
+func (c *deleteFacieCommand) Undo(room *RoomData) error {
+	if _, exists := room.Facies[c.Facie]; exists {
+		return errConflict
+	}
+	sections := c.Sections
+	if sections == nil {
+		sections = []models.FaciesSection{}
+	}
+	addFacie(room, dtos.Facie{Facie: c.Facie}, sections)
+	return nil
+}
+
+type addFacieSectionCommand struct {
+	Facie   string               `json:"facie"`
+	Index   int                  `json:"index"`
+	Section models.FaciesSection `json:"section"`
+}
+
+func (c *addFacieSectionCommand) Execute(room *RoomData) error {
+	if _, exists := room.Facies[c.Facie]; !exists {
+		return errNotFound
+	}
+	addFacieSection(room, dtos.AddFacieSection{Facie: c.Facie, Index: c.Index}, c.Section)
+	return nil
+}
+
+func (c *addFacieSectionCommand) Undo(room *RoomData) error {
+	index := findSection(room.Facies[c.Facie], c.Section, c.Index)
+	if index == -1 {
+		return errConflict
+	}
+	deleteFacieSection(room, dtos.DeleteFacieSection{Facie: c.Facie, Index: index})
+	return nil
+}
+
+type deleteFacieSectionCommand struct {
+	Facie   string               `json:"facie"`
+	Index   int                  `json:"index"`
+	Section models.FaciesSection `json:"section"`
+}
+
+func (c *deleteFacieSectionCommand) Execute(room *RoomData) error {
+	index := findSection(room.Facies[c.Facie], c.Section, c.Index)
+	if index == -1 {
+		return errConflict
+	}
+	c.Index = index
+	deleteFacieSection(room, dtos.DeleteFacieSection{Facie: c.Facie, Index: index})
+	return nil
+}
+
+func (c *deleteFacieSectionCommand) Undo(room *RoomData) error {
+	if _, exists := room.Facies[c.Facie]; !exists {
+		return errNotFound
+	}
+	addFacieSection(room, dtos.AddFacieSection{Facie: c.Facie, Index: c.Index}, c.Section)
+	return nil
+}
+
+// Registros de pozo
+
+type addLogCommand struct {
+	ID  string         `json:"id"`
+	Log models.WellLog `json:"log"`
+}
+
+func (c *addLogCommand) Execute(room *RoomData) error {
+	if _, exists := room.Logs[c.ID]; exists {
+		return errConflict
+	}
+	addLog(room, c.ID, c.Log)
+	return nil
+}
+
+func (c *addLogCommand) Undo(room *RoomData) error {
+	if _, exists := room.Logs[c.ID]; !exists {
+		return errNotFound
+	}
+	deleteLog(room, dtos.DeleteLog{IdLog: c.ID})
+	return nil
+}
+
+type deleteLogCommand struct {
+	ID  string         `json:"id"`
+	Log models.WellLog `json:"log"`
+}
+
+func (c *deleteLogCommand) Execute(room *RoomData) error {
+	current, exists := room.Logs[c.ID]
+	if !exists {
+		return errNotFound
+	}
+	c.Log = current
+	deleteLog(room, dtos.DeleteLog{IdLog: c.ID})
+	return nil
+}
+
+func (c *deleteLogCommand) Undo(room *RoomData) error {
+	if _, exists := room.Logs[c.ID]; exists {
+		return errConflict
+	}
+	addLog(room, c.ID, c.Log)
+	return nil
+}
+
+// Columnas y configuración
+
+type addColumnCommand struct {
+	Column models.Column          `json:"column"`
+	Values map[string]interface{} `json:"values"` // valores por ID de capa al deshacer
+}
+
+func (c *addColumnCommand) Execute(room *RoomData) error {
+	if hasColumn(room.Config.Columns, c.Column.Name) {
+		return errColumnExists
+	}
+	addColumn(room, len(room.Config.Columns), c.Column, c.Values)
+	return nil
+}
+
+func (c *addColumnCommand) Undo(room *RoomData) error {
+	if !hasColumn(room.Config.Columns, c.Column.Name) {
+		return errNotFound
+	}
+	_, c.Values = deleteColumn(room, c.Column.Name)
+	return nil
+}
+
+type deleteColumnCommand struct {
+	Column models.Column          `json:"column"`
+	Index  int                    `json:"index"`
+	Values map[string]interface{} `json:"values"` // valores por ID de capa
+}
+
+func (c *deleteColumnCommand) Execute(room *RoomData) error {
+	if !hasColumn(room.Config.Columns, c.Column.Name) {
+		return errNotFound
+	}
+	c.Index, c.Values = deleteColumn(room, c.Column.Name)
+	return nil
+}
+
+func (c *deleteColumnCommand) Undo(room *RoomData) error {
+	if hasColumn(room.Config.Columns, c.Column.Name) {
+		return errColumnExists
+	}
+	addColumn(room, c.Index, c.Column, c.Values)
+	return nil
+}
+
+type invertCommand struct {
+	Old bool `json:"old"`
+	New bool `json:"new"`
+}
+
+func (c *invertCommand) set(room *RoomData, from bool, to bool) error {
+	if room.Config.IsInverted != from {
+		return errConflict
+	}
+	isInverted(room, to)
+	return nil
+}
+
+func (c *invertCommand) Execute(room *RoomData) error { return c.set(room, c.Old, c.New) }
+func (c *invertCommand) Undo(room *RoomData) error    { return c.set(room, c.New, c.Old) }
+
+type toggleColumnCommand struct {
+	Column  string `json:"column"`
+	Visible bool   `json:"visible"` // visibilidad antes del cambio
+}
+
+func (c *toggleColumnCommand) toggle(room *RoomData, from bool) error {
+	index := columnIndex(room.Config.Columns, c.Column)
+	if index == -1 {
+		return errNotFound
+	}
+	if room.Config.Columns[index].Visible != from {
+		return errConflict
+	}
+	toggleColumn(room, c.Column)
+	return nil
+}
+
+func (c *toggleColumnCommand) Execute(room *RoomData) error { return c.toggle(room, c.Visible) }
+func (c *toggleColumnCommand) Undo(room *RoomData) error    { return c.toggle(room, !c.Visible) }
+
+// moveColumnCommand solo se aplica si la columna sigue donde quedó, porque
+// otro usuario pudo agregar o quitar columnas
+type moveColumnCommand struct {
+	Column string `json:"column"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+}
+
+func (c *moveColumnCommand) move(room *RoomData, from int, to int) error {
+	if columnIndex(room.Config.Columns, c.Column) != from || to >= len(room.Config.Columns) {
+		return errConflict
+	}
+	moveColumn(room, from, to)
+	return nil
+}
+
+func (c *moveColumnCommand) Execute(room *RoomData) error { return c.move(room, c.From, c.To) }
+func (c *moveColumnCommand) Undo(room *RoomData) error    { return c.move(room, c.To, c.From) }
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// editOperation arma una operación de edición con los datos serializados
+func editOperation(t *testing.T, user string, action string, data interface{}) *models.Operation {
+	t.Helper()
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.Operation{User: user, Action: action, Data: raw}
+}
+
+func TestAddFosilAndMuestraRejectNegativeBounds(t *testing.T) {
+	tests := []struct {
+		action string
+		data   map[string]interface{}
+		err    error
+	}{
+		{"addFosil", map[string]interface{}{"upper": -1, "lower": 5, "fosilImg": "a"}, errInvalidData},
+		{"addFosil", map[string]interface{}{"upper": 1, "lower": -5, "fosilImg": "a"}, errInvalidData},
+		{"addFosil", map[string]interface{}{"upper": 0, "lower": 5, "fosilImg": "a"}, nil},
+		{"addMuestra", map[string]interface{}{"upper": -1, "lower": 5, "muestraText": "m"}, errInvalidData},
+		{"addMuestra", map[string]interface{}{"upper": 1, "lower": -5, "muestraText": "m"}, errInvalidData},
+		{"addMuestra", map[string]interface{}{"upper": 1, "lower": 5, "muestraText": "m"}, nil},
+	}
+
+	for _, tt := range tests {
+		room := testRoom()
+		op := editOperation(t, "a@test.com", tt.action, tt.data)
+
+		err := applyOperation(room, op)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s %v = %v, want %v", tt.action, tt.data, err, tt.err)
+			continue
+		}
+
+		added := len(room.Fosil) + len(room.Muestras)
+		stack := len(room.undoStacks[op.User])
+		if tt.err != nil && (added != 0 || stack != 0 || room.streamSeq != 0) {
+			t.Errorf("%s %v was applied: %d added, %d in history, seq %d", tt.action, tt.data, added, stack, room.streamSeq)
+		}
+		if tt.err == nil && (added != 1 || stack != 1) {
+			t.Errorf("%s %v: %d added, %d in history, want 1 and 1", tt.action, tt.data, added, stack)
+		}
+	}
+}
+
+func TestDeleteColumnUndoRestoresValuesByRow(t *testing.T) {
+	room := testRoom()
+	want := map[string]interface{}{}
+	for i := range room.Data {
+		value := string(rune('a' + i))
+		room.Data[i].Columns["Notas"] = value
+		want[room.Data[i].ID] = value
+	}
+
+	if err := applyOperation(room, editOperation(t, "a@test.com", "deleteColumn", map[string]interface{}{"name": "Notas"})); err != nil {
+		t.Fatal(err)
+	}
+
+	// Otro usuario reordena las capas y agrega una arriba
+	if err := applyOperation(room, editOperation(t, "b@test.com", "drop", map[string]interface{}{"activeId": 0, "overId": 2})); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyOperation(room, editOperation(t, "b@test.com", "añadir", map[string]interface{}{"rowIndex": 0})); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applyOperation(room, &models.Operation{User: "a@test.com", Action: "undo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if index := columnIndex(room.Config.Columns, "Notas"); index != 1 {
+		t.Errorf("column index = %d, want 1", index)
+	}
+	for _, row := range room.Data {
+		value, ok := row.Columns["Notas"]
+		expected, known := want[row.ID]
+		if !known {
+			if ok {
+				t.Errorf("new layer %s got value %v", row.ID, value)
+			}
+			continue
+		}
+		if value != expected {
+			t.Errorf("layer %s = %v, want %v", row.ID, value, expected)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ProyectoT/api/internal/api/dtos"
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Hilos de comentarios del proyecto, anclados a una capa, un intervalo de
+// profundidad, un fósil, una muestra o una sección de facie. Solo los miembros
+// pueden verlos y comentar. Cada cambio se envía a la sala como "comment" y
+// los usuarios mencionados con @correo reciben además "mention".
+
+var commentLimit = 5000 // caracteres por comentario
+
+var errInvalidComment = fmt.Errorf("comment must not be empty or longer than %d characters", commentLimit)
+
+var errInvalidAnchor = errors.New("invalid anchor")
+
+// mentionPattern reconoce @correo en el texto
+var mentionPattern = regexp.MustCompile(`@([^\s@]+@[^\s@]+\.[^\s@]+)`)
+
+type ThreadsResponse struct {
+	Threads []models.Thread `json:"threads"`
+}
+
+// resolveAnchor comprueba que el lugar exista en el proyecto y lo convierte
+// en un ancla que no depende de la posición
+func resolveAnchor(project *models.Project, anchor dtos.ThreadAnchor) (models.Anchor, error) {
+	switch anchor.Type {
+
+	case models.AnchorLayer:
+		rowID := anchor.RowID
+		if anchor.RowIndex != nil {
+			if *anchor.RowIndex < 0 || *anchor.RowIndex >= len(project.Data) {
+				return models.Anchor{}, errLayerNotFound
+			}
+			rowID = project.Data[*anchor.RowIndex].ID
+		}
+		for _, row := range project.Data {
+			if rowID != "" && row.ID == rowID {
+				return models.Anchor{Type: anchor.Type, RowID: rowID}, nil
+			}
+		}
+		return models.Anchor{}, errLayerNotFound
+
+	case models.AnchorDepth:
+		if anchor.Top < 0 || anchor.Bottom <= anchor.Top {
+			return models.Anchor{}, errInvalidAnchor
+		}
+		return models.Anchor{Type: anchor.Type, Top: anchor.Top, Bottom: anchor.Bottom}, nil
+
+	case models.AnchorFosil:
+		if _, ok := project.Fosil[anchor.ID]; !ok {
+			return models.Anchor{}, errNotFound
+		}
+		return models.Anchor{Type: anchor.Type, ID: anchor.ID}, nil
+
+	case models.AnchorMuestra:
+		if _, ok := project.Muestras[anchor.ID]; !ok {
+			return models.Anchor{}, errNotFound
+		}
+		return models.Anchor{Type: anchor.Type, ID: anchor.ID}, nil
+
+	case models.AnchorFacie:
+		sections := project.Facies[anchor.Facie]
+		if anchor.Index < 0 || anchor.Index >= len(sections) {
+			return models.Anchor{}, errNotFound
+		}
+		index := anchor.Index
+		located := models.Anchor{Type: anchor.Type, Facie: anchor.Facie, Index: &index}
+		locateAnchor(project, &located)
+		return located, nil
+	}
+
+	return models.Anchor{}, errInvalidAnchor
+}
+
+// locateAnchor completa Y1 y Y2 de un ancla de facie con la posición actual de
+// la sección. Si la sección ya no existe quedan en 0
+func locateAnchor(project *models.Project, anchor *models.Anchor) {
+	if anchor.Type != models.AnchorFacie || anchor.Index == nil {
+		return
+	}
+
+	sections := project.Facies[anchor.Facie]
+	if *anchor.Index < 0 || *anchor.Index >= len(sections) {
+		anchor.Y1, anchor.Y2 = 0, 0
+		return
+	}
+
+	section := sections[*anchor.Index]
+	anchor.Y1, anchor.Y2 = section.Y1, section.Y2
+}
+
+// locateThreads completa la posición de los hilos anclados a secciones de
+// facie. Solo lee el proyecto si hay alguno
+func (a *API) locateThreads(ctx context.Context, roomID string, user string, threads ...*models.Thread) {
+	needed := false
+	for _, thread := range threads {
+		if thread.Anchor.Type == models.AnchorFacie {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return
+	}
+
+	project, err := a.loadProject(ctx, roomID, user)
+	if err != nil {
+		log.Println("Error loading project:", err)
+		return
+	}
+
+	for _, thread := range threads {
+		locateAnchor(project, &thread.Anchor)
+	}
+}
+
+// parseMentions devuelve los miembros del proyecto mencionados en el texto
+func parseMentions(content string, info models.ProjectInfo, author string) []string {
+	mentions := []string{}
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		email := strings.TrimRight(match[1], ".,;:!?)")
+		if email == author || contains(mentions, email) || !isMember(info, email) {
+			continue
+		}
+		mentions = append(mentions, email)
+	}
+
+	return mentions
+}
+
+// newThreadComment valida el texto y arma el comentario
+func newThreadComment(content string, info models.ProjectInfo, author string) (models.ThreadComment, error) {
+	content = strings.TrimSpace(content)
+	if content == "" || len([]rune(content)) > commentLimit {
+		return models.ThreadComment{}, errInvalidComment
+	}
+
+	return models.ThreadComment{
+		ID:        primitive.NewObjectID(),
+		Author:    author,
+		Content:   content,
+		Mentions:  parseMentions(content, info, author),
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// notifyThread envía el hilo a los miembros conectados y avisa a los
+// mencionados. Pasa por el broker porque la sala puede estar abierta en otra
+// instancia
+func (a *API) notifyThread(roomID string, event string, thread *models.Thread, comment *models.ThreadComment) {
+	msg, err := json.Marshal(map[string]interface{}{
+		"action": "comment",
+		"event":  event,
+		"thread": thread,
+	})
+	if err != nil {
+		log.Println("Error serializing message:", err)
+		return
+	}
+
+	a.publishRoom(roomID, roomEvent{Type: roomEventDirect, Message: msg, AllMembers: true})
+
+	if comment == nil || len(comment.Mentions) == 0 {
+		return
+	}
+
+	mention, err := json.Marshal(map[string]interface{}{
+		"action":   "mention",
+		"threadId": thread.ID,
+		"by":       comment.Author,
+		"content":  comment.Content,
+	})
+	if err != nil {
+		log.Println("Error serializing message:", err)
+		return
+	}
+
+	a.publishRoom(roomID, roomEvent{Type: roomEventDirect, Message: mention, Users: comment.Mentions})
+}
+
+// HandleGetThreads lista los hilos del proyecto (?resolved=true|false)
+func (a *API) HandleGetThreads(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+
+	info, err := a.projectInfo(ctx, roomID)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	if !isMember(*info, user) {
+		return a.handleError(c, http.StatusForbidden, "Access denied")
+	}
+
+	var resolved *bool
+	switch c.QueryParam("resolved") {
+	case "true":
+		r := true
+		resolved = &r
+	case "false":
+		r := false
+		resolved = &r
+	}
+
+	threads, err := a.repo.GetThreads(ctx, roomID, resolved)
+	if err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Error getting comments")
+	}
+
+	located := make([]*models.Thread, len(threads))
+	for i := range threads {
+		located[i] = &threads[i]
+	}
+	a.locateThreads(ctx, roomID, user, located...)
+
+	return c.JSON(http.StatusOK, ThreadsResponse{Threads: threads})
+}
+
+// HandleCreateThread abre un hilo anclado a un lugar del proyecto
+func (a *API) HandleCreateThread(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+
+	var req dtos.NewThread
+	if err := c.Bind(&req); err != nil {
+		return a.handleError(c, http.StatusBadRequest, "Invalid request")
+	}
+
+	project, err := a.loadProject(ctx, roomID, user)
+	if err != nil {
+		return a.handleLoadError(c, err)
+	}
+
+	if !isMember(project.ProjectInfo, user) {
+		return a.handleError(c, http.StatusForbidden, "Access denied")
+	}
+
+	anchor, err := resolveAnchor(project, req.Anchor)
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+
+	comment, err := newThreadComment(req.Content, project.ProjectInfo, user)
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+
+	thread := models.Thread{
+		ProjectID: project.ID,
+		Anchor:    anchor,
+		Author:    user,
+		Comments:  []models.ThreadComment{comment},
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.CreatedAt,
+	}
+
+	id, err := a.repo.CreateThread(ctx, thread)
+	if err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Failed to create comment")
+	}
+	thread.ID, _ = primitive.ObjectIDFromHex(id)
+
+	a.notifyThread(roomID, "created", &thread, &comment)
+
+	return c.JSON(http.StatusCreated, thread)
+}
+
+// HandleReplyThread agrega una respuesta al hilo
+func (a *API) HandleReplyThread(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+
+	var req dtos.ThreadReply
+	if err := c.Bind(&req); err != nil {
+		return a.handleError(c, http.StatusBadRequest, "Invalid request")
+	}
+
+	info, err := a.projectInfo(ctx, roomID)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	if !isMember(*info, user) {
+		return a.handleError(c, http.StatusForbidden, "Access denied")
+	}
+
+	comment, err := newThreadComment(req.Content, *info, user)
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+
+	thread, err := a.repo.AddThreadComment(ctx, roomID, c.Param("thread"), comment)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Comment not found")
+	}
+
+	a.locateThreads(ctx, roomID, user, thread)
+	a.notifyThread(roomID, "reply", thread, &comment)
+
+	return c.JSON(http.StatusOK, thread)
+}
+
+// HandleResolveThread marca el hilo como resuelto
+func (a *API) HandleResolveThread(c echo.Context) error {
+	return a.setThreadResolved(c, true)
+}
+
+// HandleReopenThread vuelve a abrir un hilo resuelto
+func (a *API) HandleReopenThread(c echo.Context) error {
+	return a.setThreadResolved(c, false)
+}
+
+// setThreadResolved lo puede usar quien abrió el hilo, el dueño o un editor
+func (a *API) setThreadResolved(c echo.Context, resolved bool) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+	threadID := c.Param("thread")
+
+	info, err := a.projectInfo(ctx, roomID)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	if !isMember(*info, user) {
+		return a.handleError(c, http.StatusForbidden, "Access denied")
+	}
+
+	thread, err := a.repo.GetThread(ctx, roomID, threadID)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Comment not found")
+	}
+
+	if thread.Author != user && !canEdit(*info, user) {
+		return a.handleError(c, http.StatusForbidden, "Don't have permission to resolve this comment")
+	}
+
+	thread, err = a.repo.SetThreadResolved(ctx, roomID, threadID, resolved, user)
+	if err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Failed to update comment")
+	}
+	a.locateThreads(ctx, roomID, user, thread)
+
+	event := "resolved"
+	if !resolved {
+		event = "reopened"
+	}
+	a.notifyThread(roomID, event, thread, nil)
+
+	return c.JSON(http.StatusOK, thread)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/api/dtos"
+	"github.com/ProyectoT/api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestThreadEventsOnlyReachMembers(t *testing.T) {
+	a := newTestAPI(t, newFakeRepo(), "a")
+
+	room := testRoom()
+	room.ProjectInfo.Visible = true
+	room.ProjectInfo.Members.Owner = "owner@test.com"
+	room.ProjectInfo.Members.Editors = []string{"editor@test.com"}
+	room.start()
+	defer room.stop()
+
+	owner := testUser("owner@test.com")
+	editor := testUser("editor@test.com")
+	public := testUser("public@test.com")
+	room.do(func() {
+		room.Active["owner"] = owner
+		room.Active["editor"] = editor
+		room.Active["public"] = public
+	})
+
+	// Lo que publica notifyThread, entregado a la instancia dueña de la sala
+	roomID := room.ID.Hex()
+	unsubscribe, _ := a.broker.Subscribe(roomTopic(roomID), func(msg []byte) {
+		a.handleRoomEvent(roomID, room, msg)
+	})
+	defer unsubscribe()
+
+	thread := &models.Thread{}
+	comment := &models.ThreadComment{Author: "owner@test.com", Content: "mira", Mentions: []string{"editor@test.com", "public@test.com"}}
+	a.notifyThread(roomID, "created", thread, comment)
+
+	var ownerMessages, editorMessages, publicMessages []string
+	room.do(func() {
+		ownerMessages = received(owner)
+		editorMessages = received(editor)
+		publicMessages = received(public)
+	})
+
+	if len(ownerMessages) != 1 || !strings.Contains(ownerMessages[0], `"comment"`) {
+		t.Errorf("owner received %q, want the thread", ownerMessages)
+	}
+	if len(editorMessages) != 2 || !strings.Contains(editorMessages[1], `"mention"`) {
+		t.Errorf("editor received %q, want the thread and the mention", editorMessages)
+	}
+	if len(publicMessages) != 0 {
+		t.Errorf("a visitor received %q", publicMessages)
+	}
+
+	var seq int64
+	room.do(func() { seq = room.streamSeq })
+	if seq != 0 {
+		t.Errorf("seq = %d, want thread events outside the stream", seq)
+	}
+}
+
+func TestFacieAnchorFollowsSection(t *testing.T) {
+	repo := newFakeRepo()
+	a := newTestAPI(t, repo, "a")
+	project := membersProject()
+	project.Facies = map[string][]models.FaciesSection{"arena": {{Y1: 0, Y2: 10}, {Y1: 10, Y2: 20}}}
+	roomID := repo.addProject(project)
+
+	anchor, err := resolveAnchor(&project, dtos.ThreadAnchor{Type: models.AnchorFacie, Facie: "arena", Index: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anchor.Index == nil || *anchor.Index != 1 || anchor.Y1 != 10 || anchor.Y2 != 20 {
+		t.Fatalf("anchor = %+v, want section 1 at 10-20", anchor)
+	}
+
+	// Solo se guarda la facie y el índice de la sección
+	raw, err := bson.Marshal(anchor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored models.Anchor
+	if err := bson.Unmarshal(raw, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Y1 != 0 || stored.Y2 != 0 || stored.Index == nil || *stored.Index != 1 {
+		t.Fatalf("stored anchor = %+v, want no coordinates", stored)
+	}
+
+	// Al leer el hilo se toma la posición actual, por ejemplo tras invertir la columna
+	project.Facies["arena"] = []models.FaciesSection{{Y1: 20, Y2: 30}, {Y1: 30, Y2: 45}}
+	repo.projects[roomID] = project
+	thread := &models.Thread{Anchor: stored}
+	a.locateThreads(context.Background(), roomID, "r@test.com", thread)
+	if thread.Anchor.Y1 != 30 || thread.Anchor.Y2 != 45 {
+		t.Errorf("located anchor = %+v, want 30-45", thread.Anchor)
+	}
+
+	// Si la sección ya no existe no tiene posición
+	project.Facies["arena"] = project.Facies["arena"][:1]
+	repo.projects[roomID] = project
+	a.locateThreads(context.Background(), roomID, "r@test.com", thread)
+	if thread.Anchor.Y1 != 0 || thread.Anchor.Y2 != 0 {
+		t.Errorf("anchor of a deleted section = %+v, want no coordinates", thread.Anchor)
+	}
+
+	if _, err := resolveAnchor(&project, dtos.ThreadAnchor{Type: models.AnchorFacie, Facie: "arena", Index: 1}); err != errNotFound {
+		t.Errorf("anchor to a missing section = %v, want %v", err, errNotFound)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ProyectoT/api/internal/diff"
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+type DiffResponse struct {
+	From string     `json:"from"`
+	To   string     `json:"to"`
+	Diff *diff.Diff `json:"diff"`
+}
+
+// loadState obtiene un estado del proyecto: "live" (o vacío) es el estado
+// actual, incluidos los cambios aún no guardados; cualquier otro valor es el id de una revisión
+func (a *API) loadState(ctx context.Context, roomID string, ref string, user string) (*models.Project, error) {
+	if ref == "" || ref == "live" {
+		return a.loadProject(ctx, roomID, user)
+	}
+
+	info, err := a.projectInfo(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Las revisiones son solo para miembros, ver revisions.go
+	if !isMember(*info, user) {
+		return nil, errProjectAccess
+	}
+
+	revision, err := a.repo.GetRevision(ctx, roomID, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision.Project, nil
+}
+
+// HandleDiff compara dos estados de proyectos. ?from= y ?to= son "live" o el id
+// de una revisión (to es "live" por defecto); ?toProject= compara contra otro proyecto
+func (a *API) HandleDiff(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+
+	from := c.QueryParam("from")
+	to := c.QueryParam("to")
+
+	toProject := c.QueryParam("toProject")
+	if toProject == "" {
+		toProject = roomID
+	}
+
+	if from == "" && toProject == roomID {
+		return a.handleError(c, http.StatusBadRequest, "missing from")
+	}
+
+	stateA, err := a.loadState(ctx, roomID, from, user)
+	if err != nil {
+		return a.handleLoadError(c, err)
+	}
+
+	stateB, err := a.loadState(ctx, toProject, to, user)
+	if err != nil {
+		return a.handleLoadError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, DiffResponse{
+		From: stateRef(roomID, from),
+		To:   stateRef(toProject, to),
+		Diff: diff.Compare(stateA, stateB),
+	})
+}
+
+func stateRef(projectID string, ref string) string {
+	if ref == "" {
+		ref = "live"
+	}
+	return projectID + "@" + ref
+}
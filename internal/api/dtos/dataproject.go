@@ -1,5 +1,11 @@
 package dtos
 
+import (
+	"encoding/json"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
 // Case editText
 type EditText struct {
 	Key      string `json:"key"`
@@ -79,6 +85,32 @@ type DeleteMuestra struct {
 	IdMuestra string `json:"idMuestra"`
 }
 
+type DeleteLog struct {
+	IdLog string `json:"idLog"`
+}
+
+// Case addLog
+type AddLog struct {
+	IdLog string         `json:"idLog"`
+	Value models.WellLog `json:"value"`
+}
+
+// Case importRows
+type ImportRows struct {
+	Columns []string          `json:"columns"`
+	Rows    []models.DataInfo `json:"rows"`
+}
+
+// Case batch
+type Batch struct {
+	Actions []BatchAction `json:"actions"`
+}
+
+type BatchAction struct {
+	Action string          `json:"action"`
+	Data   json.RawMessage `json:"data"`
+}
+
 type Column struct {
 	Column    string `json:"column"`
 	IsVisible bool   `json:"isVisible"`
@@ -95,8 +127,10 @@ type EditPolygon struct {
 }
 
 type UserEditingState struct {
-	Section string `json:"section"`
-	Name    string `json:"name"`
+	Section  string `json:"section"`
+	Name     string `json:"name"`
+	RowIndex *int   `json:"rowIndex,omitempty"`
+	Column   string `json:"column,omitempty"`
 }
 
 type Drop struct {
@@ -126,3 +160,37 @@ type EditInfoProject struct {
 	Visible     bool   `json:"Visible"`
 	Description string `json:"Description"`
 }
+
+type Resync struct {
+	Seq    int64  `json:"seq"`
+	Stream string `json:"stream"`
+}
+
+type Presence struct {
+	Cursor   *Cursor   `json:"cursor,omitempty"`
+	Viewport *Viewport `json:"viewport,omitempty"`
+}
+
+type Cursor struct {
+	X      float64 `json:"x"`
+	Depth  float64 `json:"depth"`
+	Row    *int    `json:"row,omitempty"`
+	Column string  `json:"column,omitempty"`
+}
+
+type Viewport struct {
+	Top    float64 `json:"top"`
+	Bottom float64 `json:"bottom"`
+}
+
+type Follow struct {
+	ID string `json:"id"`
+}
+
+type Chat struct {
+	Message string `json:"message"`
+}
+
+type ChatRead struct {
+	ID string `json:"id"`
+}
@@ -1,16 +1,43 @@
 package dtos
 
 type Project struct {
-	RoomName string  `json:"roomName" validate:"required"`
-	Location string  `json:"location" validate:"required"`
-	Lat      float64 `json:"lat"`
-	Long     float64 `json:"long"`
-	Desc     string  `json:"desc"`
-	Visible  bool    `json:"visible"`
+	RoomName string  `json:"roomName" form:"roomName" validate:"required"`
+	Location string  `json:"location" form:"location" validate:"required"`
+	Lat      float64 `json:"lat" form:"lat"`
+	Long     float64 `json:"long" form:"long"`
+	Desc     string  `json:"desc" form:"desc"`
+	Visible  bool    `json:"visible" form:"visible"`
 }
 
 type Comment struct {
 	Content   string   `bson:"content"`
 	CreatedAt string   `bson:"createdAt"`
 	Labels    []string `bson:"labels"`
-}
\ No newline at end of file
+}
+
+type ForkProject struct {
+	RoomName string `json:"roomName" form:"roomName"`
+	Visible  bool   `json:"visible" form:"visible"`
+}
+
+type NewThread struct {
+	Anchor  ThreadAnchor `json:"anchor"`
+	Content string       `json:"content"`
+}
+
+// ThreadAnchor indica dónde se abre el hilo, ver models.Anchor. Las capas se
+// indican por rowIndex o rowId y las secciones de facie por facie e index
+type ThreadAnchor struct {
+	Type     string  `json:"type"`
+	RowIndex *int    `json:"rowIndex"`
+	RowID    string  `json:"rowId"`
+	Top      float64 `json:"top"`
+	Bottom   float64 `json:"bottom"`
+	ID       string  `json:"id"`
+	Facie    string  `json:"facie"`
+	Index    int     `json:"index"`
+}
+
+type ThreadReply struct {
+	Content string `json:"content"`
+}
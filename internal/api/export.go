@@ -0,0 +1,374 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ProyectoT/api/internal/export"
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+var errProjectAccess = errors.New("access denied")
+
+// toProject copia el estado de la sala a un models.Project. Se debe llamar desde la goroutine de la sala
+func (r *RoomData) toProject() models.Project {
+	return models.Project{
+		ID:          r.ID,
+		ProjectInfo: r.ProjectInfo,
+		Data:        r.Data,
+		Config:      r.Config,
+		Fosil:       r.Fosil,
+		Facies:      r.Facies,
+		Muestras:    r.Muestras,
+		Logs:        r.Logs,
+		Shared:      r.Shared,
+		Seq:         r.opSeq,
+		History:     r.encodeHistory(),
+		LeaseEpoch:  r.epoch,
+	}
+}
+
+// canRead indica si el usuario puede ver el proyecto (miembro o proyecto público)
+func canRead(info models.ProjectInfo, user string) bool {
+	members := info.Members
+	return members.Owner == user || contains(members.Editors, user) || contains(members.Readers, user) || info.Visible
+}
+
+// loadProject obtiene una copia del proyecto, desde la sala en memoria si está
+// abierta (para incluir los cambios aún no guardados) o desde la base de datos
+func (a *API) loadProject(ctx context.Context, roomID string, user string) (*models.Project, error) {
+	var project *models.Project
+
+	if roomInterface, ok := rooms.Load(roomID); ok {
+		room := roomInterface.(*RoomData)
+		room.do(func() { project = room.snapshot() })
+	}
+
+	// Si la sala no está abierta (o se cerró mientras tanto) se lee de la base de datos
+	if project == nil {
+		p, err := a.repo.GetRoom(ctx, roomID)
+		if err != nil {
+			return nil, err
+		}
+		project = p
+	}
+
+	if !canRead(project.ProjectInfo, user) {
+		return nil, errProjectAccess
+	}
+
+	return project, nil
+}
+
+// handleLoadError traduce los errores de loadProject a respuestas HTTP
+func (a *API) handleLoadError(c echo.Context, err error) error {
+	if errors.Is(err, errProjectAccess) {
+		return a.handleError(c, http.StatusForbidden, "Access denied")
+	}
+	return a.handleError(c, http.StatusNotFound, "Room not found")
+}
+
+// HandleExportSVG dibuja la columna estratigráfica del proyecto como SVG
+func (a *API) HandleExportSVG(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+
+	project, err := a.loadProject(ctx, c.Param("id"), user)
+	if err != nil {
+		return a.handleLoadError(c, err)
+	}
+
+	opts, err := exportOptions(c)
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := export.SVG(&buf, project, opts); err != nil {
+		if errors.Is(err, export.ErrTooLarge) {
+			return a.handleError(c, http.StatusBadRequest, err.Error())
+		}
+		return a.handleError(c, http.StatusInternalServerError, "Failed to render project")
+	}
+
+	return c.Blob(http.StatusOK, "image/svg+xml", buf.Bytes())
+}
+
+// exportOptions lee las opciones de dibujo desde la query (?scale=&patterns=&fosils=).
+// scale debe estar entre export.MinScale y export.MaxScale
+func exportOptions(c echo.Context) (export.Options, error) {
+	opts := export.Options{
+		Scale:       1,
+		PatternBase: c.QueryParam("patterns"),
+		FosilBase:   c.QueryParam("fosils"),
+	}
+
+	scale, err := parseScale(c.QueryParam("scale"), opts.Scale, export.MinScale, export.MaxScale)
+	if err != nil {
+		return opts, err
+	}
+	opts.Scale = scale
+
+	return opts, nil
+}
+
+// parseScale lee la escala de la query; si falta usa def
+func parseScale(s string, def, min, max float64) (float64, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	scale, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(scale) || scale < min || scale > max {
+		return 0, fmt.Errorf("invalid scale, must be between %s and %s", formatFloat(min), formatFloat(max))
+	}
+	return scale, nil
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// HandleExportPDF genera el PDF imprimible del proyecto (?scale=&page=&landscape=).
+// scale debe estar entre export.MinPDFScale y export.MaxPDFScale
+func (a *API) HandleExportPDF(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+
+	project, err := a.loadProject(ctx, c.Param("id"), user)
+	if err != nil {
+		return a.handleLoadError(c, err)
+	}
+
+	opts := export.PDFOptions{
+		PageSize:  "A4",
+		Landscape: c.QueryParam("landscape") == "true",
+	}
+
+	scale, err := parseScale(c.QueryParam("scale"), 0, export.MinPDFScale, export.MaxPDFScale)
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+	opts.Scale = scale
+
+	switch page := strings.ToUpper(c.QueryParam("page")); page {
+	case "":
+	case "A4", "A3":
+		opts.PageSize = page
+	case "LETTER", "LEGAL":
+		opts.PageSize = page[:1] + strings.ToLower(page[1:])
+	default:
+		return a.handleError(c, http.StatusBadRequest, "invalid page size")
+	}
+
+	var buf bytes.Buffer
+	if err := export.PDF(&buf, project, opts); err != nil {
+		if errors.Is(err, export.ErrTooLarge) {
+			return a.handleError(c, http.StatusBadRequest, err.Error())
+		}
+		return a.handleError(c, http.StatusInternalServerError, "Failed to render project")
+	}
+
+	return c.Blob(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// HandleExportTable exporta la tabla de capas, fósiles, muestras y facies.
+// ?format=csv entrega un zip con un CSV por tabla (o un solo CSV con ?table=),
+// ?format=xlsx entrega un libro con una hoja por tabla
+func (a *API) HandleExportTable(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+
+	project, err := a.loadProject(ctx, c.Param("id"), user)
+	if err != nil {
+		return a.handleLoadError(c, err)
+	}
+
+	sheets := export.Tables(project)
+	name := exportFileName(project)
+
+	var buf bytes.Buffer
+
+	switch c.QueryParam("format") {
+	case "", "csv":
+		if table := c.QueryParam("table"); table != "" {
+			for _, sheet := range sheets {
+				if strings.EqualFold(sheet.Name, table) {
+					if err := export.WriteCSV(&buf, sheet); err != nil {
+						return a.handleError(c, http.StatusInternalServerError, "Failed to export project")
+					}
+					c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+name+"-"+sheet.Name+`.csv"`)
+					return c.Blob(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+				}
+			}
+			return a.handleError(c, http.StatusBadRequest, "invalid table")
+		}
+
+		if err := export.WriteCSVZip(&buf, sheets); err != nil {
+			return a.handleError(c, http.StatusInternalServerError, "Failed to export project")
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+name+`.zip"`)
+		return c.Blob(http.StatusOK, "application/zip", buf.Bytes())
+
+	case "xlsx":
+		if err := export.WriteXLSX(&buf, sheets); err != nil {
+			return a.handleError(c, http.StatusInternalServerError, "Failed to export project")
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+name+`.xlsx"`)
+		return c.Blob(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buf.Bytes())
+
+	default:
+		return a.handleError(c, http.StatusBadRequest, "invalid format")
+	}
+}
+
+// exportFileName arma un nombre de archivo seguro a partir del nombre del proyecto
+func exportFileName(project *models.Project) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r == ' ':
+			return '_'
+		default:
+			return -1
+		}
+	}, project.ProjectInfo.Name)
+
+	if name == "" {
+		return project.ID.Hex()
+	}
+	return name
+}
+
+// HandleExportLAS exporta el proyecto como LAS 2.0 (?step=&unit=). step debe
+// ser al menos export.MinLASStep
+func (a *API) HandleExportLAS(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+
+	project, err := a.loadProject(ctx, c.Param("id"), user)
+	if err != nil {
+		return a.handleLoadError(c, err)
+	}
+
+	opts := export.LASOptions{Step: 1, Unit: c.QueryParam("unit")}
+
+	if s := c.QueryParam("step"); s != "" {
+		step, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(step) || math.IsInf(step, 0) || step < export.MinLASStep {
+			return a.handleError(c, http.StatusBadRequest, "invalid step, must be at least "+formatFloat(export.MinLASStep))
+		}
+		opts.Step = step
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteLAS(&buf, project, opts); err != nil {
+		if errors.Is(err, export.ErrTooLarge) {
+			return a.handleError(c, http.StatusBadRequest, err.Error())
+		}
+		return a.handleError(c, http.StatusInternalServerError, "Failed to export project")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+exportFileName(project)+`.las"`)
+	return c.Blob(http.StatusOK, "text/plain; charset=utf-8", buf.Bytes())
+}
+
+// HandleExportArchive descarga el respaldo completo del proyecto como JSON, o como zip con ?format=zip
+func (a *API) HandleExportArchive(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+
+	project, err := a.loadProject(ctx, c.Param("id"), user)
+	if err != nil {
+		return a.handleLoadError(c, err)
+	}
+
+	name := exportFileName(project)
+
+	var buf bytes.Buffer
+
+	switch c.QueryParam("format") {
+	case "", "json":
+		if err := export.WriteArchive(&buf, project); err != nil {
+			return a.handleError(c, http.StatusInternalServerError, "Failed to export project")
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+name+`.json"`)
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, buf.Bytes())
+
+	case "zip":
+		if err := export.WriteArchiveZip(&buf, project); err != nil {
+			return a.handleError(c, http.StatusInternalServerError, "Failed to export project")
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+name+`.zip"`)
+		return c.Blob(http.StatusOK, "application/zip", buf.Bytes())
+
+	default:
+		return a.handleError(c, http.StatusBadRequest, "invalid format")
+	}
+}
+
+// HandleExportSedLog exporta las capas en el formato de intercambio de SedLog (?format=csv|xml)
+func (a *API) HandleExportSedLog(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+
+	project, err := a.loadProject(ctx, c.Param("id"), user)
+	if err != nil {
+		return a.handleLoadError(c, err)
+	}
+
+	name := exportFileName(project)
+
+	var buf bytes.Buffer
+
+	switch c.QueryParam("format") {
+	case "", "csv":
+		if err := export.WriteSedLogCSV(&buf, project); err != nil {
+			return a.handleError(c, http.StatusInternalServerError, "Failed to export project")
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+name+`-sedlog.csv"`)
+		return c.Blob(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+
+	case "xml":
+		if err := export.WriteSedLogXML(&buf, project); err != nil {
+			return a.handleError(c, http.StatusInternalServerError, "Failed to export project")
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+name+`-sedlog.xml"`)
+		return c.Blob(http.StatusOK, echo.MIMEApplicationXMLCharsetUTF8, buf.Bytes())
+
+	default:
+		return a.handleError(c, http.StatusBadRequest, "invalid format")
+	}
+}
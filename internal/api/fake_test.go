@@ -0,0 +1,291 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ProyectoT/api/encryption"
+	"github.com/ProyectoT/api/internal/entity"
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/ProyectoT/api/internal/pubsub"
+	"github.com/ProyectoT/api/internal/repository"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errFakeNotFound = errors.New("not found")
+
+// fakeRepo guarda en memoria lo que las salas leen y escriben en la base de
+// datos. Los métodos que no implementa entran en pánico
+type fakeRepo struct {
+	repository.Repository
+
+	mu        sync.Mutex
+	projects  map[string]models.Project
+	leases    map[string]models.RoomLease
+	saves     int
+	revisions int
+
+	stored        map[string]models.Revision // revisiones por ID
+	revisionReads int
+	// onSaveRevision se llama al guardar una revisión, fuera del candado
+	onSaveRevision func()
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{
+		projects: make(map[string]models.Project),
+		leases:   make(map[string]models.RoomLease),
+		stored:   make(map[string]models.Revision),
+	}
+}
+
+// addProject guarda un proyecto de prueba y devuelve su ID
+func (f *fakeRepo) addProject(project models.Project) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	project.ID = primitive.NewObjectID()
+	f.projects[project.ID.Hex()] = project
+	return project.ID.Hex()
+}
+
+func (f *fakeRepo) project(roomID string) models.Project {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.projects[roomID]
+}
+
+// lease devuelve la concesión vigente de la sala
+func (f *fakeRepo) lease(roomID string) (models.RoomLease, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lease, ok := f.leases[roomID]
+	return lease, ok && lease.ExpiresAt.After(time.Now())
+}
+
+// expireLease vence la concesión de la sala, como si su dueño hubiera dejado
+// de renovarla
+func (f *fakeRepo) expireLease(roomID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lease := f.leases[roomID]
+	lease.ExpiresAt = time.Now().Add(-time.Second)
+	f.leases[roomID] = lease
+}
+
+func (f *fakeRepo) saveCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.saves
+}
+
+func (f *fakeRepo) GetRoom(ctx context.Context, roomID string) (*models.Project, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	project, ok := f.projects[roomID]
+	if !ok {
+		return nil, errFakeNotFound
+	}
+	return project.Clone(), nil
+}
+
+func (f *fakeRepo) SaveRoom(ctx context.Context, data models.Project) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Como en Mongo, no se guarda si otra instancia tomó la sala después
+	stored, ok := f.projects[data.ID.Hex()]
+	if ok && stored.LeaseEpoch > data.LeaseEpoch {
+		return repository.ErrFenced
+	}
+
+	project := *data.Clone()
+	if ok {
+		project.LeaseEpoch = stored.LeaseEpoch
+	}
+	f.projects[data.ID.Hex()] = project
+	f.saves++
+	return nil
+}
+
+// CreateProject guarda el proyecto tal cual, sin copiarlo, para que las pruebas
+// vean si comparte datos con otra sala
+func (f *fakeRepo) CreateProject(ctx context.Context, project models.Project) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	project.ID = primitive.NewObjectID()
+	f.projects[project.ID.Hex()] = project
+	return project.ID.Hex(), nil
+}
+
+func (f *fakeRepo) SaveRevision(ctx context.Context, revision models.Revision) (string, error) {
+	f.mu.Lock()
+	f.revisions++
+	revision.ID = primitive.NewObjectID()
+	revision.Project = *revision.Project.Clone()
+	f.stored[revision.ID.Hex()] = revision
+	hook := f.onSaveRevision
+	f.mu.Unlock()
+
+	if hook != nil {
+		hook()
+	}
+	return revision.ID.Hex(), nil
+}
+
+func (f *fakeRepo) GetRevision(ctx context.Context, projectID string, revisionID string) (*models.Revision, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.revisionReads++
+	revision, ok := f.stored[revisionID]
+	if !ok || revision.ProjectID.Hex() != projectID {
+		return nil, errFakeNotFound
+	}
+	revision.Project = *revision.Project.Clone()
+	return &revision, nil
+}
+
+// addRevision guarda una revisión del proyecto y devuelve su ID
+func (f *fakeRepo) addRevision(roomID string, project models.Project) string {
+	id, _ := primitive.ObjectIDFromHex(roomID)
+	project.ID = id
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	revision := models.Revision{ID: primitive.NewObjectID(), ProjectID: id, Project: project}
+	f.stored[revision.ID.Hex()] = revision
+	return revision.ID.Hex()
+}
+
+func (f *fakeRepo) SaveOperation(ctx context.Context, operation models.Operation) error {
+	return nil
+}
+
+func (f *fakeRepo) GetOperations(ctx context.Context, projectID string, afterSeq int64, untilSeq int64, limit int) ([]models.Operation, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) AcquireRoomLease(ctx context.Context, roomID string, owner string, url string, ttl time.Duration) (*models.RoomLease, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	lease := f.leases[roomID]
+	switch {
+	case lease.Owner == owner && !lease.ExpiresAt.Before(now):
+		lease.ExpiresAt = now.Add(ttl)
+	case lease.ExpiresAt.Before(now):
+		// Tomarla de nuevo cambia la época y deja afuera al dueño anterior
+		lease = models.RoomLease{RoomID: roomID, Owner: owner, URL: url, ExpiresAt: now.Add(ttl), Epoch: lease.Epoch + 1}
+		if project, ok := f.projects[roomID]; ok && project.LeaseEpoch < lease.Epoch {
+			project.LeaseEpoch = lease.Epoch
+			f.projects[roomID] = project
+		}
+	}
+	f.leases[roomID] = lease
+	return &lease, nil
+}
+
+func (f *fakeRepo) ReleaseRoomLease(ctx context.Context, roomID string, owner string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if lease, ok := f.leases[roomID]; ok && lease.Owner == owner {
+		lease.ExpiresAt = time.Time{}
+		f.leases[roomID] = lease
+	}
+	return nil
+}
+
+// newTestAPI arma una instancia de la API sobre repo. Al terminar la prueba
+// se detienen las salas que hayan quedado abiertas
+func newTestAPI(t *testing.T, repo repository.Repository, instanceID string) *API {
+	t.Helper()
+
+	a := &API{
+		repo:        repo,
+		broker:      pubsub.NewLocal(),
+		instanceID:  instanceID,
+		instanceURL: "http://" + instanceID,
+	}
+
+	t.Cleanup(func() {
+		rooms.Range(func(key, value interface{}) bool {
+			value.(*RoomData).stop()
+			rooms.Delete(key)
+			return true
+		})
+	})
+
+	return a
+}
+
+// testUser devuelve un usuario conectado cuyos mensajes quedan en la cola del
+// cliente, sin socket
+func testUser(email string) *UserConnection {
+	return &UserConnection{
+		Email: email,
+		Conn:  &Client{send: make(chan []byte, sendQueueSize), done: make(chan struct{})},
+	}
+}
+
+func (f *fakeRepo) SaveMessage(ctx context.Context, message entity.Message) error {
+	return nil
+}
+
+func (f *fakeRepo) MarkMessagesRead(ctx context.Context, roomID string, user string, messageID string) error {
+	return nil
+}
+
+// received devuelve los mensajes que quedaron en la cola del usuario
+func received(u *UserConnection) []string {
+	var messages []string
+	for {
+		select {
+		case msg := <-u.Conn.send:
+			messages = append(messages, string(msg))
+		default:
+			return messages
+		}
+	}
+}
+
+// callHandler llama al handler como el usuario con la sesión iniciada. params
+// son pares de nombre y valor de los parámetros de la ruta
+func callHandler(t *testing.T, handler echo.HandlerFunc, method string, user string, params ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	t.Setenv("KEYPWD", "0123456789abcdef0123456789abcdef")
+	token, err := encryption.SignedLoginToken(&models.User{Email: user, Name: user})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(method, "/", nil)
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+
+	c := echo.New().NewContext(req, rec)
+	var names, values []string
+	for i := 0; i+1 < len(params); i += 2 {
+		names = append(names, params[i])
+		values = append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+
+	if err := handler(c); err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
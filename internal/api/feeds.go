@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/ProyectoT/api/internal/export"
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+// Dirección del cliente web que se usa para los enlaces a las salas si no se indica ?base=
+const defaultAppURL = "https://stratascope.inf.uct.cl"
+
+// feedPageSize es el tamaño de página con que se recorren los proyectos del usuario
+const feedPageSize = 100
+
+// roomLink arma el enlace al editor del proyecto en el cliente web
+func roomLink(c echo.Context) func(id string) string {
+	base := strings.TrimRight(c.QueryParam("base"), "/")
+	if base == "" {
+		base = defaultAppURL
+	}
+
+	return func(id string) string {
+		return base + "/editor/" + id
+	}
+}
+
+// writeFeed responde las ubicaciones de los proyectos como KML si la ruta termina en .kml, o como GeoJSON
+func (a *API) writeFeed(c echo.Context, name string, projects []models.InfoProject) error {
+	marks := export.Placemarks(projects, roomLink(c))
+
+	var buf bytes.Buffer
+
+	if strings.HasSuffix(c.Path(), ".kml") {
+		if err := export.WriteKML(&buf, name, marks); err != nil {
+			return a.handleError(c, http.StatusInternalServerError, "Failed to export projects")
+		}
+		return c.Blob(http.StatusOK, "application/vnd.google-earth.kml+xml", buf.Bytes())
+	}
+
+	if err := export.WriteGeoJSON(&buf, marks); err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Failed to export projects")
+	}
+	return c.Blob(http.StatusOK, "application/geo+json", buf.Bytes())
+}
+
+// HandlePublicFeed devuelve la ubicación de los proyectos públicos como GeoJSON o KML
+func (a *API) HandlePublicFeed(c echo.Context) error {
+
+	ctx := c.Request().Context()
+	auth := c.Request().Header.Get("Authorization")
+	if auth == "" {
+		return a.handleError(c, http.StatusUnauthorized, "invalid or expired token")
+	}
+
+	proyects, err := a.repo.HandleGetPublicProject(ctx)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, "Error getting proyects")
+	}
+
+	return a.writeFeed(c, "Proyectos públicos", proyects)
+}
+
+// HandleProjectsFeed devuelve la ubicación de todos los proyectos del usuario como GeoJSON o KML
+func (a *API) HandleProjectsFeed(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+
+	var proyects []models.InfoProject
+
+	for page, totalPages := 1, 1; page <= totalPages; page++ {
+		var batch []models.InfoProject
+
+		batch, _, totalPages, err = a.repo.GetProyects(ctx, user, page, feedPageSize)
+		if err != nil {
+			return a.handleError(c, http.StatusUnauthorized, "Error getting projects")
+		}
+
+		proyects = append(proyects, batch...)
+	}
+
+	return a.writeFeed(c, "Mis proyectos", proyects)
+}
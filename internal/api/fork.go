@@ -0,0 +1,81 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ProyectoT/api/internal/api/dtos"
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+type ForkResponse struct {
+	Message    string         `json:"message"`
+	ProjectID  string         `json:"projectId"`
+	ForkedFrom models.ForkRef `json:"forkedFrom"`
+}
+
+// HandleForkProject crea una copia del proyecto a nombre del usuario. Se copia
+// la sala en memoria si está abierta, con los cambios aún no guardados. Basta
+// con poder leer el proyecto, así que también se pueden copiar los públicos
+func (a *API) HandleForkProject(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	correo := claims["email"].(string)
+	name := claims["name"].(string)
+	roomID := c.Param("id")
+
+	var params dtos.ForkProject
+	if err := c.Bind(&params); err != nil {
+		return a.handleError(c, http.StatusBadRequest, "Invalid request")
+	}
+
+	source, err := a.loadProject(ctx, roomID, correo)
+	if err != nil {
+		return a.handleLoadError(c, err)
+	}
+
+	info := source.ProjectInfo
+	if params.RoomName == "" {
+		params.RoomName = fmt.Sprintf("%s (copia)", info.Name)
+	}
+
+	project := models.Project{
+		ProjectInfo: newProjectInfo(dtos.Project{
+			RoomName: params.RoomName,
+			Desc:     info.Description,
+			Location: info.Location,
+			Lat:      info.Lat,
+			Long:     info.Long,
+			Visible:  params.Visible,
+		}, name, correo),
+		Data:     source.Data,
+		Config:   source.Config,
+		Fosil:    source.Fosil,
+		Facies:   source.Facies,
+		Muestras: source.Muestras,
+		Logs:     source.Logs,
+	}
+
+	forkedFrom := models.ForkRef{
+		ProjectID: roomID,
+		Name:      info.Name,
+		Seq:       source.Seq,
+		Date:      project.ProjectInfo.CreationDate,
+	}
+	project.ProjectInfo.ForkedFrom = &forkedFrom
+
+	id, err := a.repo.CreateProject(ctx, project)
+	if err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Failed to create a room")
+	}
+
+	return c.JSON(http.StatusOK, ForkResponse{
+		Message:    "Room forked successfully",
+		ProjectID:  id,
+		ForkedFrom: forkedFrom,
+	})
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+func TestForkProject(t *testing.T) {
+	repo := newFakeRepo()
+	a := newTestAPI(t, repo, "a")
+	source := membersProject()
+	source.Fosil = map[string]models.Fosil{"f1": {Upper: 1, Lower: 2, FosilImg: "amonite"}}
+	roomID := repo.addProject(source)
+
+	room, err := a.instanceRoom(context.Background(), roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit := func(value string) {
+		data := json.RawMessage(`{"key": "Notas", "value": "` + value + `", "rowIndex": 0}`)
+		room.do(func() {
+			if err := a.record(room, "a@test.com", "", "editText", data); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	// El cambio aún no guardado entra en la copia
+	edit("sin guardar")
+
+	rec := callHandler(t, a.HandleForkProject, http.MethodPost, "r@test.com", "id", roomID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", rec.Code, rec.Body)
+	}
+	var res ForkResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.ProjectID == "" || res.ProjectID == roomID {
+		t.Fatalf("fork id = %q, want a new project", res.ProjectID)
+	}
+	fork := repo.project(res.ProjectID)
+	if fork.ID.Hex() != res.ProjectID {
+		t.Fatalf("fork %s was not created", res.ProjectID)
+	}
+
+	info := fork.ProjectInfo
+	if info.ForkedFrom == nil || info.ForkedFrom.ProjectID != roomID || *info.ForkedFrom != res.ForkedFrom {
+		t.Errorf("forkedFrom = %+v, want %s", info.ForkedFrom, roomID)
+	}
+	want := models.Members{Owner: "r@test.com", Editors: []string{}, Readers: []string{}}
+	if !reflect.DeepEqual(info.Members, want) {
+		t.Errorf("members = %+v, want only the user who forked", info.Members)
+	}
+	if fork.Shared != (models.Shared{}) {
+		t.Errorf("shared = %+v, want no invite links", fork.Shared)
+	}
+	if fork.Data[0].Columns["Notas"] != "sin guardar" {
+		t.Errorf("fork notes = %v, want the unsaved edit", fork.Data[0].Columns["Notas"])
+	}
+
+	// Editar la sala original no cambia la copia
+	edit("después")
+	if fork.Data[0].Columns["Notas"] != "sin guardar" {
+		t.Errorf("fork notes = %v after editing the source", fork.Data[0].Columns["Notas"])
+	}
+
+	// Ni editar la copia cambia la sala original
+	fork.Data[0].Columns["Notas"] = "en la copia"
+	fork.Fosil["f1"] = models.Fosil{Upper: 5, Lower: 6}
+	var current *models.Project
+	room.do(func() { current = room.snapshot() })
+	if current.Data[0].Columns["Notas"] != "después" {
+		t.Errorf("source notes = %v after editing the fork", current.Data[0].Columns["Notas"])
+	}
+	if current.Fosil["f1"].Upper != 1 {
+		t.Errorf("source fosil = %+v after editing the fork", current.Fosil["f1"])
+	}
+}
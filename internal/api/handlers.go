@@ -3,15 +3,16 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"runtime"
+	"strconv"
 	"sync"
 
 	"encoding/hex"
 	"log"
 	"math/rand"
 	"net/http"
-	"strings"
 
 	"time"
 
@@ -41,6 +42,15 @@ type ErrorMessage struct {
 	Message string `json:"message"`
 }
 
+// CloseMessage se envía antes de cerrar la conexión desde el servidor
+type CloseMessage struct {
+	Action     string `json:"action"`
+	Message    string `json:"message"`
+	Reason     string `json:"reason"`
+	Reconnect  bool   `json:"reconnect"`            // el cliente puede volver a conectarse
+	RetryAfter int    `json:"retryAfter,omitempty"` // milisegundos a esperar antes de hacerlo
+}
+
 type responseMessage struct {
 	Message string `json:"message"`
 }
@@ -57,14 +67,21 @@ type ProjectResponse struct {
 }
 
 type UserConnection struct {
-	Email   string
-	Conn    *websocket.Conn
-	Editing string
-	Color   string
+	Email    string
+	Conn     *Client
+	Editing  string
+	Color    string
+	Lease    *lease    // capa bloqueada por esta conexión, ver leases.go
+	Presence *presence // cursor y vista, ver presence.go
 }
 
 type RoomData struct {
-	mu             sync.Mutex
+	commands       chan func() // ver actor.go
+	quit           chan struct{}
+	stopOnce       sync.Once
+	closed         chan struct{} // se cierra al sacar la sala de memoria
+	closeOnce      sync.Once
+	epoch          int64 // época del lease con que se abrió, ver cluster.go
 	ID             primitive.ObjectID
 	ProjectInfo    models.ProjectInfo
 	Data           []models.DataInfo
@@ -72,12 +89,19 @@ type RoomData struct {
 	Fosil          map[string]models.Fosil
 	Facies         map[string][]models.FaciesSection
 	Muestras       map[string]models.Muestra
+	Logs           map[string]models.WellLog
 	Shared         models.Shared
 	Active         map[string]*UserConnection
-	undoStack      []Action
-	redoStack      []Action
+	undoStacks     map[string][]Action // historial de cada usuario, ver undoChanges.go
+	redoStacks     map[string][]Action
 	saveTimer      *time.Timer
 	actionsCounter int
+	opSeq          int64 // última operación aplicada, ver operations.go
+	batching       bool  // true mientras se aplica un batch, ver batch.go
+	pending        []json.RawMessage
+	streamID       string // instancia de la sala, ver stream.go
+	streamSeq      int64  // último mensaje enviado
+	sent           [][]byte
 }
 
 var rooms sync.Map
@@ -86,39 +110,23 @@ var rooms sync.Map
 
 var roomActionsThreshold = 30
 
-func RemoveElement(a *API, roomID string, userID string, project *RoomData) {
+func RemoveElement(a *API, roomID string, userID string, user string, project *RoomData) {
+
+	var err error
 
-	err := project.DisconnectUser(userID)
+	derr := project.do(func() {
+		err = project.disconnectUser(userID)
+	})
+	if derr != nil {
+		return
+	}
 	if err != nil {
 		log.Println("Disconnect: ", err)
 		return
 	}
 
 	// Si no hay más usuarios conectados, guardar y eliminar la sala
-	if len(project.Active) == 0 {
-		err := a.repo.SaveRoom(context.Background(), models.Project{
-			ID:          project.ID,
-			ProjectInfo: project.ProjectInfo,
-			Data:        project.Data,
-			Config:      project.Config,
-			Fosil:       project.Fosil,
-			Facies:      project.Facies,
-			Muestras:    project.Muestras,
-			Shared:      project.Shared,
-		})
-		if err != nil {
-			log.Println("Error al guardar la sala:", err)
-			return
-		}
-
-		log.Println("Project saved: ", project.ID)
-
-		// delete(rooms, roomID)
-		//rooms.Delete(roomID)
-		// log.Println("Deleted room: ", roomID)
-	}
-
-	// log.Print(rooms)
+	a.closeIfIdle(roomID, project, user)
 }
 
 func (a *API) HandleWebSocket(c echo.Context) error {
@@ -126,6 +134,11 @@ func (a *API) HandleWebSocket(c echo.Context) error {
 	ctx := c.Request().Context()
 	roomID := c.Param("room")
 
+	// El servidor se está apagando, ver shutdown.go
+	if a.closing.Load() {
+		return a.handleError(c, http.StatusServiceUnavailable, "Server is shutting down")
+	}
+
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
 			return true
@@ -155,24 +168,42 @@ func (a *API) HandleWebSocket(c echo.Context) error {
 	user := claims["email"].(string)
 	userID := shortuuid.New()
 
-	proyect := a.instanceRoom(ctx, roomID)
-	if proyect == nil {
-		conn.WriteJSON(ErrorMessage{Action: "error", Message: "Room not found"})
+	proyect, err := a.instanceRoom(ctx, roomID)
+	if err != nil {
+		var elsewhere *roomElsewhereError
+		if errors.As(err, &elsewhere) {
+			conn.WriteJSON(RedirectMessage{Action: "redirect", Message: err.Error(), URL: elsewhere.URL})
+		} else {
+			conn.WriteJSON(ErrorMessage{Action: "error", Message: "Room not found"})
+		}
 		conn.Close()
 		return nil
 	}
 
-	permission := 2
+	permission := -1
+	member := false
 
-	if proyect.ProjectInfo.Members.Owner == user {
-		permission = 0
-	} else if contains(proyect.ProjectInfo.Members.Editors, user) {
-		permission = 1
-	} else if contains(proyect.ProjectInfo.Members.Readers, user) {
-		permission = 2
-	} else if proyect.ProjectInfo.Visible {
-		permission = 2
-	} else {
+	err = proyect.do(func() {
+		member = isMember(proyect.ProjectInfo, user)
+
+		if proyect.ProjectInfo.Members.Owner == user {
+			permission = 0
+		} else if contains(proyect.ProjectInfo.Members.Editors, user) {
+			permission = 1
+		} else if contains(proyect.ProjectInfo.Members.Readers, user) {
+			permission = 2
+		} else if proyect.ProjectInfo.Visible {
+			permission = 2
+		}
+	})
+	if err != nil {
+		// La sala se cerró justo ahora, al reconectar se abre de nuevo
+		conn.WriteJSON(closeMessage(closeError))
+		conn.Close()
+		return nil
+	}
+
+	if permission == -1 {
 		conn.WriteJSON(ErrorMessage{Action: "error", Message: "Access denied"})
 		conn.Close()
 		return nil
@@ -180,49 +211,40 @@ func (a *API) HandleWebSocket(c echo.Context) error {
 
 	// orden := []string{"Sistema", "Edad", "Formacion", "Miembro", "Espesor", "Litologia", "Estructura fosil", "Facie", "AmbienteDepositacional", "Descripcion"}
 
-	dataRoom := proyect.DataProject()
+	// Si el cliente se reconecta con el último seq que aplicó solo recibe lo que
+	// le falta, si no el proyecto completo. Ver stream.go
+	seq, perr := strconv.ParseInt(c.QueryParam("seq"), 10, 64)
+	if perr != nil {
+		seq = -1
+	}
 
-	if err = conn.WriteJSON(dataRoom); err == nil {
+	// Desde acá todo lo que se envía pasa por la cola del cliente, ver client.go
+	client := newClient(conn)
+	defer client.Close()
 
-		proyect.AddUser(conn, user, userID)
+	err = proyect.do(func() {
+		if err = proyect.resync(client, dtos.Resync{Seq: seq, Stream: c.QueryParam("stream")}); err != nil {
+			return
+		}
+		proyect.addUser(client, user, userID)
 		sendSocketMessage(map[string]interface{}{"action": "userConnected", "id": userID, "mail": user, "color": proyect.Active[userID].Color}, proyect, "userConnected")
+	})
+
+	if err == nil {
 		// log de usuario conectado y permisos
 		log.Println("\033[36m User connected: ", user, " permission: ", permission, "\033[0m")
 
-		// Configuramos el handler para manejar el "pong"
-		// conn.SetPongHandler(func(appData string) error {
-		// 	log.Println("Pong recibido de: ", user)
-		// 	return nil
-		// })
-
-		go func() {
-			//Inicia el timer
-			ticker := time.NewTicker(10 * time.Second)
-			defer ticker.Stop()
-
-			for range ticker.C {
-				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-					conn.Close()
-					if len(proyect.Active) == 0 {
-						_, exists := rooms.Load(roomID)
-						if exists {
-							rooms.Delete(roomID)
-							log.Println("Deleted room: ", roomID)
-						}
-					}
-					return
-				}
-			}
-
-		}()
+		// Los miembros reciben los últimos mensajes del chat, ver chat.go
+		if member {
+			go a.sendChatHistory(client, roomID, user)
+		}
 
 		defer func() {
 			if r := recover(); r != nil {
 				log.Print("Error causado por: ", user)
 				log.Printf("Recovered from panic: %v", r)
-				conn.WriteJSON(ErrorMessage{Action: "error", Message: "Internal server error"})
-				proyect.DisconnectUsers()
-				rooms.Delete(roomID)
+				client.SendJSON(ErrorMessage{Action: "error", Message: "Internal server error"})
+				a.closeRoom(roomID, proyect, closeError)
 			}
 		}()
 
@@ -232,703 +254,174 @@ func (a *API) HandleWebSocket(c echo.Context) error {
 				break
 			}
 
-			if permission != 2 {
-				var dataMap GeneralMessage
-				err := json.Unmarshal([]byte(msg), &dataMap)
+			var dataMap GeneralMessage
+			if err := json.Unmarshal(msg, &dataMap); err != nil {
+				log.Print(err)
+			}
 
-				if err != nil {
-					log.Print(err)
+			// Acciones que puede mandar cualquier usuario, también los lectores
+			if sharedAction(dataMap.Action) {
+				var err error
+				if derr := proyect.do(func() { err = a.handleShared(proyect, client, userID, user, dataMap) }); derr != nil {
+					break
 				}
 
-				if dataMap.Action != "editingUser" && dataMap.Action != "deleteEditingUser" && dataMap.Action != "columns" {
-					go func() {
-						proyect.mu.Lock()
-						saveTimer := proyect.saveTimer
-						actionsCounter := proyect.actionsCounter
-						proyect.mu.Unlock()
-
-						if saveTimer != nil {
-							if actionsCounter >= roomActionsThreshold {
-								// Guardado fuera del lock
-								err := a.repo.SaveRoom(context.Background(), models.Project{ID: proyect.ID, ProjectInfo: proyect.ProjectInfo, Data: proyect.Data, Config: proyect.Config, Fosil: proyect.Fosil, Facies: proyect.Facies, Muestras: proyect.Muestras, Shared: proyect.Shared})
-								if err != nil {
-									log.Println("Error guardando el proyecto automáticamente: ", err)
-
-								} else {
-									log.Println("Proyecto guardado: ", proyect.ID)
-								}
-
-								proyect.mu.Lock()
-								proyect.actionsCounter = 0
-								proyect.mu.Unlock()
-
-								return
-							}
-
-							saveTimer.Reset(5 * time.Minute)
-
-							proyect.mu.Lock()
-							proyect.actionsCounter++
-							proyect.mu.Unlock()
-						} else {
-							proyect.mu.Lock()
-							proyect.saveTimer = time.NewTimer(5 * time.Minute)
-							proyect.actionsCounter = 1
-							proyect.mu.Unlock()
-
-							go func() {
-								<-proyect.saveTimer.C
-
-								// Guardado fuera del lock
-								err := a.repo.SaveRoom(context.Background(), models.Project{ID: proyect.ID, ProjectInfo: proyect.ProjectInfo, Data: proyect.Data, Config: proyect.Config, Fosil: proyect.Fosil, Facies: proyect.Facies, Muestras: proyect.Muestras, Shared: proyect.Shared})
-								if err != nil {
-									log.Println("Error guardando el proyecto automáticamente: ", err)
-								} else {
-									log.Println("Proyecto guardado: ", proyect.ID)
-								}
-
-								proyect.mu.Lock()
-								proyect.saveTimer = nil
-								proyect.mu.Unlock()
-							}()
-						}
-					}()
+				if err != nil {
+					client.SendJSON(ErrorMessage{Action: "error", Message: err.Error()})
 				}
+				continue
+			}
 
-				proyect.mu.Lock()
-
-				switch dataMap.Action {
-
-				case "undo":
-					undo(proyect)
-
-				case "redo":
-					redo(proyect)
-
-				case "deletetokenLink":
-
-					removeSharedPass(proyect)
-
-				case "generateTokenLink":
-
-					generateTokenLink(conn, roomID, user, proyect)
-
-				case "infoP":
-					var dataP dtos.EditInfoProject
-					err := json.Unmarshal(dataMap.Data, &dataP)
-					if err != nil {
-						log.Println("Error", err)
-						break
-					}
-
-					proyect.editInfoProject(dataP)
-
-				case "editingUser":
-
-					var editing dtos.UserEditingState
-					err := json.Unmarshal(dataMap.Data, &editing)
-					if err != nil {
-						log.Println("Error al deserializar: ", err)
-					}
-
-					section := editing.Section
-
-					proyect.Active[userID].Editing = section
-
-					msgData := map[string]interface{}{
-						"action": "editingUser",
-						"value":  section,
-						"data": map[string]interface{}{
-							"id":    userID,
-							"name":  user,
-							"color": proyect.Active[userID].Color,
-						},
-					}
-
-					sendSocketMessage(msgData, proyect, "editingUser")
-
-				case "deleteEditingUser":
-					var editing dtos.UserEditingState
-					err := json.Unmarshal(dataMap.Data, &editing)
-					if err != nil {
-						log.Println("Error al deserializar: ", err)
-						break
-					}
-
-					section := editing.Section
-
-					proyect.Active[userID].Editing = ""
-
-					msgData := map[string]interface{}{
-						"action":   "deleteEditingUser",
-						"value":    section,
-						"userName": user,
-					}
-
-					sendSocketMessage(msgData, proyect, "deleteEditingUser")
-
-				case "añadir":
-
-					var addData dtos.Add
-					err := json.Unmarshal(dataMap.Data, &addData)
-					if err != nil {
-						log.Println("Error al deserializar: ", err)
-						break
-					}
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								añadir(proyect, addData, models.NewShape())
-							},
-							Undo: func() {
-								deleteRow(proyect, dtos.Delete{RowIndex: addData.RowIndex})
-							},
-						},
-					)
-				case "drop":
-					var drop dtos.Drop
-					err := json.Unmarshal(dataMap.Data, &drop)
-					if err != nil {
-						log.Println("Error al deserializar: ", err)
-					}
-					activeId := drop.ActiveId
-					overId := drop.OverId
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								layerDrop(proyect, activeId, overId)
-							},
-							Undo: func() {
-								layerDrop(proyect, overId, activeId)
-							},
-						},
-					)
-
-				case "addCircle":
-
-					var addCircleData dtos.AddCircle
-					err := json.Unmarshal(dataMap.Data, &addCircleData)
-					if err != nil {
-						log.Println("Error al deserializar: ", err)
-						break
-					}
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								addCircle(proyect, addCircleData, models.NewCircle(addCircleData.Point))
-							},
-							Undo: func() {
-								deleteCircle(proyect, dtos.DeleteCircle{RowIndex: addCircleData.RowIndex, DeleteIndex: addCircleData.InsertIndex})
-							},
-						},
-					)
-
-				case "addFosil":
-
-					var fosil models.Fosil
-					err := json.Unmarshal(dataMap.Data, &fosil)
-					if err != nil {
-						log.Println("Error", err)
-						break
-					}
-
-					id := shortuuid.New()
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								addFosil(proyect, id, fosil)
-							},
-							Undo: func() {
-								deleteFosil(proyect, dtos.DeleteFosil{IdFosil: id})
-							},
-						},
-					)
-				case "addMuestra":
-
-					var muestra models.Muestra
-					err := json.Unmarshal(dataMap.Data, &muestra)
-					if err != nil {
-						log.Println("Error", err)
-						break
-					}
-
-					id := shortuuid.New()
-
-					log.Println(muestra, id, "aquiii")
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								addMuestra(proyect, id, muestra)
-							},
-							Undo: func() {
-								deleteMuestra(proyect, dtos.DeleteMuestra{IdMuestra: id})
-							},
-						},
-					)
-
-				case "addFacie":
-
-					var facie dtos.Facie
-					err := json.Unmarshal(dataMap.Data, &facie)
-					if err != nil {
-						log.Println("Error", err)
-						break
-					}
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								addFacie(proyect, facie, nil)
-							},
-							Undo: func() {
-								deleteFacie(proyect, facie)
-							},
-						},
-					)
-
-				case "addFacieSection":
-
-					var f dtos.AddFacieSection
-					err := json.Unmarshal(dataMap.Data, &f)
-					if err != nil {
-						log.Println("Error", err)
-						break
-					}
-
-					previousIndex := len(proyect.Facies[f.Facie])
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								addFacieSection(proyect, f, models.FaciesSection{Y1: f.Y1, Y2: f.Y2})
-							},
-							Undo: func() {
-								deleteFacieSection(proyect, dtos.DeleteFacieSection{Facie: f.Facie, Index: previousIndex})
-							},
-						},
-					)
-
-				case "editCircle":
-
-					var editCircles dtos.EditCircle
-					err := json.Unmarshal(dataMap.Data, &editCircles)
-					if err != nil {
-						log.Println("Error al deserializar: ", err)
-						break
-					}
-
-					oldx := proyect.Data[editCircles.RowIndex].Litologia.Circles[editCircles.EditIndex].X
-					oldname := proyect.Data[editCircles.RowIndex].Litologia.Circles[editCircles.EditIndex].Name
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								editCircle(proyect, editCircles)
-							},
-							Undo: func() {
-								editCircle(proyect, dtos.EditCircle{RowIndex: editCircles.RowIndex, EditIndex: editCircles.EditIndex, X: oldx, Name: oldname})
-							},
-						},
-					)
-
-				case "editText":
-
-					var editTextData dtos.EditText
-					err := json.Unmarshal(dataMap.Data, &editTextData)
-					if err != nil {
-						log.Println("Error al deserializar: ", err)
-						break
-					}
-
-					log.Print(editTextData)
-
-					// oldvalue := GetFieldString(proyect.Data[editTextData.RowIndex], editTextData.Key)
-					oldvalue, ok := proyect.Data[editTextData.RowIndex].Columns[editTextData.Key].(string)
-					if !ok {
-						oldvalue = ""
-					}
-					textData := editTextData
-					textData.Value = oldvalue
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								editText(proyect, editTextData)
-							},
-							Undo: func() {
-								editText(proyect, textData)
-							},
-						},
-					)
-
-				case "editPolygon":
-
-					var polygon dtos.EditPolygon
-					err := json.Unmarshal(dataMap.Data, &polygon)
-					if err != nil {
-						log.Println("Error deserializando el polygon:", err)
-						break
-					}
-
-					oldvalue := GetFieldString(proyect.Data[polygon.RowIndex].Litologia, polygon.Column)
-					editpolygon := polygon
-					editpolygon.Value = oldvalue
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								editPolygon(proyect, polygon)
-							},
-							Undo: func() {
-								editPolygon(proyect, editpolygon)
-							},
-						},
-					)
-
-				case "editFosil":
-
-					var fosil dtos.EditFosil
-					err := json.Unmarshal(dataMap.Data, &fosil)
-					if err != nil {
-						log.Println("Error deserializando fósil:", err)
-						break
-					}
-
-					oldFosil := proyect.Fosil[fosil.IdFosil]
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								editFosil(proyect, fosil.IdFosil, models.NewFosil(fosil.Upper, fosil.Lower, fosil.FosilImg, fosil.X))
-							},
-							Undo: func() {
-								editFosil(proyect, fosil.IdFosil, oldFosil)
-							},
-						},
-					)
-
-				case "editMuestra":
-
-					var muestra dtos.EditMuestra
-					err := json.Unmarshal(dataMap.Data, &muestra)
-					if err != nil {
-						log.Println("Error deserializando muestra:", err)
-						break
-					}
-
-					oldMuestra := proyect.Muestras[muestra.IdMuestra]
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								editMuestra(proyect, muestra.IdMuestra, models.NewMuestra(muestra.Upper, muestra.Lower, muestra.MuestraText, muestra.X))
-							},
-							Undo: func() {
-								editMuestra(proyect, muestra.IdMuestra, oldMuestra)
-							},
-						},
-					)
-
-				case "delete":
-
-					var deleteData dtos.Delete
-					err := json.Unmarshal(dataMap.Data, &deleteData)
-					if err != nil {
-						log.Println("Error al deserializar: ", err)
-						break
-					}
-
-					copia := proyect.Data[deleteData.RowIndex]
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								deleteRow(proyect, deleteData)
-							},
-							Undo: func() {
-								añadir(proyect, dtos.Add{RowIndex: deleteData.RowIndex}, copia)
-							},
-						},
-					)
-
-				case "deleteCircle":
-
-					var delCircle dtos.DeleteCircle
-					err := json.Unmarshal(dataMap.Data, &delCircle)
-					if err != nil {
-						log.Println("Error al deserializar: ", err)
-						break
-					}
-
-					oldcircle := proyect.Data[delCircle.RowIndex].Litologia.Circles[delCircle.DeleteIndex]
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								deleteCircle(proyect, delCircle)
-							},
-							Undo: func() {
-								addCircle(proyect, dtos.AddCircle{RowIndex: delCircle.RowIndex, InsertIndex: delCircle.DeleteIndex}, oldcircle)
-							},
-						},
-					)
-
-				case "deleteFosil":
-
-					var fosilID dtos.DeleteFosil
-					err := json.Unmarshal(dataMap.Data, &fosilID)
-					if err != nil {
-						log.Println("Error deserializando fósil:", err)
-						break
-					}
-
-					fosil := proyect.Fosil[fosilID.IdFosil]
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								deleteFosil(proyect, fosilID)
-							},
-							Undo: func() {
-								addFosil(proyect, fosilID.IdFosil, fosil)
-							},
-						},
-					)
-
-				case "deleteMuestra":
-
-					var muestraID dtos.DeleteMuestra
-					err := json.Unmarshal(dataMap.Data, &muestraID)
-					if err != nil {
-						log.Println("Error deserializando fósil:", err)
-						break
+			if permission != 2 {
+				// Todo el mensaje se procesa en la goroutine de la sala, ver actor.go
+				err = proyect.do(func() {
+					if dataMap.Action != "editingUser" && dataMap.Action != "deleteEditingUser" && dataMap.Action != "columns" {
+						a.autosave(proyect, user)
 					}
 
-					muestra := proyect.Muestras[muestraID.IdMuestra]
+					proyect.touchLease(userID)
 
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								deleteMuestra(proyect, muestraID)
-							},
-							Undo: func() {
-								addMuestra(proyect, muestraID.IdMuestra, muestra)
-							},
-						},
-					)
+					switch dataMap.Action {
 
-				case "deleteFacie":
+					case "generateTokenLink":
 
-					var facie dtos.Facie
-					err := json.Unmarshal(dataMap.Data, &facie)
-					if err != nil {
-						log.Println("Error", err)
-						break
-					}
+						generateTokenLink(client, roomID, user, proyect)
 
-					oldfacie := proyect.Facies[facie.Facie]
+					case "editingUser":
 
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								deleteFacie(proyect, facie)
-							},
-							Undo: func() {
-								addFacie(proyect, facie, oldfacie)
-							},
-						},
-					)
-
-				case "deleteFacieSection":
-
-					var f dtos.DeleteFacieSection
-					err := json.Unmarshal(dataMap.Data, &f)
-					if err != nil {
-						log.Println("Error", err)
-						break
-					}
-
-					var removedSection models.FaciesSection
-					if sections, exists := proyect.Facies[f.Facie]; exists && f.Index >= 0 && f.Index < len(sections) {
-						removedSection = sections[f.Index]
-					}
-
-					performAction(proyect,
-						Action{
-							Execute: func() {
-								deleteFacieSection(proyect, f)
-							},
-							Undo: func() {
-								addFacieSection(proyect, dtos.AddFacieSection{Facie: f.Facie, Index: f.Index}, removedSection)
-							},
-						},
-					)
-				case "addColumn":
-
-					var column models.Column
-					err := json.Unmarshal(dataMap.Data, &column)
-					if err != nil {
-						log.Println("Error deserializando columna:", err)
-						break
-					}
-
-					if strings.TrimSpace(column.Name) == "" {
-						break
-					}
-
-					column.Visible = true
-					column.Removable = true
+						var editing dtos.UserEditingState
+						err := json.Unmarshal(dataMap.Data, &editing)
+						if err != nil {
+							log.Println("Error al deserializar: ", err)
+						}
 
-					found := false
-					for _, col := range proyect.Config.Columns {
-						if col.Name == column.Name {
-							found = true
+						if err := proyect.acquireLease(userID, editing); err != nil {
+							client.SendJSON(ErrorMessage{Action: "error", Message: err.Error()})
 							break
 						}
-					}
-					if !found {
-						proyect.Config.Columns = append(proyect.Config.Columns, column)
 
-						sendSocketMessage(map[string]interface{}{
-							"action": "addColumn",
-							"column": column,
-						}, proyect, "addColumn")
-					}
+						section := editing.Section
 
-				case "deleteColumn":
+						proyect.Active[userID].Editing = section
 
-					var column models.Column
-					err := json.Unmarshal(dataMap.Data, &column)
-					if err != nil {
-						log.Println("Error deserializando columna:", err)
-						break
-					}
+						msgData := map[string]interface{}{
+							"action": "editingUser",
+							"value":  section,
+							"data": map[string]interface{}{
+								"id":    userID,
+								"name":  user,
+								"color": proyect.Active[userID].Color,
+							},
+						}
 
-					columnDeleted := false
+						sendSocketMessage(msgData, proyect, "editingUser")
 
-					for i, col := range proyect.Config.Columns {
-						if col.Name == column.Name {
-							log.Println(col.Removable)
-							if col.Removable {
-								proyect.Config.Columns = append(proyect.Config.Columns[:i], proyect.Config.Columns[i+1:]...)
-								columnDeleted = true
-							}
+					case "deleteEditingUser":
+						var editing dtos.UserEditingState
+						err := json.Unmarshal(dataMap.Data, &editing)
+						if err != nil {
+							log.Println("Error al deserializar: ", err)
 							break
 						}
-					}
-
-					if columnDeleted {
-						sendSocketMessage(map[string]interface{}{
-							"action": "deleteColumn",
-							"column": column.Name,
-						}, proyect, "deleteColumn")
-					}
-
-				case "isInverted":
 
-					isInverted(proyect, dataMap)
+						section := editing.Section
 
-				case "toggleColumn":
-					var column dtos.Column
-					err := json.Unmarshal(dataMap.Data, &column)
-					if err != nil {
-						log.Println("Error deserializando columna:", err)
-						break
-					}
+						proyect.releaseLease(userID)
+						proyect.Active[userID].Editing = ""
 
-					for i, col := range proyect.Config.Columns {
-						if col.Name == column.Column {
-							proyect.Config.Columns[i].Visible = !proyect.Config.Columns[i].Visible
-							break
+						msgData := map[string]interface{}{
+							"action":   "deleteEditingUser",
+							"value":    section,
+							"userName": user,
 						}
-					}
 
-					sendSocketMessage(map[string]interface{}{
-						"action": "toggleColumn",
-						"column": column.Column,
-					}, proyect, "toggleColumn")
+						sendSocketMessage(msgData, proyect, "deleteEditingUser")
 
-				case "MoveColumn":
-					var drop dtos.Drop
-					err := json.Unmarshal(dataMap.Data, &drop)
-					if err != nil {
-						log.Println("Error al deserializar: ", err)
-					}
+					case "save":
 
-					activeId := drop.ActiveId
-					overId := drop.OverId
+						a.save(proyect, user)
 
-					roomData := proyect.Config.Columns
-
-					if activeId < 0 || activeId >= len(roomData) || overId < 0 || overId >= len(roomData) {
-						log.Println("Índice fuera de los límites")
-						break
-					}
+					default:
 
-					arrayMove(roomData, activeId, overId)
+						if err := a.record(proyect, user, userID, dataMap.Action, dataMap.Data); err != nil && !errors.Is(err, errUnknownAction) {
+							client.SendJSON(ErrorMessage{Action: "error", Message: err.Error()})
+						}
 
-					msgData := map[string]interface{}{
-						"action":   "MoveColumn",
-						"activeId": activeId,
-						"overId":   overId,
 					}
-
-					sendSocketMessage(msgData, proyect, "MoveColumn")
-
-				case "save":
-
-					a.save(proyect)
-
+				})
+				if err != nil {
+					break
 				}
-				proyect.mu.Unlock()
 			} else {
 				errMessage := "Error: Don't have permission to edit this document"
-				conn.WriteMessage(websocket.TextMessage, []byte(errMessage))
+				client.Send([]byte(errMessage))
 			}
 		}
 	}
 
-	RemoveElement(a, roomID, userID, proyect)
+	RemoveElement(a, roomID, userID, user, proyect)
 
 	return nil
 }
 
+// sharedAction indica si la acción la pueden mandar también los lectores
+func sharedAction(action string) bool {
+	switch action {
+	case "resync", "presence", "follow", "chat", "chatRead":
+		return true
+	}
+	return false
+}
+
+func (a *API) handleShared(proyect *RoomData, client *Client, userID string, user string, msg GeneralMessage) error {
+	switch msg.Action {
+	case "resync":
+		return proyect.resync(client, resyncRequest(msg.Data))
+	case "presence":
+		return proyect.setPresence(userID, msg.Data)
+	case "follow":
+		return proyect.follow(userID, msg.Data)
+	case "chat":
+		return a.chat(proyect, user, msg.Data)
+	case "chatRead":
+		return a.chatRead(proyect, user, msg.Data)
+	}
+	return errUnknownAction
+}
+
 // solicitud http para mostrar la cantidad de goroutines
 func (a *API) HandleGoroutines(c echo.Context) error {
 	return c.String(http.StatusOK, fmt.Sprintf("Número total de goroutines: %d", runtime.NumGoroutine()))
 }
 
 func sendSocketMessage(msgData map[string]interface{}, project *RoomData, action string) {
-	jsonMsg, err := json.Marshal(msgData)
+	// Dentro de un batch los mensajes se juntan y se envían al final, ver batch.go
+	if project.batching {
+		jsonMsg, err := json.Marshal(msgData)
+		if err != nil {
+			log.Println("Error serializing message:", err)
+			return
+		}
+
+		project.pending = append(project.pending, jsonMsg)
+		return
+	}
+
+	jsonMsg, err := project.sequence(msgData)
 	if err != nil {
 		log.Println("Error serializing message:", err)
 		return
 	}
 
 	for _, client := range project.Active {
-		client.Conn.WriteMessage(websocket.TextMessage, jsonMsg)
+		client.Conn.Send(jsonMsg)
 	}
 
 }
 
-func (a *API) instanceRoom(ctx context.Context, roomID string) *RoomData {
-	// Intenta cargar la sala existente desde sync.Map
-	if existingRoom, ok := rooms.Load(roomID); ok {
-		return existingRoom.(*RoomData)
-	}
-
-	// Si la sala no existe, se crea una nueva instancia de RoomData
-	room, err := a.repo.GetRoom(ctx, roomID)
-	if err != nil {
-		return nil
-	}
-
-	newRoom := &RoomData{
+// newRoomData arma una sala sin usuarios a partir de un proyecto guardado
+func newRoomData(room *models.Project) *RoomData {
+	r := &RoomData{
 		ID:          room.ID,
 		ProjectInfo: room.ProjectInfo,
 		Data:        room.Data,
@@ -936,19 +429,75 @@ func (a *API) instanceRoom(ctx context.Context, roomID string) *RoomData {
 		Fosil:       room.Fosil,
 		Facies:      room.Facies,
 		Muestras:    room.Muestras,
+		Logs:        room.Logs,
 		Shared:      room.Shared,
 		Active:      make(map[string]*UserConnection),
-		undoStack:   make([]Action, 0),
-		redoStack:   make([]Action, 0),
+		opSeq:       room.Seq,
+		streamID:    shortuuid.New(),
+		commands:    make(chan func()),
+		quit:        make(chan struct{}),
+		closed:      make(chan struct{}),
+	}
+
+	r.ensureRowIDs()
+	r.loadHistory(room.History)
+
+	return r
+}
+
+func (a *API) instanceRoom(ctx context.Context, roomID string) (*RoomData, error) {
+	// Intenta cargar la sala existente desde sync.Map
+	if existingRoom, ok := rooms.Load(roomID); ok {
+		room := existingRoom.(*RoomData)
+		if !room.stopped() {
+			return room, nil
+		}
+
+		// Se está cerrando: se espera a que se guarde para abrirla de nuevo
+		select {
+		case <-room.closed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return a.instanceRoom(ctx, roomID)
+	}
+
+	// Solo se abre si ninguna otra instancia la tiene abierta (ver cluster.go)
+	epoch, err := a.acquireRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Si la sala no existe, se crea una nueva instancia de RoomData
+	room, err := a.repo.GetRoom(ctx, roomID)
+	if err != nil {
+		if err := a.repo.ReleaseRoomLease(context.Background(), roomID, a.instanceID); err != nil {
+			log.Println("Error soltando la sala: ", err)
+		}
+		return nil, err
+	}
+
+	newRoom := newRoomData(room)
+	newRoom.epoch = epoch
+
+	// Si quedaron operaciones sin guardar (por ejemplo tras una caída) se vuelven a aplicar
+	ops, err := a.repo.GetOperations(ctx, roomID, room.Seq, 0, 0)
+	if err != nil {
+		log.Println("Error leyendo el registro de operaciones: ", err)
+	} else if len(ops) > 0 {
+		skipped := a.replay(ctx, newRoom, ops)
+		log.Println("Recovered ", len(ops)-len(skipped), " operations for room ", roomID, " skipped: ", skipped)
 	}
 
 	// Almacena la nueva sala en el mapa si no existe (control de concurrencia)
 	actualRoom, loaded := rooms.LoadOrStore(roomID, newRoom)
 	if loaded {
-		return actualRoom.(*RoomData) // Si ya existe, usa la instancia creada por otro goroutine
+		return actualRoom.(*RoomData), nil // Si ya existe, usa la instancia creada por otro goroutine
 	}
 
-	return newRoom // Devuelve la nueva sala creada
+	newRoom.start()
+	a.holdRoom(roomID, newRoom)
+	return newRoom, nil // Devuelve la nueva sala creada
 }
 
 func (a *API) HandleGetActiveProject(c echo.Context) error {
@@ -965,42 +514,11 @@ func (a *API) HandleGetActiveProject(c echo.Context) error {
 			return true // Continua si el valor no es de tipo RoomData
 		}
 
-		var activeUsersCopy map[string]*UserConnection
-
-		// Intentamos leer `room.Active` sin bloqueo y recuperamos si ocurre un error
-		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					// Si ocurre un panic, simplemente continuamos
-					activeUsersCopy = nil
-				}
-			}()
-
-			// Intentamos hacer una copia de `room.Active` sin `mutex`
-			activeUsersCopy = make(map[string]*UserConnection, len(room.Active))
-			for k, v := range room.Active {
-				activeUsersCopy[k] = &UserConnection{
-					Email:   v.Email,
-					Editing: v.Editing,
-					Color:   v.Color,
-				}
-			}
-		}()
-
-		// Si falló la copia, continuamos a la siguiente iteración
-		if activeUsersCopy == nil {
+		var users []map[string]string
+		if err := room.do(func() { users = room.activeUsers() }); err != nil {
 			return true
 		}
 
-		users := make([]map[string]string, 0, len(activeUsersCopy))
-		for _, userConn := range activeUsersCopy {
-			users = append(users, map[string]string{
-				"email":   userConn.Email,
-				"editing": userConn.Editing,
-				"color":   userConn.Color,
-			})
-		}
-
 		roomInfo := map[string]interface{}{
 			"roomID": key.(string),
 			"users":  users,
@@ -1099,15 +617,17 @@ func deleteRow(project *RoomData, deleteData dtos.Delete) {
 	sendSocketMessage(msgData, project, "delete")
 }
 
-func editText(project *RoomData, editTextData dtos.EditText) {
-
-	key := editTextData.Key
-	value := editTextData.Value
-	rowIndex := editTextData.RowIndex
+// editText cambia una celda. value mantiene su tipo (al deshacer vuelve el
+// valor original); nil borra la celda
+func editText(project *RoomData, rowIndex int, key string, value interface{}) {
 
 	roomData := &project.Data[rowIndex]
 
-	roomData.Columns[key] = value
+	if value == nil {
+		delete(roomData.Columns, key)
+	} else {
+		roomData.Columns[key] = value
+	}
 
 	msgData := map[string]interface{}{
 		"action":   "editText",
@@ -1284,6 +804,42 @@ func deleteMuestra(project *RoomData, muestraID dtos.DeleteMuestra) {
 
 }
 
+func addLog(project *RoomData, id string, newLog models.WellLog) {
+
+	if project.Logs == nil {
+		project.Logs = make(map[string]models.WellLog)
+	}
+
+	project.Logs[id] = newLog
+
+	msgData := map[string]interface{}{
+		"action": "addLog",
+		"idLog":  id,
+		"value":  newLog,
+	}
+
+	sendSocketMessage(msgData, project, "addLog")
+
+}
+
+func deleteLog(project *RoomData, logID dtos.DeleteLog) {
+
+	id := logID.IdLog
+	if _, exists := project.Logs[id]; !exists {
+		return
+	}
+
+	delete(project.Logs, id)
+
+	msgData := map[string]interface{}{
+		"action": "deleteLog",
+		"idLog":  id,
+	}
+
+	sendSocketMessage(msgData, project, "deleteLog")
+
+}
+
 func editFosil(project *RoomData, id string, newFosil models.Fosil) {
 
 	roomData := &project.Fosil
@@ -1369,22 +925,15 @@ func deleteFacie(project *RoomData, facie dtos.Facie) {
 
 }
 
-func isInverted(project *RoomData, dataMap GeneralMessage) {
+func isInverted(project *RoomData, inverted bool) {
 
-	var isInverted dtos.IsInverted
-	err := json.Unmarshal(dataMap.Data, &isInverted)
-	if err != nil {
-		log.Println("Error deserializando columna:", err)
-		return
-	}
-
-	project.Config.IsInverted = isInverted.IsInverted
+	project.Config.IsInverted = inverted
 
 	project.UpdateCoord(project.GetSizeRoom())
 
 	msgData := map[string]interface{}{
 		"action":     "isInverted",
-		"isInverted": isInverted.IsInverted,
+		"isInverted": inverted,
 		"facies":     project.Facies,
 		"fosil":      project.Fosil,
 		"muestras":   project.Muestras,
@@ -1394,6 +943,102 @@ func isInverted(project *RoomData, dataMap GeneralMessage) {
 
 }
 
+// addColumn inserta la columna en la posición index y, si se indican, vuelve a
+// poner los valores que tenía en cada capa (al deshacer un deleteColumn)
+func addColumn(project *RoomData, index int, column models.Column, values map[string]interface{}) {
+	columns := project.Config.Columns
+
+	if index < 0 || index > len(columns) {
+		index = len(columns)
+	}
+
+	columns = append(columns, models.Column{})
+	copy(columns[index+1:], columns[index:])
+	columns[index] = column
+	project.Config.Columns = columns
+
+	restored := make(map[int]interface{})
+	for i, row := range project.Data {
+		if value, ok := values[row.ID]; ok {
+			row.Columns[column.Name] = value
+			restored[i] = value
+		}
+	}
+
+	msgData := map[string]interface{}{
+		"action": "addColumn",
+		"column": column,
+		"index":  index,
+	}
+	if len(restored) > 0 {
+		msgData["values"] = restored
+	}
+
+	sendSocketMessage(msgData, project, "addColumn")
+}
+
+// deleteColumn quita la columna y sus valores de todas las capas. Devuelve la
+// posición y los valores que tenía, por ID de capa, para poder deshacerlo
+func deleteColumn(project *RoomData, name string) (int, map[string]interface{}) {
+	index := -1
+	for i, col := range project.Config.Columns {
+		if col.Name == name {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return -1, nil
+	}
+
+	project.Config.Columns = append(project.Config.Columns[:index], project.Config.Columns[index+1:]...)
+
+	values := make(map[string]interface{})
+	for _, row := range project.Data {
+		if value, ok := row.Columns[name]; ok {
+			values[row.ID] = value
+			delete(row.Columns, name)
+		}
+	}
+
+	msgData := map[string]interface{}{
+		"action": "deleteColumn",
+		"column": name,
+	}
+
+	sendSocketMessage(msgData, project, "deleteColumn")
+
+	return index, values
+}
+
+func toggleColumn(project *RoomData, name string) {
+	for i, col := range project.Config.Columns {
+		if col.Name == name {
+			project.Config.Columns[i].Visible = !project.Config.Columns[i].Visible
+			break
+		}
+	}
+
+	msgData := map[string]interface{}{
+		"action": "toggleColumn",
+		"column": name,
+	}
+
+	sendSocketMessage(msgData, project, "toggleColumn")
+}
+
+func moveColumn(project *RoomData, activeId int, overId int) {
+	arrayMove(project.Config.Columns, activeId, overId)
+
+	msgData := map[string]interface{}{
+		"action":   "MoveColumn",
+		"activeId": activeId,
+		"overId":   overId,
+	}
+
+	sendSocketMessage(msgData, project, "MoveColumn")
+}
+
 func (r *RoomData) GetSizeRoom() float32 {
 	var height float32
 	for _, Lit := range r.Data {
@@ -1428,13 +1073,9 @@ func (room *RoomData) UpdateCoord(totalHeight float32) {
 	}
 }
 
-func (a *API) save(project *RoomData) {
-
-	err := a.repo.SaveRoom(context.Background(), models.Project{ID: project.ID, ProjectInfo: project.ProjectInfo, Data: project.Data, Config: project.Config, Fosil: project.Fosil, Facies: project.Facies, Muestras: project.Muestras, Shared: project.Shared})
-	if err != nil {
-		log.Println("No se guardo la data")
-	}
-
+// save guarda la sala a pedido del usuario, fuera de la goroutine de la sala
+func (a *API) save(project *RoomData, author string) {
+	go a.saveProject(project.snapshot(), author, models.RevisionManual)
 }
 
 func addFacieSection(project *RoomData, f dtos.AddFacieSection, section models.FaciesSection) {
@@ -1490,7 +1131,10 @@ func contains(slice []string, value string) bool {
 	return false
 }
 
-func generateTokenLink(conn *websocket.Conn, roomID string, user string, proyect *RoomData) {
+// generateTokenLink envía al dueño los enlaces de invitación, creando la
+// contraseña la primera vez. No se registra como operación para no dejar la
+// contraseña en el registro, ver replay
+func generateTokenLink(conn *Client, roomID string, user string, proyect *RoomData) {
 	if user == proyect.ProjectInfo.Members.Owner {
 
 		storedpass := proyect.Shared.Pass
@@ -1528,7 +1172,7 @@ func generateTokenLink(conn *websocket.Conn, roomID string, user string, proyect
 			log.Println("Error al serializar mensaje:", err)
 		}
 
-		conn.WriteMessage(websocket.TextMessage, shareproyect)
+		conn.Send(shareproyect)
 	}
 }
 
@@ -1536,6 +1180,11 @@ func removeSharedPass(proyect *RoomData) {
 	proyect.Shared.Pass = ""
 }
 
+// restoreSharedPass vuelve a activar los enlaces de invitación generados con esa contraseña
+func restoreSharedPass(proyect *RoomData, pass string) {
+	proyect.Shared.Pass = pass
+}
+
 func (a *API) ValidateInvitation(c echo.Context) error {
 	ctx, claimsAuth, err := a.getContextAndClaims(c)
 	if err != nil {
@@ -1566,13 +1215,17 @@ func (a *API) ValidateInvitation(c echo.Context) error {
 	if exists {
 		existingRoom := roomInterface.(*RoomData)
 
-		// Bloqueo de la sala para operaciones concurrentes internas
-		existingRoom.mu.Lock()
-		defer existingRoom.mu.Unlock()
+		err := existingRoom.do(func() {
+			m := existingRoom.ProjectInfo.Members
+			members = &m
+			pass = existingRoom.Shared.Pass
+		})
+
+		// La sala se cerró mientras tanto
+		exists = err == nil
+	}
 
-		members = &existingRoom.ProjectInfo.Members
-		pass = existingRoom.Shared.Pass
-	} else {
+	if !exists {
 		// Sala no está en memoria; accede a la base de datos para obtener miembros y pass
 		var err error
 		members, pass, err = a.repo.GetMembersAndPass(ctx, claims.RoomID)
@@ -1601,12 +1254,14 @@ func (a *API) ValidateInvitation(c echo.Context) error {
 	// Añadir al usuario como miembro si no existe
 	if exists {
 		existingRoom := roomInterface.(*RoomData)
-		switch claims.Role {
-		case "editors":
-			existingRoom.ProjectInfo.Members.Editors = append(existingRoom.ProjectInfo.Members.Editors, email)
-		case "readers":
-			existingRoom.ProjectInfo.Members.Readers = append(existingRoom.ProjectInfo.Members.Readers, email)
-		}
+		existingRoom.do(func() {
+			switch claims.Role {
+			case "editors":
+				existingRoom.ProjectInfo.Members.Editors = append(existingRoom.ProjectInfo.Members.Editors, email)
+			case "readers":
+				existingRoom.ProjectInfo.Members.Readers = append(existingRoom.ProjectInfo.Members.Readers, email)
+			}
+		})
 	}
 
 	// Persistencia en la base de datos
@@ -1614,13 +1269,15 @@ func (a *API) ValidateInvitation(c echo.Context) error {
 		return a.handleError(c, http.StatusInternalServerError, "Server error")
 	}
 
+	// Si la sala está abierta en otra instancia se le avisa
+	if !exists {
+		a.publishMembers(ctx, claims.RoomID)
+	}
+
 	return c.JSON(http.StatusOK, response)
 }
 
-func (r *RoomData) AddUser(conn *websocket.Conn, email string, userID string) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
+func (r *RoomData) addUser(conn *Client, email string, userID string) {
 	r.Active[userID] = &UserConnection{
 		Email: email,
 		Conn:  conn,
@@ -1628,21 +1285,22 @@ func (r *RoomData) AddUser(conn *websocket.Conn, email string, userID string) {
 	}
 }
 
-func (r *RoomData) DisconnectUsers() error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (r *RoomData) DisconnectUsers(message CloseMessage) error {
+	var err error
+	if derr := r.do(func() { err = r.disconnectUsers(message) }); derr != nil {
+		return derr
+	}
+	return err
+}
+
+func (r *RoomData) disconnectUsers(message CloseMessage) error {
+	var err error
 
-	// Desconectar a todos los usuarios conectados
+	// Desconectar a todos los usuarios conectados, aunque alguno falle
 	for _, client := range r.Active {
 		if client != nil {
-			message := ErrorMessage{
-				Action:  "close",
-				Message: "project closed",
-			}
-
-			err := client.Conn.WriteJSON(message)
-			if err != nil {
-				return fmt.Errorf("error sending close message to user %s: %w", client.Email, err)
+			if serr := client.Conn.SendJSON(message); serr != nil && err == nil {
+				err = fmt.Errorf("error sending close message to user %s: %w", client.Email, serr)
 			}
 
 			client.Conn.Close()
@@ -1651,19 +1309,18 @@ func (r *RoomData) DisconnectUsers() error {
 
 	r.Active = make(map[string]*UserConnection)
 	log.Println("All users disconnected from room: ", r.ID)
-	return nil
+	return err
 }
 
-func (r *RoomData) DisconnectUser(userID string) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
+func (r *RoomData) disconnectUser(userID string) error {
 	client, exists := r.Active[userID]
 	if !exists {
 		return fmt.Errorf("user %s not found", userID)
 	}
 
 	client.Conn.Close()
+	r.releaseLease(userID)
+	r.unfollow(userID)
 
 	log.Println("\033[35m User disconnected: ", client.Email, "\033[0m")
 	delete(r.Active, userID)
@@ -1712,8 +1369,12 @@ func (r *RoomData) DataProject() map[string]interface{} {
 		"fosil":       r.Fosil,
 		"muestras":    r.Muestras,
 		"facies":      r.Facies,
+		"logs":        r.Logs,
 		"users":       users,
 		"userEditing": userEditing,
+		"presence":    r.presenceSnapshot(),
+		"seq":         r.streamSeq,
+		"stream":      r.streamID,
 	}
 
 	return dataRoom
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// storedProject guarda el proyecto con su historial en BSON y lo vuelve a
+// leer, como al cerrar y abrir la sala
+func storedProject(t *testing.T, room *RoomData) *models.Project {
+	t.Helper()
+
+	data, err := bson.Marshal(room.snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var project models.Project
+	if err := bson.Unmarshal(data, &project); err != nil {
+		t.Fatal(err)
+	}
+	return &project
+}
+
+func TestHistoryRoundTrip(t *testing.T) {
+	room := testRoom()
+	original := storedProject(t, room)
+	user := "a@test.com"
+
+	edits := []*models.Operation{
+		editOperation(t, user, "añadir", map[string]interface{}{"rowIndex": 1}),
+		editOperation(t, user, "editText", map[string]interface{}{"key": "Espesor", "value": "5", "rowIndex": 0}),
+		editOperation(t, user, "deleteColumn", map[string]interface{}{"name": "Notas"}),
+		editOperation(t, user, "addFosil", map[string]interface{}{"upper": 1, "lower": 2, "fosilImg": "a"}),
+	}
+	for _, op := range edits {
+		if err := applyOperation(room, op); err != nil {
+			t.Fatalf("%s: %v", op.Action, err)
+		}
+	}
+
+	saved := storedProject(t, room)
+	if len(saved.History) != 1 || len(saved.History[0].Undo) != len(edits) {
+		t.Fatalf("history = %+v, want %d steps for one user", saved.History, len(edits))
+	}
+
+	// Se deshace todo en una sala abierta desde lo guardado, y los pasos
+	// registrados se repiten sobre otra copia
+	loaded := newRoomData(saved)
+	replayed := newRoomData(storedProject(t, loaded))
+
+	for range edits {
+		op := historyOperation(user, "undo")
+		if err := applyOperation(loaded, op); err != nil {
+			t.Fatal(err)
+		}
+		if op.Action != "undone" {
+			t.Fatalf("logged action = %q, want undone", op.Action)
+		}
+		if err := replayHistory(replayed, op); err != nil {
+			t.Fatalf("replayHistory() = %v", err)
+		}
+	}
+
+	for name, r := range map[string]*RoomData{"loaded": loaded, "replayed": replayed} {
+		got := r.snapshot()
+		if !reflect.DeepEqual(got.Data, original.Data) || !reflect.DeepEqual(got.Config, original.Config) || len(got.Fosil) != 0 {
+			t.Errorf("%s room was not restored\nwant: %+v\ngot:  %+v", name, original.Data, got.Data)
+		}
+		if len(r.redoStacks[user]) != len(edits) || len(r.undoStacks[user]) != 0 {
+			t.Errorf("%s room: undo %d, redo %d", name, len(r.undoStacks[user]), len(r.redoStacks[user]))
+		}
+	}
+}
+
+func TestEncodeHistorySizeLimit(t *testing.T) {
+	defer func(limit int) { historySizeLimit = limit }(historySizeLimit)
+
+	room := testRoom()
+	user := "a@test.com"
+	for i := 0; i < 5; i++ {
+		editCell(t, room, user, 0, string(rune('a'+i)))
+	}
+	// Un paso deshecho queda en rehacer y se descarta antes que los de deshacer
+	if err := applyOperation(room, historyOperation(user, "undo")); err != nil {
+		t.Fatal(err)
+	}
+
+	full := room.encodeHistory()[0]
+	size := 0
+	for _, c := range full.Undo {
+		size += len(c.Data)
+	}
+	step := len(full.Undo[0].Data)
+
+	tests := []struct {
+		name       string
+		limit      int
+		undo, redo int
+	}{
+		{"no trimming", 1 << 20, 4, 1},
+		{"redo first", size, 4, 0},
+		{"oldest undo", size - step, 3, 0},
+		{"nothing fits", 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historySizeLimit = tt.limit
+			history := room.encodeHistory()
+
+			if tt.undo == 0 && tt.redo == 0 {
+				if len(history) != 0 {
+					t.Errorf("history = %+v, want none", history)
+				}
+				return
+			}
+			if len(history) != 1 || len(history[0].Undo) != tt.undo || len(history[0].Redo) != tt.redo {
+				t.Fatalf("history = %+v, want %d undo and %d redo", history, tt.undo, tt.redo)
+			}
+			// Se quitan los más antiguos: el último paso sigue siendo el mismo
+			if last := history[0].Undo[len(history[0].Undo)-1]; !reflect.DeepEqual(last, full.Undo[len(full.Undo)-1]) {
+				t.Errorf("last step = %s, want %s", last.Data, full.Undo[len(full.Undo)-1].Data)
+			}
+		})
+	}
+}
+
+func TestLoadHistory(t *testing.T) {
+	defer func(limit int) { historyLimit = limit }(historyLimit)
+	historyLimit = 2
+
+	step := func(value string) models.Command {
+		data, _ := json.Marshal(editTextCommand{RowID: "x", Key: "Espesor", New: value})
+		return models.Command{Action: "editText", Data: data}
+	}
+
+	room := testRoom()
+	room.loadHistory([]models.UserHistory{
+		{
+			User: "a@test.com",
+			Undo: []models.Command{
+				step("1"),
+				{Action: "unknown", Data: json.RawMessage(`{}`)},
+				step("2"),
+				{Action: "editText", Data: json.RawMessage(`{"rowId": 5}`)},
+				step("3"),
+			},
+			Redo: []models.Command{step("4"), step("5"), step("6")},
+		},
+		{User: "b@test.com", Undo: []models.Command{{Action: "unknown"}}},
+	})
+
+	undo := room.undoStacks["a@test.com"]
+	if len(undo) != 2 || undo[0].Command.(*editTextCommand).New != "2" || undo[1].Command.(*editTextCommand).New != "3" {
+		t.Errorf("undo = %+v, want the last two valid steps", undo)
+	}
+	if redo := room.redoStacks["a@test.com"]; len(redo) != 2 || redo[1].Command.(*editTextCommand).New != "6" {
+		t.Errorf("redo = %+v, want the last two steps", redo)
+	}
+	if _, ok := room.undoStacks["b@test.com"]; ok {
+		t.Error("a history with only unknown steps was loaded")
+	}
+}
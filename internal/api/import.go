@@ -0,0 +1,441 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ProyectoT/api/internal/api/dtos"
+	"github.com/ProyectoT/api/internal/importer"
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lithammer/shortuuid/v4"
+)
+
+type ImportResponse struct {
+	Message    string              `json:"message"`
+	ProjectID  string              `json:"projectId,omitempty"`
+	Imported   int                 `json:"imported"`
+	NewColumns []string            `json:"newColumns"`
+	Ignored    []string            `json:"ignored"`
+	Errors     []importer.RowError `json:"errors"`
+}
+
+// canEdit indica si el usuario puede modificar el proyecto (dueño o editor)
+func canEdit(info models.ProjectInfo, user string) bool {
+	return info.Members.Owner == user || contains(info.Members.Editors, user)
+}
+
+// newProjectInfo arma la info de un proyecto nuevo cuyo dueño es el usuario autenticado
+func newProjectInfo(params dtos.Project, name string, email string) models.ProjectInfo {
+	anio, mes, dia := time.Now().Date()
+
+	return models.ProjectInfo{
+		Name:  params.RoomName,
+		Owner: name,
+		Members: models.Members{
+			Owner:   email,
+			Editors: []string{},
+			Readers: []string{},
+		},
+		CreationDate: fmt.Sprintf("%d-%02d-%02d", anio, mes, dia),
+		Description:  params.Desc,
+		Location:     params.Location,
+		Lat:          params.Lat,
+		Long:         params.Long,
+		Visible:      params.Visible,
+	}
+}
+
+// readUploadedTable lee el archivo "file" del formulario. El formato se toma
+// del campo "format" o de la extensión del archivo
+func readUploadedTable(c echo.Context) ([][]string, error) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("missing file")
+	}
+
+	format := c.FormValue("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	return importer.ReadRows(src, format, c.FormValue("sheet"))
+}
+
+// HandleImportProject crea un proyecto nuevo a partir de una planilla CSV o XLSX
+func (a *API) HandleImportProject(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	correo := claims["email"].(string)
+	name := claims["name"].(string)
+
+	var params dtos.Project
+	if err := c.Bind(&params); err != nil {
+		return a.handleError(c, http.StatusBadRequest, "Invalid request")
+	}
+
+	if err := a.dataValidator.Struct(params); err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+
+	records, err := readUploadedTable(c)
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+
+	config := models.DefaultConfig()
+
+	result, err := importer.Table(records, config.Columns)
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+
+	response := ImportResponse{
+		Imported:   len(result.Rows),
+		NewColumns: result.NewColumns,
+		Ignored:    result.Ignored,
+		Errors:     result.Errors,
+	}
+
+	if len(result.Rows) == 0 {
+		response.Message = "No rows imported"
+		return c.JSON(http.StatusBadRequest, response)
+	}
+
+	for _, column := range result.NewColumns {
+		config.Columns = append(config.Columns, models.Column{Name: column, Visible: true, Removable: true})
+	}
+
+	project := models.Project{
+		ProjectInfo: newProjectInfo(params, name, correo),
+		Data:        result.Rows,
+		Config:      config,
+		Fosil:       map[string]models.Fosil{},
+		Muestras:    map[string]models.Muestra{},
+		Facies:      map[string][]models.FaciesSection{},
+	}
+
+	id, err := a.repo.CreateProject(ctx, project)
+	if err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Failed to create a room")
+	}
+
+	response.Message = "Room created successfully"
+	response.ProjectID = id
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// HandleImportToRoom agrega las capas de una planilla al final de un proyecto
+// existente. Se aplica sobre la sala en memoria para que los usuarios
+// conectados reciban las capas nuevas
+func (a *API) HandleImportToRoom(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+
+	records, err := readUploadedTable(c)
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+
+	proyect, err := a.instanceRoom(ctx, roomID)
+	if err != nil {
+		return a.handleRoomError(c, err)
+	}
+	// Si nadie la tiene abierta se guarda y se cierra al terminar
+	defer a.closeIfIdle(roomID, proyect, user)
+
+	// Se aplica en la goroutine de la sala (ver actor.go) y se responde afuera
+	// para no demorar a los demás usuarios
+	var response ImportResponse
+	var status int
+	var message string
+
+	err = proyect.do(func() {
+		if !canEdit(proyect.ProjectInfo, user) {
+			status, message = http.StatusForbidden, "Don't have permission to edit this document"
+			return
+		}
+
+		result, err := importer.Table(records, proyect.Config.Columns)
+		if err != nil {
+			status, message = http.StatusBadRequest, err.Error()
+			return
+		}
+
+		response = ImportResponse{
+			Imported:   len(result.Rows),
+			NewColumns: result.NewColumns,
+			Ignored:    result.Ignored,
+			Errors:     result.Errors,
+		}
+
+		if len(result.Rows) == 0 {
+			status = http.StatusBadRequest
+			response.Message = "No rows imported"
+			return
+		}
+
+		data, err := json.Marshal(dtos.ImportRows{Columns: result.NewColumns, Rows: result.Rows})
+		if err != nil {
+			status, message = http.StatusInternalServerError, "Failed to import rows"
+			return
+		}
+
+		if err := a.record(proyect, user, "", "importRows", data); err != nil {
+			status, message = http.StatusConflict, err.Error()
+			return
+		}
+
+		a.save(proyect, user)
+
+		status = http.StatusOK
+		response.Message = "Rows imported successfully"
+		response.ProjectID = roomID
+	})
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	if message != "" {
+		return a.handleError(c, status, message)
+	}
+	if status == http.StatusOK {
+		log.Println("Imported ", response.Imported, " rows into project ", roomID)
+	}
+
+	return c.JSON(status, response)
+}
+
+// HandleImportLAS adjunta las curvas de un archivo LAS al proyecto. Se aplica
+// sobre la sala en memoria y se avisa a los usuarios conectados
+func (a *API) HandleImportLAS(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, "missing file")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, "Invalid request")
+	}
+	defer src.Close()
+
+	wellLog, err := importer.ReadLAS(src)
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+	wellLog.Source = file.Filename
+
+	proyect, err := a.instanceRoom(ctx, roomID)
+	if err != nil {
+		return a.handleRoomError(c, err)
+	}
+	// Si nadie la tiene abierta se guarda y se cierra al terminar
+	defer a.closeIfIdle(roomID, proyect, user)
+
+	id := shortuuid.New()
+
+	data, err := json.Marshal(dtos.AddLog{IdLog: id, Value: *wellLog})
+	if err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Failed to import log")
+	}
+
+	// Se aplica en la goroutine de la sala (ver actor.go) y se responde afuera
+	var status int
+	var message string
+
+	err = proyect.do(func() {
+		if !canEdit(proyect.ProjectInfo, user) {
+			status, message = http.StatusForbidden, "Don't have permission to edit this document"
+			return
+		}
+
+		if err := a.record(proyect, user, "", "addLog", data); err != nil {
+			status, message = http.StatusConflict, err.Error()
+			return
+		}
+
+		a.save(proyect, user)
+	})
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	if message != "" {
+		return a.handleError(c, status, message)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"idLog": id, "curves": len(wellLog.Curves), "samples": len(wellLog.Depth)})
+}
+
+// HandleImportArchive crea un proyecto nuevo a partir de un respaldo (JSON o
+// zip). El proyecto queda a nombre de quien lo importa; ?roomName= reemplaza el nombre
+func (a *API) HandleImportArchive(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	correo := claims["email"].(string)
+	name := claims["name"].(string)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, "missing file")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, "Invalid request")
+	}
+	defer src.Close()
+
+	project, err := importer.Archive(src)
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+
+	info := project.ProjectInfo
+	if roomName := c.FormValue("roomName"); roomName != "" {
+		info.Name = roomName
+	}
+
+	project.ProjectInfo = newProjectInfo(dtos.Project{
+		RoomName: info.Name,
+		Desc:     info.Description,
+		Location: info.Location,
+		Lat:      info.Lat,
+		Long:     info.Long,
+		Visible:  info.Visible,
+	}, name, correo)
+
+	id, err := a.repo.CreateProject(ctx, *project)
+	if err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Failed to create a room")
+	}
+
+	return c.JSON(http.StatusOK, ImportResponse{
+		Message:    "Room created successfully",
+		ProjectID:  id,
+		Imported:   len(project.Data),
+		NewColumns: []string{},
+		Ignored:    []string{},
+		Errors:     []importer.RowError{},
+	})
+}
+
+type SedLogImportResponse struct {
+	ImportResponse
+	Fosils   int                 `json:"fosils"`
+	Warnings []importer.RowError `json:"warnings"`
+}
+
+// HandleImportSedLog crea un proyecto nuevo a partir de un registro de SedLog
+// (CSV o XML). El formato se toma del campo "format" o de la extensión del archivo
+func (a *API) HandleImportSedLog(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	correo := claims["email"].(string)
+	name := claims["name"].(string)
+
+	var params dtos.Project
+	if err := c.Bind(&params); err != nil {
+		return a.handleError(c, http.StatusBadRequest, "Invalid request")
+	}
+
+	if err := a.dataValidator.Struct(params); err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, "missing file")
+	}
+
+	format := c.FormValue("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, "Invalid request")
+	}
+	defer src.Close()
+
+	records, err := importer.ReadSedLog(src, format)
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+
+	result, err := importer.SedLog(records)
+	if err != nil {
+		return a.handleError(c, http.StatusBadRequest, err.Error())
+	}
+
+	response := SedLogImportResponse{
+		ImportResponse: ImportResponse{
+			Imported:   len(result.Rows),
+			NewColumns: []string{},
+			Ignored:    []string{},
+			Errors:     result.Errors,
+		},
+		Fosils:   len(result.Fosil),
+		Warnings: result.Warnings,
+	}
+
+	if len(result.Rows) == 0 {
+		response.Message = "No rows imported"
+		return c.JSON(http.StatusBadRequest, response)
+	}
+
+	project := models.Project{
+		ProjectInfo: newProjectInfo(params, name, correo),
+		Data:        result.Rows,
+		Config:      models.DefaultConfig(),
+		Fosil:       result.Fosil,
+		Muestras:    map[string]models.Muestra{},
+		Facies:      map[string][]models.FaciesSection{},
+	}
+
+	id, err := a.repo.CreateProject(ctx, project)
+	if err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Failed to create a room")
+	}
+
+	response.Message = "Room created successfully"
+	response.ProjectID = id
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ProyectoT/api/internal/api/dtos"
+)
+
+// Cuando un usuario avisa con editingUser qué capa está editando (rowIndex y
+// opcionalmente la columna), la conexión toma un bloqueo sobre ella: los demás
+// no pueden modificar ahí (editar, borrar o mover la capa, borrar u ocultar la
+// columna, ni deshacer o rehacer algo de eso) hasta que lo suelte con
+// deleteEditingUser, se desconecte o pase leaseTimeout sin actividad. Si el
+// mensaje no trae rowIndex solo se avisa a los demás, como antes.
+
+var leaseTimeout = 30 * time.Second
+
+var errLeased = errors.New("the layer is being edited")
+
+// lockedColumn es la columna que bloquean editPolygon y editCircle
+const lockedColumn = "Litologia"
+
+type lease struct {
+	RowID   string
+	Column  string // "" bloquea toda la capa
+	Expires time.Time
+	timer   *time.Timer
+}
+
+// overlaps indica si el bloqueo cubre esa capa y columna. rowID "" es
+// cualquier capa
+func (l *lease) overlaps(rowID string, column string) bool {
+	return (rowID == "" || l.RowID == rowID) && (l.Column == "" || column == "" || l.Column == column)
+}
+
+// leaseTarget es lo que modifica un comando: una capa, una columna de todas
+// las capas (RowID "") o una columna de una capa
+type leaseTarget struct {
+	RowID  string
+	Column string
+}
+
+// leaseTargets devuelve lo que modifica el comando al aplicarlo, o al
+// deshacerlo si undo es true. Agregar capas o columnas no toca lo bloqueado,
+// pero deshacerlo sí
+func leaseTargets(c command, undo bool) []leaseTarget {
+	switch c := c.(type) {
+	case *editTextCommand:
+		return []leaseTarget{{c.RowID, c.Key}}
+	case *editPolygonCommand:
+		return []leaseTarget{{c.RowID, lockedColumn}}
+	case *editCircleCommand:
+		return []leaseTarget{{c.RowID, lockedColumn}}
+	case *addCircleCommand:
+		return []leaseTarget{{c.RowID, lockedColumn}}
+	case *deleteCircleCommand:
+		return []leaseTarget{{c.RowID, lockedColumn}}
+	case *moveRowCommand:
+		return []leaseTarget{{c.ID, ""}}
+	case *toggleColumnCommand:
+		return []leaseTarget{{"", c.Column}}
+	case *addRowCommand:
+		if undo {
+			return []leaseTarget{{c.Row.ID, ""}}
+		}
+	case *deleteRowCommand:
+		if !undo {
+			return []leaseTarget{{c.ID, ""}}
+		}
+	case *importRowsCommand:
+		if undo {
+			targets := make([]leaseTarget, 0, len(c.Rows)+len(c.Added))
+			for _, row := range c.Rows {
+				targets = append(targets, leaseTarget{row.ID, ""})
+			}
+			for _, column := range c.Added {
+				targets = append(targets, leaseTarget{"", column})
+			}
+			return targets
+		}
+	case *addColumnCommand:
+		if undo {
+			return []leaseTarget{{"", c.Column.Name}}
+		}
+	case *deleteColumnCommand:
+		if !undo {
+			return []leaseTarget{{"", c.Column.Name}}
+		}
+	case *batchCommand:
+		var targets []leaseTarget
+		for _, action := range c.Actions {
+			targets = append(targets, leaseTargets(action.Command, undo)...)
+		}
+		return targets
+	}
+	return nil
+}
+
+// leaseHolder devuelve la conexión que tiene bloqueada esa capa y columna,
+// sin contar a conn. rowID "" es cualquier capa
+func (r *RoomData) leaseHolder(conn string, rowID string, column string) *UserConnection {
+	now := time.Now()
+
+	for id, u := range r.Active {
+		if id == conn || u.Lease == nil || now.After(u.Lease.Expires) {
+			continue
+		}
+		if u.Lease.overlaps(rowID, column) {
+			return u
+		}
+	}
+	return nil
+}
+
+// checkLease rechaza el comando si otra conexión tiene bloqueado lo que
+// modifica al aplicarlo (o al deshacerlo, con undo). Las operaciones sin
+// conexión (registro, importaciones) no se revisan
+func (r *RoomData) checkLease(conn string, c command, undo bool) error {
+	if conn == "" {
+		return nil
+	}
+
+	for _, target := range leaseTargets(c, undo) {
+		if holder := r.leaseHolder(conn, target.RowID, target.Column); holder != nil {
+			return fmt.Errorf("%w by %s", errLeased, holder.Email)
+		}
+	}
+
+	return nil
+}
+
+// acquireLease le da a la conexión el bloqueo sobre la capa del mensaje
+// editingUser. Reemplaza el que tuviera antes
+func (r *RoomData) acquireLease(conn string, editing dtos.UserEditingState) error {
+	u := r.Active[conn]
+	if u == nil {
+		return nil
+	}
+	if editing.RowIndex == nil {
+		r.releaseLease(conn)
+		return nil
+	}
+
+	rowID, err := r.rowID(*editing.RowIndex)
+	if err != nil {
+		return err
+	}
+
+	if holder := r.leaseHolder(conn, rowID, editing.Column); holder != nil {
+		return fmt.Errorf("%w by %s", errLeased, holder.Email)
+	}
+
+	r.releaseLease(conn)
+
+	l := &lease{RowID: rowID, Column: editing.Column}
+	u.Lease = l
+	r.touchLease(conn)
+
+	l.timer = time.AfterFunc(leaseTimeout, func() {
+		r.do(func() { r.expireLease(conn, l) })
+	})
+	return nil
+}
+
+// touchLease extiende el bloqueo de la conexión, si tiene uno
+func (r *RoomData) touchLease(conn string) {
+	u := r.Active[conn]
+	if u == nil || u.Lease == nil {
+		return
+	}
+
+	u.Lease.Expires = time.Now().Add(leaseTimeout)
+	if u.Lease.timer != nil {
+		u.Lease.timer.Reset(leaseTimeout)
+	}
+}
+
+// releaseLease suelta el bloqueo de la conexión
+func (r *RoomData) releaseLease(conn string) {
+	u := r.Active[conn]
+	if u == nil || u.Lease == nil {
+		return
+	}
+
+	if u.Lease.timer != nil {
+		u.Lease.timer.Stop()
+	}
+	u.Lease = nil
+}
+
+// expireLease suelta el bloqueo si pasó leaseTimeout sin actividad y avisa a
+// la sala que el usuario dejó de editar
+func (r *RoomData) expireLease(conn string, l *lease) {
+	u := r.Active[conn]
+	if u == nil || u.Lease != l || time.Now().Before(l.Expires) {
+		return
+	}
+
+	r.releaseLease(conn)
+
+	section := u.Editing
+	u.Editing = ""
+
+	sendSocketMessage(map[string]interface{}{
+		"action":   "deleteEditingUser",
+		"value":    section,
+		"userName": u.Email,
+		"expired":  true,
+	}, r, "deleteEditingUser")
+}
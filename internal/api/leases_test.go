@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/api/dtos"
+	"github.com/ProyectoT/api/internal/models"
+)
+
+func TestLeaseBlocksOtherConnections(t *testing.T) {
+	room := testRoom()
+	room.Active["a"] = testUser("a@test.com")
+	room.Active["b"] = testUser("b@test.com")
+
+	edit := func(conn string, action string, data interface{}) error {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		email := room.Active[conn].Email
+		return applyOperation(room, &models.Operation{User: email, Conn: conn, Action: action, Data: raw})
+	}
+
+	// a edita la capa 1 antes de que b la bloquee
+	if err := edit("a", "editText", map[string]interface{}{"key": "Notas", "value": "x", "rowIndex": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := edit("a", "addColumn", map[string]interface{}{"name": "Edad"}); err != nil {
+		t.Fatal(err)
+	}
+
+	row := 1
+	if err := room.acquireLease("b", dtos.UserEditingState{RowIndex: &row}); err != nil {
+		t.Fatal(err)
+	}
+	defer room.releaseLease("b")
+
+	blocked := []struct {
+		action string
+		data   interface{}
+	}{
+		{"editText", map[string]interface{}{"key": "Espesor", "value": "1", "rowIndex": 1}},
+		{"delete", map[string]interface{}{"rowIndex": 1}},
+		{"drop", map[string]interface{}{"activeId": 1, "overId": 0}},
+		{"deleteColumn", map[string]interface{}{"name": "Notas"}},
+		{"toggleColumn", map[string]interface{}{"column": "Notas"}},
+		{"batch", map[string]interface{}{"actions": []interface{}{
+			map[string]interface{}{"action": "editText", "data": map[string]interface{}{"key": "Espesor", "value": "1", "rowIndex": 0}},
+			map[string]interface{}{"action": "delete", "data": map[string]interface{}{"rowIndex": 1}},
+		}}},
+		// Deshacer la columna la borraría de la capa bloqueada
+		{"undo", nil},
+	}
+	for _, tc := range blocked {
+		if err := edit("a", tc.action, tc.data); !errors.Is(err, errLeased) {
+			t.Errorf("%s = %v, want %v", tc.action, err, errLeased)
+		}
+	}
+	if n := len(room.undoStacks["a@test.com"]); n != 2 {
+		t.Errorf("undo stack has %d actions, want the blocked one kept", n)
+	}
+
+	// Lo que no toca la capa bloqueada sigue permitido
+	allowed := []struct {
+		action string
+		data   interface{}
+	}{
+		{"editText", map[string]interface{}{"key": "Espesor", "value": "1", "rowIndex": 0}},
+		{"añadir", map[string]interface{}{"rowIndex": -1}},
+		{"addColumn", map[string]interface{}{"name": "Formacion"}},
+	}
+	for _, tc := range allowed {
+		if err := edit("a", tc.action, tc.data); err != nil {
+			t.Errorf("%s = %v, want nil", tc.action, err)
+		}
+	}
+
+	// El dueño del bloqueo puede editar
+	if err := edit("b", "editText", map[string]interface{}{"key": "Espesor", "value": "2", "rowIndex": 1}); err != nil {
+		t.Errorf("editText by the lease holder = %v", err)
+	}
+}
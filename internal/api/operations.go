@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lithammer/shortuuid/v4"
+)
+
+type ReplayResponse struct {
+	Project  models.Project `json:"project"`
+	Revision string         `json:"revision,omitempty"`
+	FromSeq  int64          `json:"fromSeq"`
+	ToSeq    int64          `json:"toSeq"`
+	Applied  int            `json:"applied"`
+	Skipped  []int64        `json:"skipped"`
+}
+
+// operationRef devuelve el ID generado por la operación, creándolo la primera vez
+func operationRef(op *models.Operation) string {
+	if op.Ref == "" {
+		op.Ref = shortuuid.New()
+	}
+	return op.Ref
+}
+
+// record aplica una acción de edición y la agrega al registro de operaciones.
+// Se debe llamar desde la goroutine de la sala
+func (a *API) record(proyect *RoomData, user string, conn string, action string, data json.RawMessage) error {
+	op := models.Operation{
+		ProjectID: proyect.ID,
+		User:      user,
+		Action:    action,
+		Data:      data,
+		Conn:      conn,
+	}
+
+	if err := applyOperation(proyect, &op); err != nil {
+		return err
+	}
+
+	a.logOperation(proyect, op)
+	return nil
+}
+
+// logOperation numera la operación y la guarda en segundo plano para no
+// demorar la edición. Se debe llamar desde la goroutine de la sala
+func (a *API) logOperation(proyect *RoomData, op models.Operation) {
+	proyect.opSeq++
+	op.Seq = proyect.opSeq
+	op.CreatedAt = time.Now().UTC()
+
+	go func() {
+		if err := a.repo.SaveOperation(context.Background(), op); err != nil {
+			log.Println("Error guardando la operación: ", err)
+		}
+	}()
+}
+
+// replay aplica en orden las operaciones sobre la sala. Las que no se pueden
+// aplicar (por ejemplo un índice que ya no existe) se saltan y se devuelve su seq.
+// La configuración para compartir no forma parte del registro: la contraseña
+// de los enlaces no se guarda en las operaciones, que leen todos los miembros
+// (ver generateTokenLink), y HandleReplay la quita del resultado
+func (a *API) replay(ctx context.Context, proyect *RoomData, ops []models.Operation) []int64 {
+	skipped := []int64{}
+
+	for i := range ops {
+		op := ops[i]
+
+		if !a.replayOperation(ctx, proyect, &op) {
+			skipped = append(skipped, op.Seq)
+		}
+
+		if op.Seq > proyect.opSeq {
+			proyect.opSeq = op.Seq
+		}
+	}
+
+	return skipped
+}
+
+func (a *API) replayOperation(ctx context.Context, proyect *RoomData, op *models.Operation) (applied bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Error repitiendo la operación %d (%s): %v", op.Seq, op.Action, r)
+			applied = false
+		}
+	}()
+
+	if op.Action == "restore" {
+		var data struct {
+			Revision string `json:"revision"`
+		}
+		if err := json.Unmarshal(op.Data, &data); err != nil {
+			return false
+		}
+
+		revision, err := a.repo.GetRevision(ctx, proyect.ID.Hex(), data.Revision)
+		if err != nil {
+			return false
+		}
+
+		proyect.restore(revision.Project.Clone())
+		return true
+	}
+
+	// Pasos deshechos o rehechos, ver applyHistory
+	if op.Action == "undone" || op.Action == "redone" {
+		return replayHistory(proyect, op) == nil
+	}
+
+	return applyOperation(proyect, op) == nil
+}
+
+// isMember indica si el usuario es dueño, editor o lector del proyecto
+func isMember(info models.ProjectInfo, user string) bool {
+	members := info.Members
+	return members.Owner == user || contains(members.Editors, user) || contains(members.Readers, user)
+}
+
+// HandleGetOperations devuelve el registro de operaciones del proyecto (?after=&limit=)
+func (a *API) HandleGetOperations(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+
+	info, err := a.projectInfo(ctx, roomID)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	if !isMember(*info, user) {
+		return a.handleError(c, http.StatusForbidden, "Access denied")
+	}
+
+	after, err := strconv.ParseInt(c.QueryParam("after"), 10, 64)
+	if err != nil || after < 0 {
+		after = 0
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	operations, err := a.repo.GetOperations(ctx, roomID, after, 0, limit)
+	if err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Error getting operations")
+	}
+
+	return c.JSON(http.StatusOK, operations)
+}
+
+// HandleReplay reconstruye el proyecto a partir de una revisión (?revision=, o
+// el último guardado si no se indica) más las operaciones registradas después,
+// hasta ?until= si se indica. No modifica la sala
+func (a *API) HandleReplay(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+	revisionID := c.QueryParam("revision")
+
+	var until int64
+	if u := c.QueryParam("until"); u != "" {
+		until, err = strconv.ParseInt(u, 10, 64)
+		if err != nil || until < 0 {
+			return a.handleError(c, http.StatusBadRequest, "invalid until")
+		}
+	}
+
+	info, err := a.projectInfo(ctx, roomID)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	if !isMember(*info, user) {
+		return a.handleError(c, http.StatusForbidden, "Access denied")
+	}
+
+	var snapshot *models.Project
+
+	if revisionID != "" {
+		revision, err := a.repo.GetRevision(ctx, roomID, revisionID)
+		if err != nil {
+			return a.handleError(c, http.StatusNotFound, "Revision not found")
+		}
+		snapshot = revision.Project.Clone()
+	} else {
+		snapshot, err = a.repo.GetRoom(ctx, roomID)
+		if err != nil {
+			return a.handleError(c, http.StatusNotFound, "Room not found")
+		}
+	}
+
+	if until > 0 && until < snapshot.Seq {
+		return a.handleError(c, http.StatusBadRequest, "until is before the snapshot")
+	}
+
+	ops, err := a.repo.GetOperations(ctx, roomID, snapshot.Seq, until, 0)
+	if err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Error getting operations")
+	}
+
+	room := newRoomData(snapshot)
+	skipped := a.replay(ctx, room, ops)
+
+	return c.JSON(http.StatusOK, ReplayResponse{
+		Project:  withoutSecrets(room.toProject()),
+		Revision: revisionID,
+		FromSeq:  snapshot.Seq,
+		ToSeq:    room.opSeq,
+		Applied:  len(ops) - len(skipped),
+		Skipped:  skipped,
+	})
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ProyectoT/api/internal/api/dtos"
+)
+
+// Cada usuario manda con "presence" dónde tiene el cursor y qué intervalo de
+// profundidad está viendo, y con "follow" a quién sigue. Se reenvía al resto
+// de la sala fuera de la secuencia de stream.go (no se guarda para reenviar,
+// solo importa la última posición) y se incluye en DataProject.
+
+// presenceInterval es el tiempo mínimo entre dos envíos de la misma conexión.
+// Lo que llega antes se junta y se envía la última posición al cumplirse
+var presenceInterval = 50 * time.Millisecond
+
+var errUserNotFound = errors.New("user not connected")
+
+type presence struct {
+	Cursor    *dtos.Cursor
+	RowID     string // capa bajo el cursor, para ubicarla aunque se muevan capas
+	Viewport  *dtos.Viewport
+	Following string // ID de la conexión que sigue
+
+	sentAt  time.Time
+	pending bool
+}
+
+// setPresence guarda la posición del usuario y la reenvía a la sala
+func (r *RoomData) setPresence(conn string, data json.RawMessage) error {
+	u := r.Active[conn]
+	if u == nil {
+		return errUserNotFound
+	}
+
+	var p dtos.Presence
+	if err := json.Unmarshal(data, &p); err != nil {
+		log.Println("Error al deserializar: ", err)
+		return errInvalidData
+	}
+
+	if u.Presence == nil {
+		u.Presence = &presence{}
+	}
+
+	state := u.Presence
+	state.Cursor = p.Cursor
+	state.Viewport = p.Viewport
+	state.RowID = ""
+	if p.Cursor != nil && p.Cursor.Row != nil {
+		state.RowID, _ = r.rowID(*p.Cursor.Row)
+	}
+
+	if state.pending {
+		return nil
+	}
+
+	if wait := presenceInterval - time.Since(state.sentAt); wait > 0 {
+		state.pending = true
+		time.AfterFunc(wait, func() {
+			r.do(func() { r.flushPresence(conn, state) })
+		})
+		return nil
+	}
+
+	r.sendPresence(conn, u)
+	return nil
+}
+
+func (r *RoomData) flushPresence(conn string, state *presence) {
+	u := r.Active[conn]
+	if u == nil || u.Presence != state {
+		return
+	}
+
+	state.pending = false
+	r.sendPresence(conn, u)
+}
+
+func (r *RoomData) sendPresence(conn string, u *UserConnection) {
+	u.Presence.sentAt = time.Now()
+
+	msgData := r.presenceData(conn, u)
+	msgData["action"] = "presence"
+
+	r.sendOthers(conn, msgData)
+}
+
+// follow empieza a seguir a otro usuario, o deja de seguirlo si id es ""
+func (r *RoomData) follow(conn string, data json.RawMessage) error {
+	u := r.Active[conn]
+	if u == nil {
+		return errUserNotFound
+	}
+
+	var f dtos.Follow
+	if err := json.Unmarshal(data, &f); err != nil {
+		log.Println("Error al deserializar: ", err)
+		return errInvalidData
+	}
+
+	target := r.Active[f.ID]
+	if f.ID != "" && (target == nil || f.ID == conn) {
+		return errUserNotFound
+	}
+
+	if u.Presence == nil {
+		u.Presence = &presence{}
+	}
+	u.Presence.Following = f.ID
+
+	r.sendOthers(conn, map[string]interface{}{
+		"action": "follow",
+		"id":     conn,
+		"target": f.ID,
+	})
+
+	// El que empieza a seguir recibe enseguida dónde está el otro
+	if target != nil && target.Presence != nil {
+		msgData := r.presenceData(f.ID, target)
+		msgData["action"] = "presence"
+		u.Conn.SendJSON(msgData)
+	}
+
+	return nil
+}
+
+// unfollow deja de seguir a conn, que se desconectó
+func (r *RoomData) unfollow(conn string) {
+	for _, u := range r.Active {
+		if u.Presence != nil && u.Presence.Following == conn {
+			u.Presence.Following = ""
+		}
+	}
+}
+
+// presenceData arma la posición del usuario para enviarla
+func (r *RoomData) presenceData(conn string, u *UserConnection) map[string]interface{} {
+	msgData := map[string]interface{}{
+		"id":    conn,
+		"name":  u.Email,
+		"color": u.Color,
+	}
+
+	if u.Presence == nil {
+		return msgData
+	}
+
+	if c := u.Presence.Cursor; c != nil {
+		cursor := *c
+		cursor.Row = nil
+		if u.Presence.RowID != "" {
+			if i := r.rowIndex(u.Presence.RowID); i != -1 {
+				cursor.Row = &i
+			}
+		}
+		msgData["cursor"] = cursor
+	}
+	if u.Presence.Viewport != nil {
+		msgData["viewport"] = u.Presence.Viewport
+	}
+	if u.Presence.Following != "" {
+		msgData["following"] = u.Presence.Following
+	}
+
+	return msgData
+}
+
+// presenceSnapshot devuelve la posición de todos los usuarios para DataProject
+func (r *RoomData) presenceSnapshot() map[string]interface{} {
+	snapshot := make(map[string]interface{})
+	for id, u := range r.Active {
+		if u.Presence != nil {
+			snapshot[id] = r.presenceData(id, u)
+		}
+	}
+	return snapshot
+}
+
+// sendOthers envía el mensaje a todos menos a conn, sin numerarlo
+func (r *RoomData) sendOthers(conn string, msgData map[string]interface{}) {
+	jsonMsg, err := json.Marshal(msgData)
+	if err != nil {
+		log.Println("Error serializing message:", err)
+		return
+	}
+
+	for id, client := range r.Active {
+		if id != conn {
+			client.Conn.Send(jsonMsg)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// presenceMessages decodifica los mensajes que quedaron en la cola del usuario
+func presenceMessages(t *testing.T, u *UserConnection) []map[string]interface{} {
+	t.Helper()
+
+	var messages []map[string]interface{}
+	for _, raw := range received(u) {
+		var msg map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// checkUnsequenced revisa que la presencia no haya pasado por stream.go
+func checkUnsequenced(t *testing.T, room *RoomData, messages []map[string]interface{}) {
+	t.Helper()
+
+	for _, msg := range messages {
+		if _, ok := msg["seq"]; ok {
+			t.Errorf("%v message has a seq", msg["action"])
+		}
+	}
+
+	room.do(func() {
+		if room.streamSeq != 0 {
+			t.Errorf("streamSeq = %d, want presence outside the stream", room.streamSeq)
+		}
+		for _, msg := range room.sent {
+			if msg != nil {
+				t.Errorf("sent buffer has %s", msg)
+			}
+		}
+	})
+}
+
+func setDepth(t *testing.T, room *RoomData, conn string, depth float64) {
+	t.Helper()
+
+	data := json.RawMessage(fmt.Sprintf(`{"cursor": {"x": 1, "depth": %g}}`, depth))
+	if err := room.setPresence(conn, data); err != nil {
+		t.Error(err)
+	}
+}
+
+func messageDepth(msg map[string]interface{}) interface{} {
+	cursor, _ := msg["cursor"].(map[string]interface{})
+	return cursor["depth"]
+}
+
+func TestPresenceThrottle(t *testing.T) {
+	room := startedRoom(t)
+	a, b := testUser("a@test.com"), testUser("b@test.com")
+	room.do(func() {
+		room.Active["a"] = a
+		room.Active["b"] = b
+	})
+
+	// El primer envío sale enseguida y solo a los demás
+	room.do(func() { setDepth(t, room, "a", 1) })
+	first := presenceMessages(t, b)
+	if len(first) != 1 || first[0]["action"] != "presence" || first[0]["id"] != "a" || messageDepth(first[0]) != 1.0 {
+		t.Fatalf("b received %v, want a's first position", first)
+	}
+	if got := received(a); len(got) != 0 {
+		t.Errorf("a received its own presence: %v", got)
+	}
+
+	// Lo que llega antes de presenceInterval se junta en un solo envío
+	room.do(func() {
+		setDepth(t, room, "a", 2)
+		setDepth(t, room, "a", 3)
+	})
+	if got := received(b); len(got) != 0 {
+		t.Fatalf("b received %v before the interval", got)
+	}
+
+	time.Sleep(2 * presenceInterval)
+	var last []map[string]interface{}
+	room.do(func() { last = presenceMessages(t, b) })
+	if len(last) != 1 || messageDepth(last[0]) != 3.0 {
+		t.Fatalf("b received %v, want only the last position", last)
+	}
+
+	checkUnsequenced(t, room, append(first, last...))
+}
+
+func TestFollow(t *testing.T) {
+	room := startedRoom(t)
+	a, b, c := testUser("a@test.com"), testUser("b@test.com"), testUser("c@test.com")
+	room.do(func() {
+		room.Active["a"] = a
+		room.Active["b"] = b
+		room.Active["c"] = c
+		setDepth(t, room, "b", 7)
+	})
+	received(a)
+	received(c)
+
+	follow := func(id string) error {
+		var err error
+		room.do(func() { err = room.follow("a", json.RawMessage(`{"id": "`+id+`"}`)) })
+		return err
+	}
+
+	if err := follow("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Solo el que sigue recibe la posición del seguido
+	toA := presenceMessages(t, a)
+	if len(toA) != 1 || toA[0]["action"] != "presence" || toA[0]["id"] != "b" || messageDepth(toA[0]) != 7.0 {
+		t.Errorf("a received %v, want b's position", toA)
+	}
+	var others []map[string]interface{}
+	for _, u := range []*UserConnection{b, c} {
+		msgs := presenceMessages(t, u)
+		if len(msgs) != 1 || msgs[0]["action"] != "follow" || msgs[0]["id"] != "a" || msgs[0]["target"] != "b" {
+			t.Errorf("%s received %v, want only the follow notice", u.Email, msgs)
+		}
+		others = append(others, msgs...)
+	}
+
+	// Al dejar de seguir nadie recibe posiciones
+	if err := follow(""); err != nil {
+		t.Fatal(err)
+	}
+	if got := received(a); len(got) != 0 {
+		t.Errorf("a received %v after unfollowing", got)
+	}
+	for _, u := range []*UserConnection{b, c} {
+		msgs := presenceMessages(t, u)
+		if len(msgs) != 1 || msgs[0]["action"] != "follow" || msgs[0]["target"] != "" {
+			t.Errorf("%s received %v, want the unfollow notice", u.Email, msgs)
+		}
+		others = append(others, msgs...)
+	}
+
+	// No se puede seguir a uno mismo ni a quien no está
+	for _, id := range []string{"a", "x"} {
+		if err := follow(id); !errors.Is(err, errUserNotFound) {
+			t.Errorf("follow(%q) = %v, want %v", id, err, errUserNotFound)
+		}
+	}
+
+	// Si el seguido se desconecta se deja de seguir
+	if err := follow("b"); err != nil {
+		t.Fatal(err)
+	}
+	room.do(func() {
+		room.unfollow("b")
+		if got := a.Presence.Following; got != "" {
+			t.Errorf("a follows %q after b left", got)
+		}
+	})
+
+	checkUnsequenced(t, room, append(toA, others...))
+}
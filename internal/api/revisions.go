@@ -0,0 +1,266 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+type RevisionsResponse struct {
+	Revisions   []models.RevisionInfo `json:"revisions"`
+	CurrentPage int                   `json:"currentPage"`
+	TotalPages  int                   `json:"totalPages"`
+}
+
+// saveWithRevision guarda el proyecto y agrega una copia al historial de revisiones
+func (a *API) saveWithRevision(project models.Project, author string, reason string) error {
+	ctx := context.Background()
+
+	if err := a.repo.SaveRoom(ctx, project); err != nil {
+		return err
+	}
+
+	// El historial de deshacer es de la sala, no de la revisión
+	project.History = nil
+
+	_, err := a.repo.SaveRevision(ctx, models.Revision{
+		ProjectID: project.ID,
+		Author:    author,
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+		Project:   project,
+	})
+	return err
+}
+
+// saveSnapshot pide a la sala una copia de su estado y la guarda fuera de la
+// goroutine de la sala
+func (a *API) saveSnapshot(proyect *RoomData, author string, reason string) error {
+	var snapshot *models.Project
+	if err := proyect.do(func() { snapshot = proyect.snapshot() }); err != nil {
+		return err
+	}
+
+	return a.saveWithRevision(*snapshot, author, reason)
+}
+
+// projectInfo devuelve la info del proyecto desde la sala en memoria o desde la base de datos
+func (a *API) projectInfo(ctx context.Context, roomID string) (*models.ProjectInfo, error) {
+	if roomInterface, ok := rooms.Load(roomID); ok {
+		room := roomInterface.(*RoomData)
+
+		var info models.ProjectInfo
+		if err := room.do(func() { info = room.ProjectInfo }); err == nil {
+			return &info, nil
+		}
+	}
+
+	project, err := a.repo.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &project.ProjectInfo, nil
+}
+
+// HandleGetRevisions lista las revisiones del proyecto, de la más reciente a la más antigua
+func (a *API) HandleGetRevisions(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+
+	info, err := a.projectInfo(ctx, roomID)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	// Igual que el registro de operaciones, solo para miembros
+	if !isMember(*info, user) {
+		return a.handleError(c, http.StatusForbidden, "Access denied")
+	}
+
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+
+	revisions, currentPage, totalPages, err := a.repo.GetRevisions(ctx, roomID, page, limit)
+	if err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Error getting revisions")
+	}
+
+	return c.JSON(http.StatusOK, RevisionsResponse{
+		Revisions:   revisions,
+		CurrentPage: currentPage,
+		TotalPages:  totalPages,
+	})
+}
+
+// HandleGetRevision devuelve una revisión con la copia completa del proyecto
+func (a *API) HandleGetRevision(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+
+	info, err := a.projectInfo(ctx, roomID)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	// Igual que el registro de operaciones, solo para miembros
+	if !isMember(*info, user) {
+		return a.handleError(c, http.StatusForbidden, "Access denied")
+	}
+
+	revision, err := a.repo.GetRevision(ctx, roomID, c.Param("revision"))
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Revision not found")
+	}
+	revision.Project = withoutSecrets(revision.Project)
+
+	return c.JSON(http.StatusOK, revision)
+}
+
+// withoutSecrets quita de una copia del proyecto lo que no se muestra a los
+// miembros: la contraseña de los enlaces de invitación, el historial y los
+// miembros que tenía al guardarse, que pueden ya no serlo
+func withoutSecrets(project models.Project) models.Project {
+	project.Shared = models.Shared{}
+	project.History = nil
+	project.ProjectInfo.Members = models.Members{}
+	return project
+}
+
+// HandleRestoreRevision reemplaza el contenido de la sala por el de una
+// revisión. El estado actual queda guardado como revisión antes de
+// reemplazarlo y los usuarios conectados reciben el proyecto completo
+func (a *API) HandleRestoreRevision(c echo.Context) error {
+	ctx, claims, err := a.getContextAndClaims(c)
+	if err != nil {
+		return a.handleError(c, http.StatusUnauthorized, err.Error())
+	}
+
+	user := claims["email"].(string)
+	roomID := c.Param("id")
+	revisionID := c.Param("revision")
+
+	// Se revisan los permisos antes de buscar la revisión, así quien no es
+	// editor no sabe si existe
+	info, err := a.projectInfo(ctx, roomID)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	if !canEdit(*info, user) {
+		return a.handleError(c, http.StatusForbidden, "Don't have permission to edit this document")
+	}
+
+	revision, err := a.repo.GetRevision(ctx, roomID, revisionID)
+	if err != nil {
+		return a.handleError(c, http.StatusNotFound, "Revision not found")
+	}
+
+	proyect, err := a.instanceRoom(ctx, roomID)
+	if err != nil {
+		return a.handleRoomError(c, err)
+	}
+	// Si nadie la tiene abierta se guarda y se cierra al terminar
+	defer a.closeIfIdle(roomID, proyect, user)
+
+	// Primero se guarda el estado actual como revisión, fuera de la sala
+	var current *models.Project
+	var allowed bool
+	if err := proyect.do(func() {
+		if allowed = canEdit(proyect.ProjectInfo, user); allowed {
+			current = proyect.snapshot()
+		}
+	}); err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	if !allowed {
+		return a.handleError(c, http.StatusForbidden, "Don't have permission to edit this document")
+	}
+
+	if err := a.saveWithRevision(*current, user, models.RevisionRestore); err != nil {
+		return a.handleError(c, http.StatusInternalServerError, "Failed to save current state")
+	}
+
+	// Si alguien editó mientras se guardaba, esos cambios no quedaron en la revisión
+	var restored *models.Project
+	if err := proyect.do(func() {
+		if proyect.opSeq != current.Seq {
+			return
+		}
+
+		proyect.restore(revision.Project.Clone())
+
+		data, _ := json.Marshal(map[string]string{"revision": revisionID})
+		a.logOperation(proyect, models.Operation{ProjectID: proyect.ID, User: user, Action: "restore", Data: data})
+
+		msgData := proyect.DataProject()
+		msgData["revision"] = revisionID
+		sendSocketMessage(msgData, proyect, "data")
+
+		restored = proyect.snapshot()
+	}); err != nil {
+		return a.handleError(c, http.StatusNotFound, "Room not found")
+	}
+
+	if restored == nil {
+		return a.handleError(c, http.StatusConflict, "The project changed while restoring, try again")
+	}
+
+	if err := a.repo.SaveRoom(ctx, *restored); err != nil {
+		log.Println("Error guardando la revisión restaurada: ", err)
+	}
+
+	return c.JSON(http.StatusOK, responseMessage{Message: "Revision restored successfully"})
+}
+
+// restore reemplaza el contenido de la sala por el de una copia del proyecto.
+// La info y los miembros se mantienen, y el historial de deshacer se descarta
+// porque sus acciones apuntan al estado anterior
+func (r *RoomData) restore(project *models.Project) {
+	r.Data = project.Data
+	r.Config = project.Config
+	r.Fosil = project.Fosil
+	r.Facies = project.Facies
+	r.Muestras = project.Muestras
+	r.Logs = project.Logs
+
+	if r.Data == nil {
+		r.Data = []models.DataInfo{}
+	}
+	if r.Fosil == nil {
+		r.Fosil = map[string]models.Fosil{}
+	}
+	if r.Facies == nil {
+		r.Facies = map[string][]models.FaciesSection{}
+	}
+	if r.Muestras == nil {
+		r.Muestras = map[string]models.Muestra{}
+	}
+
+	r.ensureRowIDs()
+	r.clearHistory()
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// membersProject devuelve el proyecto de prueba con dueño, editor y lector
+func membersProject() models.Project {
+	project := *testRoom().snapshot()
+	project.ProjectInfo.Owner = "a@test.com"
+	project.ProjectInfo.Members = models.Members{
+		Owner:   "a@test.com",
+		Editors: []string{"b@test.com"},
+		Readers: []string{"r@test.com"},
+	}
+	project.Shared = models.Shared{Pass: "secreto"}
+	return project
+}
+
+// revisionProject devuelve el contenido de una revisión con una sola capa
+func revisionProject() models.Project {
+	project := membersProject()
+	project.Data = project.Data[:1]
+	project.Data[0].Columns["Notas"] = "revisión"
+	return project
+}
+
+func TestRestoreRevision(t *testing.T) {
+	repo := newFakeRepo()
+	a := newTestAPI(t, repo, "a")
+	roomID := repo.addProject(membersProject())
+	before := repo.project(roomID)
+	revision := revisionProject()
+	revisionID := repo.addRevision(roomID, revision)
+
+	rec := callHandler(t, a.HandleRestoreRevision, http.MethodPost, "b@test.com", "id", roomID, "revision", revisionID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", rec.Code, rec.Body)
+	}
+
+	saved := repo.project(roomID)
+	if !reflect.DeepEqual(saved.Data, revision.Data) {
+		t.Errorf("saved layers = %+v, want the revision", saved.Data)
+	}
+	if saved.Seq != before.Seq+1 {
+		t.Errorf("seq = %d, want the restore logged as %d", saved.Seq, before.Seq+1)
+	}
+
+	// El estado anterior quedó guardado como revisión
+	found := false
+	for _, revision := range repo.stored {
+		if revision.Reason == models.RevisionRestore && reflect.DeepEqual(revision.Project.Data, before.Data) {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("the previous state was not saved as a revision")
+	}
+}
+
+func TestRestoreRevisionConflict(t *testing.T) {
+	repo := newFakeRepo()
+	a := newTestAPI(t, repo, "a")
+	roomID := repo.addProject(membersProject())
+	revisionID := repo.addRevision(roomID, revisionProject())
+
+	// Otro usuario edita mientras se guarda el estado previo
+	edited := false
+	repo.onSaveRevision = func() {
+		if edited {
+			return
+		}
+		edited = true
+
+		value, _ := rooms.Load(roomID)
+		room := value.(*RoomData)
+		data := json.RawMessage(`{"key": "Notas", "value": "editada", "rowIndex": 2}`)
+		room.do(func() {
+			if err := a.record(room, "a@test.com", "", "editText", data); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	rec := callHandler(t, a.HandleRestoreRevision, http.MethodPost, "b@test.com", "id", roomID, "revision", revisionID)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d %s, want %d", rec.Code, rec.Body, http.StatusConflict)
+	}
+
+	saved := repo.project(roomID)
+	if len(saved.Data) != 3 || saved.Data[2].Columns["Notas"] != "editada" {
+		t.Errorf("saved layers = %+v, want the edit and no restore", saved.Data)
+	}
+}
+
+func TestRestoreRevisionPermissions(t *testing.T) {
+	tests := []struct {
+		user     string
+		revision string // "" usa la revisión guardada
+		status   int
+	}{
+		{"x@test.com", "", http.StatusForbidden},
+		{"x@test.com", "000000000000000000000000", http.StatusForbidden},
+		{"r@test.com", "", http.StatusForbidden},
+		{"b@test.com", "000000000000000000000000", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		repo := newFakeRepo()
+		a := newTestAPI(t, repo, "a")
+		roomID := repo.addProject(membersProject())
+		before := repo.project(roomID)
+
+		revisionID := tt.revision
+		if revisionID == "" {
+			revisionID = repo.addRevision(roomID, revisionProject())
+		}
+
+		rec := callHandler(t, a.HandleRestoreRevision, http.MethodPost, tt.user, "id", roomID, "revision", revisionID)
+		if rec.Code != tt.status {
+			t.Errorf("%s restoring %s = %d, want %d", tt.user, revisionID, rec.Code, tt.status)
+		}
+
+		// Quien no puede editar no llega a leer la revisión
+		if tt.status == http.StatusForbidden && repo.revisionReads != 0 {
+			t.Errorf("%s: the revision was read %d times", tt.user, repo.revisionReads)
+		}
+		if !reflect.DeepEqual(repo.project(roomID).Data, before.Data) {
+			t.Errorf("%s: the project changed", tt.user)
+		}
+	}
+}
+
+func TestGetRevisionWithoutSecrets(t *testing.T) {
+	repo := newFakeRepo()
+	a := newTestAPI(t, repo, "a")
+	roomID := repo.addProject(membersProject())
+
+	stored := revisionProject()
+	stored.ProjectInfo.Members.Editors = append(stored.ProjectInfo.Members.Editors, "antiguo@test.com")
+	stored.History = []models.UserHistory{{User: "a@test.com"}}
+	revisionID := repo.addRevision(roomID, stored)
+
+	rec := callHandler(t, a.HandleGetRevision, http.MethodGet, "r@test.com", "id", roomID, "revision", revisionID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", rec.Code, rec.Body)
+	}
+
+	body := rec.Body.String()
+	for _, secret := range []string{"secreto", "antiguo@test.com", "b@test.com"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("the response contains %q: %s", secret, body)
+		}
+	}
+
+	clean := withoutSecrets(stored)
+	if clean.Shared.Pass != "" || clean.History != nil || !reflect.DeepEqual(clean.ProjectInfo.Members, models.Members{}) {
+		t.Errorf("withoutSecrets() = %+v", clean)
+	}
+	if !reflect.DeepEqual(clean.Data, stored.Data) || clean.ProjectInfo.Name != stored.ProjectInfo.Name {
+		t.Error("withoutSecrets() changed the content")
+	}
+	// La copia guardada no se modifica
+	if stored.Shared.Pass != "secreto" {
+		t.Error("withoutSecrets() changed its argument")
+	}
+}
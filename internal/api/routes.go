@@ -11,15 +11,44 @@ func (a *API) RegisterRoutes(e *echo.Echo) {
 	users.POST("/register", a.RegisterUser)        // users/register
 	users.POST("/login", a.LoginUser)              // users/login
 	users.GET("/projects", a.projects)             // users
+	users.GET("/projects.geojson", a.HandleProjectsFeed)
+	users.GET("/projects.kml", a.HandleProjectsFeed)
 	users.DELETE("/projects/:id", a.DeleteProject) // users/projects/:id
 	users.GET("/me", a.HandleGetUser)              // users/me
 	users.POST("/editprofile", a.HandleEditProfile)
 	users.POST("/chagePassword",a.HandleEditPassword)
 
 	e.GET("/search/public", a.HandleGetPublicProject) // search/public
+	e.GET("/search/public.geojson", a.HandlePublicFeed)
+	e.GET("/search/public.kml", a.HandlePublicFeed)
 	e.GET("/ws/:room", a.HandleWebSocket)             //ws/sala
 	e.POST("/validate-invitation", a.ValidateInvitation)
 	e.POST("/rooms/create", a.HandleCreateProyect) //rooms/sala/usuario
+	e.GET("/rooms/:id/export.svg", a.HandleExportSVG)
+	e.GET("/rooms/:id/export.pdf", a.HandleExportPDF)
+	e.GET("/rooms/:id/export", a.HandleExportTable)
+	e.GET("/rooms/:id/export.las", a.HandleExportLAS)
+	e.GET("/rooms/:id/export.sedlog", a.HandleExportSedLog)
+	e.GET("/rooms/:id/archive", a.HandleExportArchive)
+	e.POST("/rooms/import", a.HandleImportProject)
+	e.POST("/rooms/archive", a.HandleImportArchive)
+	e.POST("/rooms/sedlog", a.HandleImportSedLog)
+	e.POST("/rooms/:id/import", a.HandleImportToRoom)
+	e.POST("/rooms/:id/las", a.HandleImportLAS)
+	e.GET("/rooms/:id/revisions", a.HandleGetRevisions)
+	e.GET("/rooms/:id/revisions/:revision", a.HandleGetRevision)
+	e.POST("/rooms/:id/revisions/:revision/restore", a.HandleRestoreRevision)
+	e.GET("/rooms/:id/operations", a.HandleGetOperations)
+	e.GET("/rooms/:id/replay", a.HandleReplay)
+	e.GET("/rooms/:id/diff", a.HandleDiff)
+	e.POST("/rooms/:id/fork", a.HandleForkProject)
+	e.GET("/rooms/:id/messages", a.HandleGetMessages)
+	e.POST("/rooms/:id/messages/read", a.HandleReadMessages)
+	e.GET("/rooms/:id/comments", a.HandleGetThreads)
+	e.POST("/rooms/:id/comments", a.HandleCreateThread)
+	e.POST("/rooms/:id/comments/:thread/replies", a.HandleReplyThread)
+	e.POST("/rooms/:id/comments/:thread/resolve", a.HandleResolveThread)
+	e.POST("/rooms/:id/comments/:thread/reopen", a.HandleReopenThread)
 	e.POST("/comment", a.AddComment)
 
 	e.GET("/activeProject", a.HandleGetActiveProject)
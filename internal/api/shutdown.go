@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+// Al apagar el servidor se dejan de aceptar conexiones, se guarda cada sala
+// abierta y se avisa a los clientes que vuelvan a conectarse. El lease de la
+// sala se suelta recién después de guardarla, así la instancia que la abra
+// después carga el último estado.
+
+var (
+	shutdownConcurrency = 8               // salas que se guardan a la vez
+	reconnectDelay      = 2 * time.Second // espera sugerida a los clientes
+)
+
+// Motivos del mensaje close
+const (
+	closeDeleted  = "deleted"  // el proyecto se eliminó
+	closeMoved    = "moved"    // la sala pasó a otra instancia
+	closeShutdown = "shutdown" // el servidor se está apagando
+	closeError    = "error"    // la sala falló
+)
+
+func closeMessage(reason string) CloseMessage {
+	message := CloseMessage{Action: "close", Reason: reason}
+
+	switch reason {
+	case closeDeleted:
+		message.Message = "project closed"
+	case closeMoved:
+		message.Message = "project moved to another server"
+		message.Reconnect = true
+	case closeShutdown:
+		message.Message = "server is restarting"
+		message.Reconnect = true
+		message.RetryAfter = int(reconnectDelay / time.Millisecond)
+	case closeError:
+		message.Message = "project closed after an error"
+		message.Reconnect = true
+	}
+
+	return message
+}
+
+// Shutdown apaga el servidor HTTP y guarda todas las salas antes de que
+// termine ctx
+func (a *API) Shutdown(ctx context.Context, e *echo.Echo) error {
+	a.closing.Store(true)
+
+	// Termina los pedidos en curso; los websockets no cuentan, se cierran abajo
+	if err := e.Shutdown(ctx); err != nil {
+		log.Println("Error apagando el servidor: ", err)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, shutdownConcurrency)
+
+	rooms.Range(func(key, value interface{}) bool {
+		roomID := key.(string)
+		room := value.(*RoomData)
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return false
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			a.flushRoom(ctx, roomID, room)
+		}()
+		return true
+	})
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("Salas guardadas")
+		return nil
+	case <-ctx.Done():
+		rooms.Range(func(key, value interface{}) bool {
+			log.Println("No se guardó la sala ", key)
+			return true
+		})
+		return ctx.Err()
+	}
+}
+
+// flushRoom desconecta a los usuarios, detiene la sala y guarda su último
+// estado. Como la sala ya no acepta comandos, nada queda fuera del guardado
+func (a *API) flushRoom(ctx context.Context, roomID string, room *RoomData) {
+	var snapshot *models.Project
+
+	err := room.do(func() {
+		if err := room.disconnectUsers(closeMessage(closeShutdown)); err != nil {
+			log.Println("Disconnect: ", err)
+		}
+		snapshot = room.stopRoom()
+	})
+	if err != nil {
+		return // ya la cerró otro
+	}
+
+	if err := a.repo.SaveRoom(ctx, *snapshot); err != nil {
+		log.Println("Error al guardar la sala ", roomID, ": ", err)
+		return
+	}
+
+	a.dropRoom(roomID, room)
+
+	log.Println("Project saved: ", roomID)
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+func TestFlushRoomStopsBeforeSaving(t *testing.T) {
+	repo := newFakeRepo()
+	a := newTestAPI(t, repo, "a")
+	roomID := repo.addProject(models.Project{})
+
+	room, err := a.instanceRoom(context.Background(), roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := testUser("a@test.com")
+	room.do(func() {
+		room.Active["c1"] = user
+		room.ProjectInfo.Name = "editado"
+	})
+
+	a.flushRoom(context.Background(), roomID, room)
+
+	// Lo que llegue después del guardado se rechaza en vez de perderse
+	if err := room.do(func() { room.ProjectInfo.Name = "tarde" }); !errors.Is(err, errRoomClosed) {
+		t.Errorf("do() after flush = %v, want %v", err, errRoomClosed)
+	}
+	if name := repo.project(roomID).ProjectInfo.Name; name != "editado" {
+		t.Errorf("saved name = %q, want the last change", name)
+	}
+	if _, ok := rooms.Load(roomID); ok {
+		t.Error("the flushed room is still in memory")
+	}
+	if _, ok := repo.lease(roomID); ok {
+		t.Error("the lease was not released")
+	}
+
+	msgs := received(user)
+	if len(msgs) == 0 || !strings.Contains(msgs[len(msgs)-1], closeShutdown) {
+		t.Errorf("messages = %v, want a shutdown close", msgs)
+	}
+}
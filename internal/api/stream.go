@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/ProyectoT/api/internal/api/dtos"
+)
+
+// Cada mensaje que se envía a la sala lleva un número de secuencia ("seq") que
+// aumenta de a uno. El cliente guarda el último que aplicó; si se reconecta o
+// ve un salto pide "resync" y se le reenvían los mensajes que le faltan, o el
+// proyecto completo si ya no están guardados. "stream" identifica la instancia
+// de la sala: si la sala se cerró y se volvió a abrir la secuencia empieza de
+// nuevo y el cliente recibe el proyecto completo.
+
+// streamBufferSize es la cantidad de mensajes que se guardan para reenviar
+var streamBufferSize = 500
+
+// sequence numera el mensaje y lo guarda para poder reenviarlo
+func (r *RoomData) sequence(msgData map[string]interface{}) ([]byte, error) {
+	msgData["seq"] = r.streamSeq + 1
+
+	jsonMsg, err := json.Marshal(msgData)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.sent == nil {
+		r.sent = make([][]byte, streamBufferSize)
+	}
+
+	r.streamSeq++
+	r.sent[r.streamSeq%int64(len(r.sent))] = jsonMsg
+
+	return jsonMsg, nil
+}
+
+// missedMessages devuelve los mensajes enviados después de seq, o false si
+// alguno ya no está guardado
+func (r *RoomData) missedMessages(seq int64) ([][]byte, bool) {
+	if seq < 0 || seq > r.streamSeq || r.streamSeq-seq > int64(len(r.sent)) {
+		return nil, false
+	}
+
+	missed := make([][]byte, 0, r.streamSeq-seq)
+	for s := seq + 1; s <= r.streamSeq; s++ {
+		missed = append(missed, r.sent[s%int64(len(r.sent))])
+	}
+
+	return missed, true
+}
+
+// resync le manda al cliente lo que le falta para ponerse al día
+func (r *RoomData) resync(conn *Client, req dtos.Resync) error {
+	if req.Stream != "" && req.Stream == r.streamID {
+		if missed, ok := r.missedMessages(req.Seq); ok {
+			for _, msg := range missed {
+				if !conn.Send(msg) {
+					return errClientClosed
+				}
+			}
+			return nil
+		}
+	}
+
+	snapshot, err := json.Marshal(r.DataProject())
+	if err != nil {
+		return err
+	}
+
+	if !conn.Send(snapshot) {
+		return errClientClosed
+	}
+	return nil
+}
+
+// resyncRequest lee el pedido de "resync". Si no se puede leer se manda el
+// proyecto completo
+func resyncRequest(data json.RawMessage) dtos.Resync {
+	var req dtos.Resync
+	if len(data) > 0 {
+		json.Unmarshal(data, &req)
+	}
+	return req
+}
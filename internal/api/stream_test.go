@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/api/dtos"
+)
+
+// streamRoom devuelve una sala que ya envió n mensajes numerados
+func streamRoom(t *testing.T, n int) *RoomData {
+	t.Helper()
+
+	room := testRoom()
+	for i := 1; i <= n; i++ {
+		if _, err := room.sequence(map[string]interface{}{"action": "test", "n": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return room
+}
+
+// resyncResult describe lo que recibió el cliente: los n de los mensajes
+// reenviados o "data" si recibió el proyecto completo
+func resyncResult(t *testing.T, u *UserConnection) []string {
+	t.Helper()
+
+	var result []string
+	for _, raw := range received(u) {
+		var msg struct {
+			Action string `json:"action"`
+			N      int    `json:"n"`
+			Seq    int64  `json:"seq"`
+		}
+		if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Action == "data" {
+			result = append(result, "data")
+			continue
+		}
+		if int64(msg.N) != msg.Seq {
+			t.Errorf("message %d has seq %d", msg.N, msg.Seq)
+		}
+		result = append(result, fmt.Sprint(msg.N))
+	}
+	return result
+}
+
+func TestResync(t *testing.T) {
+	defer func(size int) { streamBufferSize = size }(streamBufferSize)
+	streamBufferSize = 8
+
+	tests := []struct {
+		name   string
+		sent   int
+		stream string // "" usa el de la sala
+		seq    int64
+		want   []string
+	}{
+		{"up to date", 5, "", 5, nil},
+		{"missed in order", 5, "", 2, []string{"3", "4", "5"}},
+		{"from the start", 5, "", 0, []string{"1", "2", "3", "4", "5"}},
+		{"after the ring wraps", 20, "", 14, []string{"15", "16", "17", "18", "19", "20"}},
+		{"whole buffer", 20, "", 12, []string{"13", "14", "15", "16", "17", "18", "19", "20"}},
+		{"gap larger than the buffer", 20, "", 11, []string{"data"}},
+		{"other stream", 5, "restarted", 2, []string{"data"}},
+		{"no stream", 5, "-", 2, []string{"data"}},
+		{"seq ahead", 5, "", 6, []string{"data"}},
+		{"negative seq", 5, "", -1, []string{"data"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := streamRoom(t, tt.sent)
+
+			stream := room.streamID
+			switch tt.stream {
+			case "":
+			case "-":
+				stream = ""
+			default:
+				stream = tt.stream
+			}
+
+			u := testUser("a@test.com")
+			if err := room.resync(u.Conn, dtos.Resync{Seq: tt.seq, Stream: stream}); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := resyncResult(t, u); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resync = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResyncSnapshotSeq(t *testing.T) {
+	room := streamRoom(t, 3)
+
+	u := testUser("a@test.com")
+	if err := room.resync(u.Conn, dtos.Resync{Seq: 9, Stream: room.streamID}); err != nil {
+		t.Fatal(err)
+	}
+
+	// El proyecto completo lleva la secuencia y el stream para seguir desde ahí
+	var snapshot struct {
+		Seq    int64  `json:"seq"`
+		Stream string `json:"stream"`
+	}
+	messages := received(u)
+	if len(messages) != 1 {
+		t.Fatalf("received %d messages, want 1", len(messages))
+	}
+	if err := json.Unmarshal([]byte(messages[0]), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Seq != 3 || snapshot.Stream != room.streamID {
+		t.Errorf("snapshot seq %d stream %q, want 3 and %q", snapshot.Seq, snapshot.Stream, room.streamID)
+	}
+}
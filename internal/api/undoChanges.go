@@ -1,38 +1,430 @@
 package api
 
-import "log"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
 
+	"github.com/ProyectoT/api/internal/api/dtos"
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/lithammer/shortuuid/v4"
+)
+
+// Cada usuario tiene su propio historial. Las acciones guardan el ID de la
+// capa y no su posición, así que siguen valiendo aunque otros usuarios agreguen
+// o muevan capas. Si lo que se quiere deshacer fue cambiado o eliminado por
+// otro usuario, la acción se descarta y se devuelve el error.
 type Action struct {
-	Execute func() //Apply
-	Undo    func() //Revert
+	Type    string  // acción que lo creó, ver newCommand
+	Command command // estado para aplicarla y revertirla
+}
+
+var (
+	errNothingToUndo = errors.New("nothing to undo")
+	errNothingToRedo = errors.New("nothing to redo")
+	errLayerNotFound = errors.New("the layer no longer exists")
+	errNotFound      = errors.New("the element no longer exists")
+	errConflict      = errors.New("the element was changed by another user")
+)
+
+// Límites del historial de cada usuario: cantidad de pasos en memoria y tamaño
+// al guardarlo con el proyecto. Se descartan los pasos más antiguos
+var (
+	historyLimit     = 100
+	historySizeLimit = 1 << 20
+)
+
+func performAction(room *RoomData, user string, action Action) error {
+	if err := action.Command.Execute(room); err != nil {
+		return err
+	}
+
+	pushAction(room, user, action)
+	return nil
 }
 
-func performAction(room *RoomData, action Action) {
-	action.Execute()
-	room.undoStack = append(room.undoStack, action)
-	room.redoStack = nil
+// pushAction agrega al historial del usuario una acción ya aplicada
+func pushAction(room *RoomData, user string, action Action) {
+	stack := append(room.undoStacks[user], action)
+	if len(stack) > historyLimit {
+		stack = stack[len(stack)-historyLimit:]
+	}
+
+	room.undoStacks[user] = stack
+	delete(room.redoStacks, user)
+}
+
+// undo deshace el último paso del usuario. Devuelve el paso tal como estaba
+// antes de deshacerlo, para el registro de operaciones
+func undo(room *RoomData, user string) (models.Command, error) {
+	stack := room.undoStacks[user]
+	if len(stack) == 0 {
+		return models.Command{}, errNothingToUndo
+	}
+
+	action := stack[len(stack)-1]
+	room.undoStacks[user] = stack[:len(stack)-1]
+
+	applied, err := encodeAction(action)
+	if err != nil {
+		return models.Command{}, err
+	}
+
+	if err := action.Command.Undo(room); err != nil {
+		return models.Command{}, fmt.Errorf("cannot undo: %w", err)
+	}
+
+	room.redoStacks[user] = append(room.redoStacks[user], action)
+	return applied, nil
 }
 
-func undo(room *RoomData) {
-	if len(room.undoStack) == 0 {
-		log.Println("No hay nada que deshacer")
-		return
+// redo vuelve a aplicar el último paso deshecho. Devuelve el paso tal como
+// estaba antes de aplicarlo
+func redo(room *RoomData, user string) (models.Command, error) {
+	stack := room.redoStacks[user]
+	if len(stack) == 0 {
+		return models.Command{}, errNothingToRedo
+	}
+
+	action := stack[len(stack)-1]
+	room.redoStacks[user] = stack[:len(stack)-1]
+
+	applied, err := encodeAction(action)
+	if err != nil {
+		return models.Command{}, err
+	}
+
+	if err := action.Command.Execute(room); err != nil {
+		return models.Command{}, fmt.Errorf("cannot redo: %w", err)
 	}
 
-	action := room.undoStack[len(room.undoStack)-1]
-	room.undoStack = room.undoStack[:len(room.undoStack)-1]
-	action.Undo()
-	room.redoStack = append(room.redoStack, action)
+	room.undoStacks[user] = append(room.undoStacks[user], action)
+	return applied, nil
 }
 
-func redo(room *RoomData) {
-	if len(room.redoStack) == 0 {
-		log.Println("No hay nada que rehacer")
-		return
+// applyHistory deshace o rehace el último paso del usuario. En el registro de
+// operaciones queda el paso aplicado ("undone" o "redone") y no la acción, así
+// se puede repetir sin el historial del usuario, que las revisiones no guardan.
+// Si otra conexión tiene bloqueado lo que cambia el paso, queda en el historial
+func applyHistory(room *RoomData, op *models.Operation) error {
+	var applied models.Command
+	var err error
+
+	stack := room.undoStacks[op.User]
+	if op.Action == "redo" {
+		stack = room.redoStacks[op.User]
+	}
+	if len(stack) > 0 {
+		if err := room.checkLease(op.Conn, stack[len(stack)-1].Command, op.Action == "undo"); err != nil {
+			return err
+		}
+	}
+
+	logged := "undone"
+	if op.Action == "undo" {
+		applied, err = undo(room, op.User)
+	} else {
+		logged = "redone"
+		applied, err = redo(room, op.User)
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(applied)
+	if err != nil {
+		return err
 	}
 
-	action := room.redoStack[len(room.redoStack)-1]
-	room.redoStack = room.redoStack[:len(room.redoStack)-1]
-	action.Execute()
-	room.undoStack = append(room.undoStack, action)
+	op.Action = logged
+	op.Data = data
+	return nil
+}
+
+// replayHistory repite un "undone" o "redone" del registro. El historial del
+// usuario se actualiza igual que al deshacer o rehacer, por si la sala lo tiene
+func replayHistory(room *RoomData, op *models.Operation) error {
+	var applied models.Command
+	if err := json.Unmarshal(op.Data, &applied); err != nil {
+		return errInvalidData
+	}
+
+	actions := decodeActions([]models.Command{applied})
+	if len(actions) == 0 {
+		return errUnknownAction
+	}
+	action := actions[0]
+
+	from, to := room.undoStacks, room.redoStacks
+	if op.Action == "undone" {
+		if err := action.Command.Undo(room); err != nil {
+			return err
+		}
+	} else {
+		if err := action.Command.Execute(room); err != nil {
+			return err
+		}
+		from, to = to, from
+	}
+
+	if stack := from[op.User]; len(stack) > 0 {
+		from[op.User] = stack[:len(stack)-1]
+	}
+	to[op.User] = append(to[op.User], action)
+	return nil
+}
+
+func encodeAction(action Action) (models.Command, error) {
+	data, err := json.Marshal(action.Command)
+	if err != nil {
+		return models.Command{}, err
+	}
+	return models.Command{Action: action.Type, Data: data}, nil
+}
+
+// clearHistory borra el historial de todos los usuarios
+func (r *RoomData) clearHistory() {
+	r.undoStacks = make(map[string][]Action)
+	r.redoStacks = make(map[string][]Action)
+}
+
+// encodeHistory convierte el historial en registros para guardarlo con el
+// proyecto. Si un usuario supera historySizeLimit se descartan sus pasos más
+// antiguos, primero los de rehacer
+func (r *RoomData) encodeHistory() []models.UserHistory {
+	users := make([]string, 0, len(r.undoStacks))
+	for user := range r.undoStacks {
+		users = append(users, user)
+	}
+	for user := range r.redoStacks {
+		if _, ok := r.undoStacks[user]; !ok {
+			users = append(users, user)
+		}
+	}
+	sort.Strings(users)
+
+	history := make([]models.UserHistory, 0, len(users))
+
+	for _, user := range users {
+		size := 0
+		undo := encodeActions(r.undoStacks[user], &size)
+		redo := encodeActions(r.redoStacks[user], &size)
+
+		for size > historySizeLimit && len(redo) > 0 {
+			size -= len(redo[0].Data)
+			redo = redo[1:]
+		}
+		for size > historySizeLimit && len(undo) > 0 {
+			size -= len(undo[0].Data)
+			undo = undo[1:]
+		}
+
+		if len(undo) == 0 && len(redo) == 0 {
+			continue
+		}
+
+		history = append(history, models.UserHistory{User: user, Undo: undo, Redo: redo})
+	}
+
+	return history
+}
+
+func encodeActions(actions []Action, size *int) []models.Command {
+	commands := make([]models.Command, 0, len(actions))
+
+	for _, action := range actions {
+		data, err := json.Marshal(action.Command)
+		if err != nil {
+			log.Println("Error guardando el historial: ", err)
+			continue
+		}
+
+		*size += len(data)
+		commands = append(commands, models.Command{Action: action.Type, Data: data})
+	}
+
+	return commands
+}
+
+// loadHistory recupera el historial guardado con el proyecto
+func (r *RoomData) loadHistory(history []models.UserHistory) {
+	r.clearHistory()
+
+	for _, h := range history {
+		if undo := decodeActions(h.Undo); len(undo) > 0 {
+			if len(undo) > historyLimit {
+				undo = undo[len(undo)-historyLimit:]
+			}
+			r.undoStacks[h.User] = undo
+		}
+		if redo := decodeActions(h.Redo); len(redo) > 0 {
+			if len(redo) > historyLimit {
+				redo = redo[len(redo)-historyLimit:]
+			}
+			r.redoStacks[h.User] = redo
+		}
+	}
+}
+
+func decodeActions(commands []models.Command) []Action {
+	actions := make([]Action, 0, len(commands))
+
+	for _, c := range commands {
+		cmd := newCommand(c.Action)
+		if cmd == nil {
+			continue
+		}
+
+		if err := json.Unmarshal(c.Data, cmd); err != nil {
+			log.Println("Error leyendo el historial: ", err)
+			continue
+		}
+
+		actions = append(actions, Action{Type: c.Action, Command: cmd})
+	}
+
+	return actions
+}
+
+// ensureRowIDs asigna un ID a las capas que no lo tienen (proyectos antiguos)
+func (r *RoomData) ensureRowIDs() {
+	for i := range r.Data {
+		if r.Data[i].ID == "" {
+			r.Data[i].ID = shortuuid.New()
+		}
+	}
+}
+
+// rowIndex devuelve la posición actual de la capa o -1 si ya no existe
+func (r *RoomData) rowIndex(id string) int {
+	for i, row := range r.Data {
+		if row.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// rowID devuelve el ID de la capa en esa posición
+func (r *RoomData) rowID(index int) (string, error) {
+	if index < 0 || index >= len(r.Data) {
+		return "", errLayerNotFound
+	}
+	return r.Data[index].ID, nil
+}
+
+// prevRowID devuelve el ID de la capa de arriba, o "" si es la primera
+func (r *RoomData) prevRowID(index int) string {
+	if index <= 0 || index > len(r.Data) {
+		return ""
+	}
+	return r.Data[index-1].ID
+}
+
+// rowPosition devuelve dónde insertar una capa para que quede bajo prevID. Si
+// esa capa ya no existe se usa la posición original
+func (r *RoomData) rowPosition(prevID string, fallback int) int {
+	if prevID == "" {
+		return 0
+	}
+	if p := r.rowIndex(prevID); p != -1 {
+		return p + 1
+	}
+	if fallback < 0 || fallback > len(r.Data) {
+		return len(r.Data)
+	}
+	return fallback
+}
+
+// insertRow vuelve a poner una capa bajo prevID
+func insertRow(room *RoomData, prevID string, fallback int, row models.DataInfo) error {
+	if room.rowIndex(row.ID) != -1 {
+		return errConflict
+	}
+
+	añadir(room, dtos.Add{RowIndex: room.rowPosition(prevID, fallback)}, row)
+	return nil
+}
+
+// removeRow quita la capa y devuelve cómo estaba y dónde, para poder reponerla
+func removeRow(room *RoomData, id string) (models.DataInfo, string, int, error) {
+	i := room.rowIndex(id)
+	if i == -1 {
+		return models.DataInfo{}, "", 0, errLayerNotFound
+	}
+
+	row := room.Data[i]
+	prev := room.prevRowID(i)
+	deleteRow(room, dtos.Delete{RowIndex: i})
+
+	return row, prev, i, nil
+}
+
+// moveRow mueve la capa para que quede bajo prevID
+func moveRow(room *RoomData, id string, prevID string) error {
+	from := room.rowIndex(id)
+	if from == -1 {
+		return errLayerNotFound
+	}
+
+	to := 0
+	if prevID != "" {
+		p := room.rowIndex(prevID)
+		if p == -1 {
+			return errConflict
+		}
+		if p < from {
+			to = p + 1
+		} else {
+			to = p
+		}
+	}
+
+	layerDrop(room, from, to)
+	return nil
+}
+
+// findCircle busca el punto en el polígono, primero en la posición esperada
+// por si otro usuario agregó o quitó puntos antes
+func findCircle(circles []models.CircleStruc, circle models.CircleStruc, hint int) int {
+	if hint >= 0 && hint < len(circles) && circles[hint] == circle {
+		return hint
+	}
+	for i, c := range circles {
+		if c == circle {
+			return i
+		}
+	}
+	return -1
+}
+
+// findSection busca una sección de facie, primero en la posición esperada
+func findSection(sections []models.FaciesSection, section models.FaciesSection, hint int) int {
+	if hint >= 0 && hint < len(sections) && sections[hint] == section {
+		return hint
+	}
+	for i, s := range sections {
+		if s == section {
+			return i
+		}
+	}
+	return -1
+}
+
+// cellValue devuelve el texto de una celda, para compararla
+func cellValue(row models.DataInfo, key string) string {
+	return cellText(row.Columns[key])
+}
+
+func cellText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
 }
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+func editCell(t *testing.T, room *RoomData, user string, row int, value string) {
+	t.Helper()
+
+	op := editOperation(t, user, "editText", map[string]interface{}{"key": "Espesor", "value": value, "rowIndex": row})
+	if err := applyOperation(room, op); err != nil {
+		t.Fatalf("editText by %s: %v", user, err)
+	}
+}
+
+func historyOperation(user string, action string) *models.Operation {
+	return &models.Operation{User: user, Action: action}
+}
+
+func TestUndoKeepsOtherUsersEdits(t *testing.T) {
+	room := testRoom()
+
+	editCell(t, room, "a@test.com", 0, "1")
+	editCell(t, room, "b@test.com", 1, "2")
+
+	if err := applyOperation(room, historyOperation("a@test.com", "undo")); err != nil {
+		t.Fatal(err)
+	}
+
+	if cell := room.Data[0].Columns["Espesor"]; cell != float64(10) {
+		t.Errorf("layer 0 = %#v, want float64(10)", cell)
+	}
+	if cell := room.Data[1].Columns["Espesor"]; cell != "2" {
+		t.Errorf("layer 1 = %#v, want the other user's edit", cell)
+	}
+	if len(room.undoStacks["b@test.com"]) != 1 || len(room.redoStacks["a@test.com"]) != 1 {
+		t.Errorf("histories changed: b undo %d, a redo %d", len(room.undoStacks["b@test.com"]), len(room.redoStacks["a@test.com"]))
+	}
+
+	// El otro usuario no tiene nada que rehacer
+	if err := applyOperation(room, historyOperation("b@test.com", "redo")); !errors.Is(err, errNothingToRedo) {
+		t.Errorf("redo by b = %v, want %v", err, errNothingToRedo)
+	}
+}
+
+func TestUndoFollowsLayerByID(t *testing.T) {
+	room := testRoom()
+	id := room.Data[1].ID
+
+	editCell(t, room, "a@test.com", 1, "7")
+
+	// Otro usuario agrega una capa arriba de la editada
+	if err := applyOperation(room, editOperation(t, "b@test.com", "añadir", map[string]interface{}{"rowIndex": 0})); err != nil {
+		t.Fatal(err)
+	}
+	if room.Data[2].ID != id {
+		t.Fatalf("the edited layer is at %d, want 2", room.rowIndex(id))
+	}
+
+	if err := applyOperation(room, historyOperation("a@test.com", "undo")); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, row := range room.Data {
+		if row.ID != id && row.Columns["Espesor"] == "7" {
+			t.Errorf("undo changed layer %d instead", i)
+		}
+	}
+	if cell := room.Data[2].Columns["Espesor"]; cell != float64(20) {
+		t.Errorf("edited layer = %#v, want float64(20)", cell)
+	}
+}
+
+func TestUndoAfterOtherUserChange(t *testing.T) {
+	tests := []struct {
+		name  string
+		other func(t *testing.T, room *RoomData)
+		err   error
+	}{
+		{
+			name: "layer deleted",
+			other: func(t *testing.T, room *RoomData) {
+				if err := applyOperation(room, editOperation(t, "b@test.com", "delete", map[string]interface{}{"rowIndex": 1})); err != nil {
+					t.Fatal(err)
+				}
+			},
+			err: errLayerNotFound,
+		},
+		{
+			name: "cell edited",
+			other: func(t *testing.T, room *RoomData) {
+				editCell(t, room, "b@test.com", 1, "9")
+			},
+			err: errConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := testRoom()
+
+			editCell(t, room, "a@test.com", 1, "7")
+			tt.other(t, room)
+			before := room.snapshot()
+
+			err := applyOperation(room, historyOperation("a@test.com", "undo"))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("undo = %v, want %v", err, tt.err)
+			}
+
+			if after := room.snapshot(); !reflect.DeepEqual(before.Data, after.Data) {
+				t.Errorf("undo changed the layers\nbefore: %+v\nafter:  %+v", before.Data, after.Data)
+			}
+			// El paso se descarta
+			if len(room.undoStacks["a@test.com"]) != 0 || len(room.redoStacks["a@test.com"]) != 0 {
+				t.Errorf("history kept the step: undo %d, redo %d", len(room.undoStacks["a@test.com"]), len(room.redoStacks["a@test.com"]))
+			}
+		})
+	}
+}
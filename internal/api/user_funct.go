@@ -102,21 +102,31 @@ func (a *API) DeleteProject(c echo.Context) error {
 	if exists {
 		existingRoom := roomInterface.(*RoomData)
 
+		var owner bool
+		var members models.Members
+
 		// Verificación de permisos del usuario
-		if existingRoom.ProjectInfo.Members.Owner != user {
-			// El usuario no es el dueño, solo se eliminará su acceso
-			existingRoom.ProjectInfo.Members.Editors = removeUser(existingRoom.ProjectInfo.Members.Editors, user)
-			existingRoom.ProjectInfo.Members.Readers = removeUser(existingRoom.ProjectInfo.Members.Readers, user)
+		err := existingRoom.do(func() {
+			owner = existingRoom.ProjectInfo.Members.Owner == user
+			if !owner {
+				// El usuario no es el dueño, solo se eliminará su acceso
+				existingRoom.ProjectInfo.Members.Editors = removeUser(existingRoom.ProjectInfo.Members.Editors, user)
+				existingRoom.ProjectInfo.Members.Readers = removeUser(existingRoom.ProjectInfo.Members.Readers, user)
+				members = existingRoom.ProjectInfo.Members
+			}
+		})
+		if err != nil {
+			return a.handleError(c, http.StatusNotFound, "Room not found")
+		}
 
-			err = a.repo.UpdateMembers(ctx, id, existingRoom.ProjectInfo.Members)
+		if !owner {
+			err = a.repo.UpdateMembers(ctx, id, members)
 			if err != nil {
 				return a.handleError(c, http.StatusInternalServerError, "Failed to delete user from room")
 			}
 		} else {
 			// El usuario es el dueño, se desconectan todos los usuarios y se elimina la sala
-			existingRoom.DisconnectUsers()
-
-			rooms.Delete(id)
+			a.closeRoom(id, existingRoom, closeDeleted)
 
 			err = a.repo.DeleteProject(ctx, id)
 			if err != nil {
@@ -136,11 +146,14 @@ func (a *API) DeleteProject(c echo.Context) error {
 			if err = a.repo.DeleteUserRoom(ctx, user, id); err != nil {
 				return a.handleError(c, http.StatusInternalServerError, "Failed to delete user from room")
 			}
+			// Puede estar abierta en otra instancia
+			a.publishMembers(ctx, id)
 		} else {
 			// Si el usuario es el dueño, elimina el proyecto de la base de datos
 			if err = a.repo.DeleteProject(ctx, id); err != nil {
 				return a.handleError(c, http.StatusInternalServerError, "Failed to delete room")
 			}
+			a.publishRoom(id, roomEvent{Type: roomEventDeleted})
 		}
 	}
 
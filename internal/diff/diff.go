@@ -0,0 +1,828 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// Tipos de cambio
+const (
+	Added   = "added"
+	Removed = "removed"
+	Moved   = "moved"
+	Changed = "changed"
+)
+
+// Diff es la diferencia entre dos estados de un proyecto, del estado A (from) al B (to)
+type Diff struct {
+	Summary  Summary        `json:"summary"`
+	Info     []FieldChange  `json:"info"`
+	Config   []ConfigChange `json:"config"`
+	Layers   []LayerChange  `json:"layers"`
+	Fosils   []EntryChange  `json:"fosils"`
+	Muestras []EntryChange  `json:"muestras"`
+	Facies   []FacieChange  `json:"facies"`
+}
+
+// Summary cuenta los cambios por tipo para mostrarlos sin recorrer el detalle
+type Summary struct {
+	LayersAdded   int  `json:"layersAdded"`
+	LayersRemoved int  `json:"layersRemoved"`
+	LayersMoved   int  `json:"layersMoved"`
+	LayersChanged int  `json:"layersChanged"`
+	Fosils        int  `json:"fosils"`
+	Muestras      int  `json:"muestras"`
+	Facies        int  `json:"facies"`
+	Config        int  `json:"config"`
+	Info          int  `json:"info"`
+	Equal         bool `json:"equal"`
+}
+
+// FieldChange es un valor que cambió
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// ConfigChange es un cambio en la configuración de columnas
+type ConfigChange struct {
+	Type   string      `json:"type"` // added, removed, moved o changed
+	Column string      `json:"column,omitempty"`
+	Field  string      `json:"field,omitempty"`
+	Old    interface{} `json:"old,omitempty"`
+	New    interface{} `json:"new,omitempty"`
+}
+
+// LayerChange es una capa agregada, eliminada, movida o editada. From y To son
+// su posición en Data en cada estado
+type LayerChange struct {
+	Type      string         `json:"type"`
+	From      *int           `json:"from,omitempty"`
+	To        *int           `json:"to,omitempty"`
+	Cells     []FieldChange  `json:"cells,omitempty"`
+	Litologia []FieldChange  `json:"litologia,omitempty"`
+	Circles   []CircleChange `json:"circles,omitempty"`
+}
+
+// CircleChange es un punto del polígono de la litología que cambió
+type CircleChange struct {
+	Index int                 `json:"index"`
+	Old   *models.CircleStruc `json:"old,omitempty"`
+	New   *models.CircleStruc `json:"new,omitempty"`
+}
+
+// EntryChange es un fósil o muestra agregado, eliminado o editado
+type EntryChange struct {
+	Type   string        `json:"type"`
+	ID     string        `json:"id"`
+	Old    interface{}   `json:"old,omitempty"`
+	New    interface{}   `json:"new,omitempty"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// FacieChange es una facie o una de sus secciones agregada, eliminada o editada.
+// Index es -1 cuando el cambio es de la facie completa
+type FacieChange struct {
+	Type  string                `json:"type"`
+	Facie string                `json:"facie"`
+	Index int                   `json:"index"`
+	Old   *models.FaciesSection `json:"old,omitempty"`
+	New   *models.FaciesSection `json:"new,omitempty"`
+}
+
+// Compare devuelve la diferencia del estado a al estado b
+func Compare(a *models.Project, b *models.Project) *Diff {
+	d := &Diff{
+		Info:     compareInfo(a.ProjectInfo, b.ProjectInfo),
+		Config:   compareConfig(a.Config, b.Config),
+		Layers:   compareLayers(a.Data, b.Data),
+		Fosils:   compareEntries(fosilEntries(a.Fosil), fosilEntries(b.Fosil)),
+		Muestras: compareEntries(muestraEntries(a.Muestras), muestraEntries(b.Muestras)),
+		Facies:   compareFacies(a.Facies, b.Facies),
+	}
+
+	for _, l := range d.Layers {
+		switch l.Type {
+		case Added:
+			d.Summary.LayersAdded++
+		case Removed:
+			d.Summary.LayersRemoved++
+		case Moved:
+			d.Summary.LayersMoved++
+		case Changed:
+			d.Summary.LayersChanged++
+		}
+	}
+	d.Summary.Fosils = len(d.Fosils)
+	d.Summary.Muestras = len(d.Muestras)
+	d.Summary.Facies = len(d.Facies)
+	d.Summary.Config = len(d.Config)
+	d.Summary.Info = len(d.Info)
+	d.Summary.Equal = len(d.Layers)+len(d.Fosils)+len(d.Muestras)+len(d.Facies)+len(d.Config)+len(d.Info) == 0
+
+	return d
+}
+
+func compareInfo(a models.ProjectInfo, b models.ProjectInfo) []FieldChange {
+	changes := []FieldChange{}
+
+	add := func(field string, old interface{}, new interface{}) {
+		if old != new {
+			changes = append(changes, FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+
+	add("name", a.Name, b.Name)
+	add("description", a.Description, b.Description)
+	add("location", a.Location, b.Location)
+	add("lat", a.Lat, b.Lat)
+	add("long", a.Long, b.Long)
+	add("visible", a.Visible, b.Visible)
+
+	return changes
+}
+
+func compareConfig(a models.Config, b models.Config) []ConfigChange {
+	changes := []ConfigChange{}
+
+	if a.IsInverted != b.IsInverted {
+		changes = append(changes, ConfigChange{Type: Changed, Field: "isInverted", Old: a.IsInverted, New: b.IsInverted})
+	}
+
+	oldColumns := map[string]models.Column{}
+	for _, col := range a.Columns {
+		oldColumns[col.Name] = col
+	}
+	newColumns := map[string]models.Column{}
+	for _, col := range b.Columns {
+		newColumns[col.Name] = col
+	}
+
+	for _, col := range a.Columns {
+		if _, ok := newColumns[col.Name]; !ok {
+			changes = append(changes, ConfigChange{Type: Removed, Column: col.Name})
+		}
+	}
+
+	for _, col := range b.Columns {
+		old, ok := oldColumns[col.Name]
+		if !ok {
+			changes = append(changes, ConfigChange{Type: Added, Column: col.Name})
+			continue
+		}
+		if old.Visible != col.Visible {
+			changes = append(changes, ConfigChange{Type: Changed, Column: col.Name, Field: "visible", Old: old.Visible, New: col.Visible})
+		}
+	}
+
+	// El orden se compara solo entre las columnas que están en ambos estados
+	var oldOrder, newOrder []string
+	for _, col := range a.Columns {
+		if _, ok := newColumns[col.Name]; ok {
+			oldOrder = append(oldOrder, col.Name)
+		}
+	}
+	for _, col := range b.Columns {
+		if _, ok := oldColumns[col.Name]; ok {
+			newOrder = append(newOrder, col.Name)
+		}
+	}
+
+	kept := lcs(oldOrder, newOrder)
+	inPlace := map[string]bool{}
+	for _, pair := range kept {
+		inPlace[oldOrder[pair[0]]] = true
+	}
+	for i, name := range newOrder {
+		if !inPlace[name] {
+			changes = append(changes, ConfigChange{Type: Moved, Column: name, Old: indexOf(oldOrder, name), New: i})
+		}
+	}
+
+	return changes
+}
+
+// compareLayers empareja las capas de ambos estados. Primero se emparejan las
+// que tienen el mismo ID en ambos (ver DataInfo.ID): son la misma capa aunque
+// se haya editado casi entera. Entre el resto, las capas idénticas que
+// mantienen su orden relativo quedan fijas, las idénticas en otra posición se
+// consideran movidas y entre las demás se emparejan las más parecidas como
+// editadas. Lo que sobra son capas agregadas o eliminadas
+func compareLayers(a []models.DataInfo, b []models.DataInfo) []LayerChange {
+	contentA := make([]string, len(a))
+	for i, row := range a {
+		contentA[i] = layerKey(row)
+	}
+	contentB := make([]string, len(b))
+	for i, row := range b {
+		contentB[i] = layerKey(row)
+	}
+
+	byID := pairByID(a, b)
+
+	// Las capas emparejadas por ID solo coinciden entre sí en lcs, así las que
+	// mantienen su orden no se cuentan como movidas
+	keysA := append([]string{}, contentA...)
+	keysB := append([]string{}, contentB...)
+	for i, j := range byID {
+		keysA[i] = "#" + a[i].ID
+		keysB[j] = "#" + b[j].ID
+	}
+
+	matchedA := make([]bool, len(a))
+	matchedB := make([]bool, len(b))
+
+	changes := []LayerChange{}
+
+	anchors := lcs(keysA, keysB)
+	for _, pair := range anchors {
+		i, j := pair[0], pair[1]
+		matchedA[i] = true
+		matchedB[j] = true
+		if contentA[i] != contentB[j] {
+			changes = append(changes, changedLayer(a, b, i, j))
+		}
+	}
+
+	for i, j := range byID {
+		if matchedA[i] {
+			continue
+		}
+		matchedA[i] = true
+		matchedB[j] = true
+		if contentA[i] == contentB[j] {
+			changes = append(changes, LayerChange{Type: Moved, From: intPtr(i), To: intPtr(j)})
+		} else {
+			changes = append(changes, changedLayer(a, b, i, j))
+		}
+	}
+
+	// Capas iguales que cambiaron de posición
+	free := map[string][]int{}
+	for i, key := range contentA {
+		if !matchedA[i] {
+			free[key] = append(free[key], i)
+		}
+	}
+	for j, key := range contentB {
+		if matchedB[j] || len(free[key]) == 0 {
+			continue
+		}
+		i := free[key][0]
+		free[key] = free[key][1:]
+		matchedA[i] = true
+		matchedB[j] = true
+		changes = append(changes, LayerChange{Type: Moved, From: intPtr(i), To: intPtr(j)})
+	}
+
+	// Capas editadas: se emparejan las más parecidas entre las que quedan
+	type candidate struct {
+		i, j  int
+		score float64
+	}
+	var restA, restB []int
+	for i := range a {
+		if !matchedA[i] {
+			restA = append(restA, i)
+		}
+	}
+	for j := range b {
+		if !matchedB[j] {
+			restB = append(restB, j)
+		}
+	}
+
+	// Cada capa se compara con todas las del otro estado, o si son demasiadas
+	// solo con las más cercanas a su posición
+	window := len(restB)
+	if len(restA) > 0 && len(restA)*len(restB) > maxSimilarityPairs {
+		window = maxSimilarityPairs / len(restA)
+		if window < 1 {
+			window = 1
+		}
+	}
+
+	var candidates []candidate
+	for x, i := range restA {
+		from := 0
+		if window < len(restB) {
+			from = x*len(restB)/len(restA) - window/2
+			if from < 0 {
+				from = 0
+			}
+			if from > len(restB)-window {
+				from = len(restB) - window
+			}
+		}
+
+		for _, j := range restB[from : from+window] {
+			if score := similarity(a[i], b[j]); score >= minSimilarity {
+				candidates = append(candidates, candidate{i, j, score})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(x, y int) bool {
+		if candidates[x].score != candidates[y].score {
+			return candidates[x].score > candidates[y].score
+		}
+		// A igual parecido se prefiere la capa más cercana a su posición original
+		return abs(candidates[x].i-candidates[x].j) < abs(candidates[y].i-candidates[y].j)
+	})
+
+	for _, c := range candidates {
+		if matchedA[c.i] || matchedB[c.j] {
+			continue
+		}
+		matchedA[c.i] = true
+		matchedB[c.j] = true
+
+		changes = append(changes, changedLayer(a, b, c.i, c.j))
+	}
+
+	for i := range a {
+		if !matchedA[i] {
+			changes = append(changes, LayerChange{Type: Removed, From: intPtr(i)})
+		}
+	}
+	for j := range b {
+		if !matchedB[j] {
+			changes = append(changes, LayerChange{Type: Added, To: intPtr(j)})
+		}
+	}
+
+	sort.SliceStable(changes, func(x, y int) bool {
+		return layerOrder(changes[x]) < layerOrder(changes[y])
+	})
+
+	return changes
+}
+
+// pairByID empareja las capas (posición en a -> posición en b) cuyo ID está
+// una sola vez en cada estado. Las capas sin ID (proyectos antiguos) o con el
+// ID repetido quedan para las demás reglas
+func pairByID(a []models.DataInfo, b []models.DataInfo) map[int]int {
+	indexes := func(rows []models.DataInfo) map[string]int {
+		ids := make(map[string]int, len(rows))
+		for i, row := range rows {
+			if row.ID == "" {
+				continue
+			}
+			if _, repeated := ids[row.ID]; repeated {
+				ids[row.ID] = -1
+				continue
+			}
+			ids[row.ID] = i
+		}
+		return ids
+	}
+
+	idsB := indexes(b)
+	pairs := map[int]int{}
+	for id, i := range indexes(a) {
+		if j, ok := idsB[id]; ok && i != -1 && j != -1 {
+			pairs[i] = j
+		}
+	}
+	return pairs
+}
+
+// changedLayer describe la edición de la capa a[i], que quedó como b[j]
+func changedLayer(a []models.DataInfo, b []models.DataInfo, i int, j int) LayerChange {
+	return LayerChange{
+		Type:      Changed,
+		From:      intPtr(i),
+		To:        intPtr(j),
+		Cells:     compareCells(a[i].Columns, b[j].Columns),
+		Litologia: compareLitologia(a[i].Litologia, b[j].Litologia),
+		Circles:   compareCircles(a[i].Litologia.Circles, b[j].Litologia.Circles),
+	}
+}
+
+// maxSimilarityPairs limita las comparaciones entre capas distintas al buscar
+// las editadas
+const maxSimilarityPairs = 200000
+
+// minSimilarity es la fracción mínima de campos iguales para considerar que
+// dos capas son la misma capa editada
+const minSimilarity = 0.5
+
+// similarity devuelve la fracción de campos iguales entre dos capas: celdas,
+// atributos de la litología y el polígono
+func similarity(a models.DataInfo, b models.DataInfo) float64 {
+	cells := compareCells(a.Columns, b.Columns)
+	keys := map[string]bool{}
+	for k, v := range a.Columns {
+		if cellText(v) != "" {
+			keys[k] = true
+		}
+	}
+	for k, v := range b.Columns {
+		if cellText(v) != "" {
+			keys[k] = true
+		}
+	}
+
+	const litologiaFields = 8
+	total := len(keys) + litologiaFields + 1
+	different := len(cells) + len(compareLitologia(a.Litologia, b.Litologia))
+	if len(compareCircles(a.Litologia.Circles, b.Litologia.Circles)) > 0 {
+		different++
+	}
+
+	return float64(total-different) / float64(total)
+}
+
+// layerOrder ordena los cambios por su posición en el estado B (las eliminadas por la de A)
+func layerOrder(c LayerChange) int {
+	if c.To != nil {
+		return *c.To
+	}
+	return *c.From
+}
+
+func compareCells(a map[string]interface{}, b map[string]interface{}) []FieldChange {
+	var keys []string
+	seen := map[string]bool{}
+	for k := range a {
+		keys = append(keys, k)
+		seen[k] = true
+	}
+	for k := range b {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []FieldChange
+	for _, k := range keys {
+		old, new := cellText(a[k]), cellText(b[k])
+		if old != new {
+			changes = append(changes, FieldChange{Field: k, Old: old, New: new})
+		}
+	}
+	return changes
+}
+
+func compareLitologia(a models.LitologiaStruc, b models.LitologiaStruc) []FieldChange {
+	var changes []FieldChange
+
+	add := func(field string, old interface{}, new interface{}) {
+		if old != new {
+			changes = append(changes, FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+
+	add("File", a.File, b.File)
+	add("ColorFill", a.ColorFill, b.ColorFill)
+	add("ColorStroke", a.ColorStroke, b.ColorStroke)
+	add("Contact", a.Contact, b.Contact)
+	add("Zoom", a.Zoom, b.Zoom)
+	add("Rotation", a.Rotation, b.Rotation)
+	add("Height", a.Height, b.Height)
+	add("Tension", a.Tension, b.Tension)
+
+	return changes
+}
+
+func compareCircles(a []models.CircleStruc, b []models.CircleStruc) []CircleChange {
+	var changes []CircleChange
+
+	for i := 0; i < len(a) || i < len(b); i++ {
+		switch {
+		case i >= len(a):
+			changes = append(changes, CircleChange{Index: i, New: &b[i]})
+		case i >= len(b):
+			changes = append(changes, CircleChange{Index: i, Old: &a[i]})
+		case a[i] != b[i]:
+			changes = append(changes, CircleChange{Index: i, Old: &a[i], New: &b[i]})
+		}
+	}
+
+	return changes
+}
+
+// entry es un fósil o muestra con sus campos comparables
+type entry struct {
+	value  interface{}
+	fields []FieldChange // campos con Old vacío, se usan como valores
+}
+
+func fosilEntries(m map[string]models.Fosil) map[string]entry {
+	entries := make(map[string]entry, len(m))
+	for id, f := range m {
+		entries[id] = entry{value: f, fields: []FieldChange{
+			{Field: "upper", New: f.Upper},
+			{Field: "lower", New: f.Lower},
+			{Field: "fosilImg", New: f.FosilImg},
+			{Field: "x", New: f.X},
+		}}
+	}
+	return entries
+}
+
+func muestraEntries(m map[string]models.Muestra) map[string]entry {
+	entries := make(map[string]entry, len(m))
+	for id, s := range m {
+		entries[id] = entry{value: s, fields: []FieldChange{
+			{Field: "upper", New: s.Upper},
+			{Field: "lower", New: s.Lower},
+			{Field: "muestraText", New: s.MuestraText},
+			{Field: "x", New: s.X},
+		}}
+	}
+	return entries
+}
+
+// compareEntries compara fósiles o muestras por id. Entre proyectos distintos
+// los ids no coinciden, así que las entradas idénticas con otro id no se reportan
+func compareEntries(a map[string]entry, b map[string]entry) []EntryChange {
+	changes := []EntryChange{}
+
+	var removed, added []string
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	for id := range b {
+		if _, ok := a[id]; !ok {
+			added = append(added, id)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	unmatched := map[interface{}]int{}
+	for _, id := range added {
+		unmatched[b[id].value]++
+	}
+	for _, id := range removed {
+		if unmatched[a[id].value] > 0 {
+			unmatched[a[id].value]--
+			continue
+		}
+		changes = append(changes, EntryChange{Type: Removed, ID: id, Old: a[id].value})
+	}
+
+	unmatched = map[interface{}]int{}
+	for _, id := range removed {
+		unmatched[a[id].value]++
+	}
+	for _, id := range added {
+		if unmatched[b[id].value] > 0 {
+			unmatched[b[id].value]--
+			continue
+		}
+		changes = append(changes, EntryChange{Type: Added, ID: id, New: b[id].value})
+	}
+
+	var common []string
+	for id := range b {
+		if _, ok := a[id]; ok {
+			common = append(common, id)
+		}
+	}
+	sort.Strings(common)
+
+	for _, id := range common {
+		var fields []FieldChange
+		for k, field := range a[id].fields {
+			old, new := field.New, b[id].fields[k].New
+			if old != new {
+				fields = append(fields, FieldChange{Field: field.Field, Old: old, New: new})
+			}
+		}
+		if len(fields) > 0 {
+			changes = append(changes, EntryChange{Type: Changed, ID: id, Old: a[id].value, New: b[id].value, Fields: fields})
+		}
+	}
+
+	return changes
+}
+
+func compareFacies(a map[string][]models.FaciesSection, b map[string][]models.FaciesSection) []FacieChange {
+	changes := []FacieChange{}
+
+	names := map[string]bool{}
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		old, inA := a[name]
+		new, inB := b[name]
+
+		switch {
+		case !inB:
+			changes = append(changes, FacieChange{Type: Removed, Facie: name, Index: -1})
+			continue
+		case !inA:
+			changes = append(changes, FacieChange{Type: Added, Facie: name, Index: -1})
+		}
+
+		for i := 0; i < len(old) || i < len(new); i++ {
+			switch {
+			case i >= len(old):
+				changes = append(changes, FacieChange{Type: Added, Facie: name, Index: i, New: &new[i]})
+			case i >= len(new):
+				changes = append(changes, FacieChange{Type: Removed, Facie: name, Index: i, Old: &old[i]})
+			case old[i] != new[i]:
+				changes = append(changes, FacieChange{Type: Changed, Facie: name, Index: i, Old: &old[i], New: &new[i]})
+			}
+		}
+	}
+
+	return changes
+}
+
+// lcs devuelve los pares de posiciones de una subsecuencia común más larga.
+// Primero se descartan el principio y el final comunes, que entre dos estados
+// del mismo proyecto suelen ser casi todo; el resto se resuelve con el
+// algoritmo de Hirschberg, que usa memoria lineal en vez de una tabla n·m. Si
+// aun así el trabajo supera maxLCSWork el medio queda sin emparejar (las capas
+// iguales se detectan después como movidas)
+func lcs(a []string, b []string) [][2]int {
+	start := 0
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		start++
+	}
+	endA, endB := len(a), len(b)
+	for endA > start && endB > start && a[endA-1] == b[endB-1] {
+		endA--
+		endB--
+	}
+
+	pairs := make([][2]int, 0, start+len(a)-endA)
+	for i := 0; i < start; i++ {
+		pairs = append(pairs, [2]int{i, i})
+	}
+
+	if (endA-start)*(endB-start) <= maxLCSWork {
+		// Se comparan números en vez de textos
+		ids := map[string]int{}
+		x := make([]int, endA-start)
+		for i := range x {
+			x[i] = keyID(ids, a[start+i])
+		}
+		y := make([]int, endB-start)
+		for j := range y {
+			y[j] = keyID(ids, b[start+j])
+		}
+
+		for _, pair := range hirschberg(x, y, 0, len(x), 0, len(y), nil) {
+			pairs = append(pairs, [2]int{start + pair[0], start + pair[1]})
+		}
+	}
+
+	for i, j := endA, endB; i < len(a); i, j = i+1, j+1 {
+		pairs = append(pairs, [2]int{i, j})
+	}
+
+	return pairs
+}
+
+// maxLCSWork limita las comparaciones de lcs (capas distintas de un estado por
+// capas distintas del otro)
+const maxLCSWork = 25000000
+
+func keyID(ids map[string]int, key string) int {
+	id, ok := ids[key]
+	if !ok {
+		id = len(ids)
+		ids[key] = id
+	}
+	return id
+}
+
+// hirschberg agrega en orden los pares de a[i0:i1] y b[j0:j1]
+func hirschberg(a []int, b []int, i0, i1, j0, j1 int, pairs [][2]int) [][2]int {
+	if i0 == i1 || j0 == j1 {
+		return pairs
+	}
+
+	if i1-i0 == 1 {
+		for j := j0; j < j1; j++ {
+			if a[i0] == b[j] {
+				return append(pairs, [2]int{i0, j})
+			}
+		}
+		return pairs
+	}
+
+	// Se parte a por la mitad y se busca dónde partir b para que la suma de
+	// ambas mitades sea máxima
+	mid := (i0 + i1) / 2
+	top := lcsLengths(a, b, i0, mid, j0, j1, false)
+	bottom := lcsLengths(a, b, mid, i1, j0, j1, true)
+
+	m := j1 - j0
+	best, split := -1, j0
+	for k := 0; k <= m; k++ {
+		if v := top[k] + bottom[m-k]; v > best {
+			best, split = v, j0+k
+		}
+	}
+
+	pairs = hirschberg(a, b, i0, mid, j0, split, pairs)
+	return hirschberg(a, b, mid, i1, split, j1, pairs)
+}
+
+// lcsLengths devuelve, para cada k, el largo de la subsecuencia común entre
+// a[i0:i1] y los primeros k elementos de b[j0:j1], o entre ambos al revés y
+// los últimos k si reverse
+func lcsLengths(a []int, b []int, i0, i1, j0, j1 int, reverse bool) []int {
+	m := j1 - j0
+	prev := make([]int, m+1)
+	cur := make([]int, m+1)
+
+	for x := 0; x < i1-i0; x++ {
+		ai := a[i0+x]
+		if reverse {
+			ai = a[i1-1-x]
+		}
+
+		for k := 1; k <= m; k++ {
+			bj := b[j0+k-1]
+			if reverse {
+				bj = b[j1-k]
+			}
+
+			switch {
+			case ai == bj:
+				cur[k] = prev[k-1] + 1
+			case prev[k] >= cur[k-1]:
+				cur[k] = prev[k]
+			default:
+				cur[k] = cur[k-1]
+			}
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev
+}
+
+// layerKey identifica el contenido de una capa. Las celdas vacías no cuentan y
+// json ordena las llaves de los mapas
+func layerKey(row models.DataInfo) string {
+	columns := make(map[string]string, len(row.Columns))
+	for k, v := range row.Columns {
+		if text := cellText(v); text != "" {
+			columns[k] = text
+		}
+	}
+
+	b, err := json.Marshal(struct {
+		Columns   map[string]string
+		Litologia models.LitologiaStruc
+	}{columns, row.Litologia})
+	if err != nil {
+		return fmt.Sprint(row)
+	}
+	return string(b)
+}
+
+func cellText(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+func indexOf(list []string, value string) int {
+	for i, v := range list {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,259 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// naiveLCS devuelve el largo de la subsecuencia común más larga con la tabla n·m
+func naiveLCS(a []string, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			switch {
+			case a[i-1] == b[j-1]:
+				table[i][j] = table[i-1][j-1] + 1
+			case table[i-1][j] >= table[i][j-1]:
+				table[i][j] = table[i-1][j]
+			default:
+				table[i][j] = table[i][j-1]
+			}
+		}
+	}
+	return table[len(a)][len(b)]
+}
+
+// checkPairs verifica que los pares sean una subsecuencia común válida
+func checkPairs(t *testing.T, a []string, b []string, pairs [][2]int) {
+	t.Helper()
+
+	for n, p := range pairs {
+		if p[0] < 0 || p[0] >= len(a) || p[1] < 0 || p[1] >= len(b) {
+			t.Fatalf("pair %v out of range", p)
+		}
+		if a[p[0]] != b[p[1]] {
+			t.Fatalf("pair %v joins %q and %q", p, a[p[0]], b[p[1]])
+		}
+		if n > 0 && (p[0] <= pairs[n-1][0] || p[1] <= pairs[n-1][1]) {
+			t.Fatalf("pairs are not increasing: %v", pairs)
+		}
+	}
+}
+
+func randomKeys(r *rand.Rand, n int, alphabet int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprint(r.Intn(alphabet))
+	}
+	return keys
+}
+
+func TestLCS(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+	}{
+		{"empty", nil, nil},
+		{"one empty", []string{"a", "b"}, nil},
+		{"equal", []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{"prefix and suffix", []string{"a", "x", "y", "c"}, []string{"a", "y", "z", "c"}},
+		{"reversed", []string{"a", "b", "c", "d"}, []string{"d", "c", "b", "a"}},
+		{"repeated", []string{"a", "a", "b", "a"}, []string{"b", "a", "a", "a", "b"}},
+		{"disjoint", []string{"a", "b"}, []string{"c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := lcs(tt.a, tt.b)
+			checkPairs(t, tt.a, tt.b, pairs)
+			if want := naiveLCS(tt.a, tt.b); len(pairs) != want {
+				t.Errorf("len(lcs) = %d, want %d (%v)", len(pairs), want, pairs)
+			}
+		})
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 300; n++ {
+		a := randomKeys(r, r.Intn(40), 1+r.Intn(6))
+		b := randomKeys(r, r.Intn(40), 1+r.Intn(6))
+
+		pairs := lcs(a, b)
+		checkPairs(t, a, b, pairs)
+		if want := naiveLCS(a, b); len(pairs) != want {
+			t.Fatalf("lcs(%v, %v) has %d pairs, want %d", a, b, len(pairs), want)
+		}
+	}
+}
+
+func TestHirschberg(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for n := 0; n < 300; n++ {
+		a := randomKeys(r, r.Intn(30), 1+r.Intn(4))
+		b := randomKeys(r, r.Intn(30), 1+r.Intn(4))
+
+		ids := map[string]int{}
+		x := make([]int, len(a))
+		for i := range a {
+			x[i] = keyID(ids, a[i])
+		}
+		y := make([]int, len(b))
+		for j := range b {
+			y[j] = keyID(ids, b[j])
+		}
+
+		pairs := hirschberg(x, y, 0, len(x), 0, len(y), nil)
+		checkPairs(t, a, b, pairs)
+		if want := naiveLCS(a, b); len(pairs) != want {
+			t.Fatalf("hirschberg(%v, %v) has %d pairs, want %d", a, b, len(pairs), want)
+		}
+	}
+}
+
+func TestLCSWorkLimit(t *testing.T) {
+	// Sin principio ni final comunes y por encima de maxLCSWork el medio queda sin emparejar
+	n := 5001
+	a := make([]string, n)
+	b := make([]string, n+1)
+	for i := range a {
+		a[i] = fmt.Sprint(i)
+		b[i+1] = fmt.Sprint(i)
+	}
+	b[0] = "x"
+	a[n-1] = "y"
+
+	if pairs := lcs(a, b); len(pairs) != 0 {
+		t.Errorf("len(lcs) = %d, want 0 above the work limit", len(pairs))
+	}
+}
+
+func layer(notes string, height float32) models.DataInfo {
+	row := models.NewShape()
+	row.Columns["Notas"] = notes
+	row.Columns["Edad"] = "Jurásico"
+	row.Litologia.Height = height
+	return row
+}
+
+// summarize describe cada cambio como "tipo from->to"
+func summarize(changes []LayerChange) []string {
+	var out []string
+	for _, c := range changes {
+		from, to := "-", "-"
+		if c.From != nil {
+			from = fmt.Sprint(*c.From)
+		}
+		if c.To != nil {
+			to = fmt.Sprint(*c.To)
+		}
+		out = append(out, fmt.Sprintf("%s %s->%s", c.Type, from, to))
+	}
+	return out
+}
+
+func TestCompareLayers(t *testing.T) {
+	x, y, z := layer("x", 10), layer("y", 20), layer("z", 30)
+
+	tests := []struct {
+		name string
+		a, b []models.DataInfo
+		want []string
+	}{
+		{"equal", []models.DataInfo{x, y, z}, []models.DataInfo{x, y, z}, nil},
+		{"moved", []models.DataInfo{x, y, z}, []models.DataInfo{y, z, x}, []string{"moved 0->2"}},
+		{"changed", []models.DataInfo{x, y, z}, []models.DataInfo{x, layer("y2", 20), z}, []string{"changed 1->1"}},
+		{"changed height", []models.DataInfo{x, y, z}, []models.DataInfo{x, y, layer("z", 35)}, []string{"changed 2->2"}},
+		{"moved and changed", []models.DataInfo{x, y, z}, []models.DataInfo{layer("z", 35), x, y}, []string{"changed 2->0"}},
+		{"added", []models.DataInfo{x, z}, []models.DataInfo{x, y, z}, []string{"added -->1"}},
+		{"removed", []models.DataInfo{x, y, z}, []models.DataInfo{x, z}, []string{"removed 1->-"}},
+		{"replaced", []models.DataInfo{x}, []models.DataInfo{func() models.DataInfo {
+			row := models.NewShape()
+			row.Columns["Otra"] = "capa"
+			row.Litologia.File = "caliza"
+			row.Litologia.ColorFill = "#000000"
+			row.Litologia.Height = 5
+			row.Litologia.Contact = "otro"
+			row.Litologia.Zoom = 3
+			return row
+		}()}, []string{"removed 0->-", "added -->0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarize(compareLayers(tt.a, tt.b))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareLayers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareLayersCells(t *testing.T) {
+	a := []models.DataInfo{layer("x", 10), layer("y", 20)}
+	b := []models.DataInfo{layer("y", 20), layer("x editada", 10)}
+
+	changes := compareLayers(a, b)
+	if len(changes) != 1 || changes[0].Type != Changed {
+		t.Fatalf("compareLayers() = %v, want one changed layer", summarize(changes))
+	}
+
+	want := []FieldChange{{Field: "Notas", Old: "x", New: "x editada"}}
+	if !reflect.DeepEqual(changes[0].Cells, want) {
+		t.Errorf("cells = %+v, want %+v", changes[0].Cells, want)
+	}
+	if len(changes[0].Litologia) != 0 || len(changes[0].Circles) != 0 {
+		t.Errorf("unexpected litologia changes: %+v %+v", changes[0].Litologia, changes[0].Circles)
+	}
+}
+
+func withID(row models.DataInfo, id string) models.DataInfo {
+	row.ID = id
+	return row
+}
+
+func TestCompareLayersByID(t *testing.T) {
+	x, y, z := withID(layer("x", 10), "x"), withID(layer("y", 20), "y"), withID(layer("z", 30), "z")
+	w := withID(layer("w", 40), "w")
+
+	// Más de la mitad de los campos cambian: sin el ID sería eliminada y agregada
+	rewritten := withID(models.NewShape(), "y")
+	rewritten.Columns["Notas"] = "otra"
+	rewritten.Columns["Edad"] = "Cretácico"
+	rewritten.Litologia.Height = 45
+	rewritten.Litologia.File = "caliza"
+	rewritten.Litologia.ColorFill = "#00ff00"
+	rewritten.Litologia.Contact = "erosivo"
+	rewritten.Litologia.Zoom = 50
+	rewritten.Litologia.Rotation = 90
+
+	same := layer("igual", 10)
+
+	tests := []struct {
+		name string
+		a, b []models.DataInfo
+		want []string
+	}{
+		{"heavily edited", []models.DataInfo{x, y, z}, []models.DataInfo{x, rewritten, z}, []string{"changed 1->1"}},
+		{"heavily edited and moved", []models.DataInfo{x, y, z, w}, []models.DataInfo{x, z, w, rewritten}, []string{"changed 1->3"}},
+		{"heavily edited without IDs", []models.DataInfo{withID(y, "")}, []models.DataInfo{withID(rewritten, "")}, []string{"removed 0->-", "added -->0"}},
+		{"identical layers swapped", []models.DataInfo{withID(same, "p"), withID(same, "q")}, []models.DataInfo{withID(same, "q"), withID(same, "p")}, []string{"moved 0->1"}},
+		{"identical layers kept", []models.DataInfo{withID(same, "p"), withID(same, "q")}, []models.DataInfo{withID(same, "p"), withID(same, "q")}, nil},
+		{"new ID is added", []models.DataInfo{x}, []models.DataInfo{x, withID(same, "n")}, []string{"added -->1"}},
+		{"repeated ID falls back to content", []models.DataInfo{x, withID(y, "x")}, []models.DataInfo{withID(y, "x"), x}, []string{"moved 0->1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarize(compareLayers(tt.a, tt.b))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareLayers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
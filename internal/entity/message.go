@@ -1,7 +1,16 @@
 package entity
 
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Message es un mensaje del chat de una sala, guardado en la colección messages
 type Message struct {
-	ID      string `bson:"_id,omitempty"`
-	Room    string `bson:"room"`
-	Message string `bson:"message"`
-}
\ No newline at end of file
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Room      string             `bson:"room" json:"room"`
+	Author    string             `bson:"author" json:"author"`
+	Message   string             `bson:"message" json:"message"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
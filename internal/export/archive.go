@@ -0,0 +1,58 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// ArchiveEntry es el nombre del JSON dentro del respaldo comprimido
+const ArchiveEntry = "project.json"
+
+// NewArchive arma el respaldo del proyecto. Los miembros no se incluyen: al
+// importarlo el proyecto queda a cargo de quien lo importa
+func NewArchive(p *models.Project) models.Archive {
+	info := p.ProjectInfo
+	info.Members = models.Members{}
+
+	return models.Archive{
+		Format:     models.ArchiveFormat,
+		Version:    models.ArchiveVersion,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		SourceID:   p.ID.Hex(),
+		Project: models.ArchiveProject{
+			ProjectInfo: info,
+			Data:        p.Data,
+			Config:      p.Config,
+			Fosil:       p.Fosil,
+			Facies:      p.Facies,
+			Muestras:    p.Muestras,
+			Logs:        p.Logs,
+		},
+	}
+}
+
+// WriteArchive escribe el respaldo del proyecto como JSON
+func WriteArchive(w io.Writer, p *models.Project) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(NewArchive(p))
+}
+
+// WriteArchiveZip escribe el respaldo del proyecto como un zip con un único project.json
+func WriteArchiveZip(w io.Writer, p *models.Project) error {
+	zw := zip.NewWriter(w)
+
+	f, err := zw.Create(ArchiveEntry)
+	if err != nil {
+		return err
+	}
+	if err := WriteArchive(f, p); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,169 @@
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// Placemark es la ubicación de un proyecto con los datos que se muestran en el mapa
+type Placemark struct {
+	ID          string
+	Name        string
+	Description string
+	Owner       string
+	Location    string
+	Link        string
+	Lat         float64
+	Long        float64
+}
+
+// Placemarks arma una marca por proyecto. Los proyectos sin coordenadas
+// (lat y long en 0) se omiten. link devuelve el enlace a la sala del proyecto
+func Placemarks(projects []models.InfoProject, link func(id string) string) []Placemark {
+	marks := make([]Placemark, 0, len(projects))
+
+	for _, p := range projects {
+		info := p.ProjectInfo
+		if info.Lat == 0 && info.Long == 0 {
+			continue
+		}
+
+		id := p.ID.Hex()
+		marks = append(marks, Placemark{
+			ID:          id,
+			Name:        info.Name,
+			Description: info.Description,
+			Owner:       info.Owner,
+			Location:    info.Location,
+			Link:        link(id),
+			Lat:         info.Lat,
+			Long:        info.Long,
+		})
+	}
+
+	return marks
+}
+
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Geometry   geoJSONPoint           `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// WriteGeoJSON escribe las marcas como FeatureCollection de puntos (RFC 7946, lon/lat WGS84)
+func WriteGeoJSON(w io.Writer, marks []Placemark) error {
+	collection := geoJSONCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+
+	for _, m := range marks {
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:     "Feature",
+			ID:       m.ID,
+			Geometry: geoJSONPoint{Type: "Point", Coordinates: [2]float64{m.Long, m.Lat}},
+			Properties: map[string]interface{}{
+				"name":        m.Name,
+				"description": m.Description,
+				"owner":       m.Owner,
+				"location":    m.Location,
+				"link":        m.Link,
+			},
+		})
+	}
+
+	return json.NewEncoder(w).Encode(collection)
+}
+
+type kmlRoot struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	ID          string          `xml:"id,attr"`
+	Name        string          `xml:"name"`
+	Description string          `xml:"description"`
+	Link        kmlLink         `xml:"http://www.w3.org/2005/Atom link"`
+	Data        kmlExtendedData `xml:"ExtendedData"`
+	Point       kmlPoint        `xml:"Point"`
+}
+
+type kmlLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type kmlExtendedData struct {
+	Data []kmlData `xml:"Data"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// WriteKML escribe las marcas como documento KML 2.2
+func WriteKML(w io.Writer, name string, marks []Placemark) error {
+	root := kmlRoot{
+		Xmlns:    "http://www.opengis.net/kml/2.2",
+		Document: kmlDocument{Name: name},
+	}
+
+	for _, m := range marks {
+		// La descripción se muestra como HTML en Google Earth; el encoder se encarga de escaparla
+		description := fmt.Sprintf("<p>%s</p><p>%s</p><p><a href=\"%s\">Abrir proyecto</a></p>",
+			esc(m.Description), esc(m.Owner), esc(m.Link))
+
+		root.Document.Placemarks = append(root.Document.Placemarks, kmlPlacemark{
+			ID:          m.ID,
+			Name:        m.Name,
+			Description: description,
+			Link:        kmlLink{Href: m.Link},
+			Data: kmlExtendedData{Data: []kmlData{
+				{Name: "owner", Value: m.Owner},
+				{Name: "location", Value: m.Location},
+				{Name: "link", Value: m.Link},
+			}},
+			Point: kmlPoint{Coordinates: fmt.Sprintf("%s,%s,0", formatCoord(m.Long), formatCoord(m.Lat))},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func formatCoord(v float64) string {
+	return fmt.Sprintf("%.6f", v)
+}
@@ -0,0 +1,163 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// geoProjects devuelve un proyecto ubicado y otro sin coordenadas
+func geoProjects() []models.InfoProject {
+	return []models.InfoProject{
+		{ID: primitive.NewObjectID(), ProjectInfo: models.ProjectInfo{
+			Name:        "Quebrada",
+			Description: "Sección <tipo>",
+			Owner:       "Ana",
+			Location:    "Atacama",
+			Lat:         -27.366,
+			Long:        -70.3322,
+		}},
+		{ID: primitive.NewObjectID(), ProjectInfo: models.ProjectInfo{Name: "Sin ubicar"}},
+	}
+}
+
+func geoLink(id string) string {
+	return "https://example.com/rooms/" + id
+}
+
+func TestPlacemarksSkipsProjectsWithoutCoordinates(t *testing.T) {
+	projects := geoProjects()
+	marks := Placemarks(projects[1:], geoLink)
+	if len(marks) != 0 {
+		t.Fatalf("marks = %+v, want none", marks)
+	}
+
+	marks = Placemarks(projects, geoLink)
+	if len(marks) != 1 || marks[0].ID != projects[0].ID.Hex() {
+		t.Fatalf("marks = %+v, want only the located project", marks)
+	}
+}
+
+func TestWriteGeoJSON(t *testing.T) {
+	projects := geoProjects()
+	id := projects[0].ID.Hex()
+
+	var buf bytes.Buffer
+	if err := WriteGeoJSON(&buf, Placemarks(projects, geoLink)); err != nil {
+		t.Fatal(err)
+	}
+
+	var collection struct {
+		Type     string
+		Features []struct {
+			Type     string
+			ID       string
+			Geometry struct {
+				Type        string
+				Coordinates []float64
+			}
+			Properties map[string]string
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatalf("invalid GeoJSON: %v", err)
+	}
+
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 1 {
+		t.Fatalf("collection = %+v, want one feature", collection)
+	}
+	feature := collection.Features[0]
+	if feature.Type != "Feature" || feature.ID != id || feature.Geometry.Type != "Point" {
+		t.Errorf("feature = %+v", feature)
+	}
+	// GeoJSON usa el orden longitud, latitud
+	if want := []float64{-70.3322, -27.366}; !reflect.DeepEqual(feature.Geometry.Coordinates, want) {
+		t.Errorf("coordinates = %v, want %v", feature.Geometry.Coordinates, want)
+	}
+	want := map[string]string{
+		"name":        "Quebrada",
+		"description": "Sección <tipo>",
+		"owner":       "Ana",
+		"location":    "Atacama",
+		"link":        geoLink(id),
+	}
+	if !reflect.DeepEqual(feature.Properties, want) {
+		t.Errorf("properties = %v, want %v", feature.Properties, want)
+	}
+}
+
+func TestWriteGeoJSONEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGeoJSON(&buf, Placemarks(geoProjects()[1:], geoLink)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sin marcas se escribe una colección vacía, no null
+	var collection map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatalf("invalid GeoJSON: %v", err)
+	}
+	features, ok := collection["features"].([]interface{})
+	if !ok || len(features) != 0 {
+		t.Errorf("features = %v, want an empty list", collection["features"])
+	}
+}
+
+func TestWriteKML(t *testing.T) {
+	projects := geoProjects()
+	id := projects[0].ID.Hex()
+
+	var buf bytes.Buffer
+	if err := WriteKML(&buf, "Proyectos", Placemarks(projects, geoLink)); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc kmlRoot
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid KML: %v", err)
+	}
+
+	if doc.Document.Name != "Proyectos" || len(doc.Document.Placemarks) != 1 {
+		t.Fatalf("document = %+v, want one placemark", doc.Document)
+	}
+	mark := doc.Document.Placemarks[0]
+	if mark.ID != id || mark.Name != "Quebrada" {
+		t.Errorf("placemark = %s %q", mark.ID, mark.Name)
+	}
+	// KML usa longitud,latitud,altura
+	if want := "-70.332200,-27.366000,0"; mark.Point.Coordinates != want {
+		t.Errorf("coordinates = %q, want %q", mark.Point.Coordinates, want)
+	}
+	if mark.Link.Href != geoLink(id) {
+		t.Errorf("link = %q, want %q", mark.Link.Href, geoLink(id))
+	}
+
+	data := map[string]string{}
+	for _, d := range mark.Data.Data {
+		data[d.Name] = d.Value
+	}
+	want := map[string]string{"owner": "Ana", "location": "Atacama", "link": geoLink(id)}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("extended data = %v, want %v", data, want)
+	}
+}
+
+func TestWriteKMLEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteKML(&buf, "Proyectos", Placemarks(geoProjects()[1:], geoLink)); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc kmlRoot
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid KML: %v", err)
+	}
+	if len(doc.Document.Placemarks) != 0 {
+		t.Errorf("placemarks = %+v, want none", doc.Document.Placemarks)
+	}
+}
@@ -0,0 +1,256 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// LASNull es el valor nulo que se escribe en los archivos LAS
+const LASNull = -999.25
+
+// Límites del muestreo: sin ellos un paso muy chico genera un archivo enorme
+const (
+	MinLASStep  = 0.001
+	maxLASSteps = 1000000
+)
+
+// LASOptions controla el muestreo de la exportación LAS
+type LASOptions struct {
+	Step float64 // intervalo de muestreo en unidades del proyecto, 1 por defecto
+	Unit string  // unidad de profundidad, M por defecto
+}
+
+// lasCurve es una curva discreta: un valor por capa y su leyenda si es codificada
+type lasCurve struct {
+	mnemonic    string
+	unit        string
+	description string
+	value       func(layer int, rel float64) float64
+	legend      []string
+}
+
+// WriteLAS escribe el proyecto como LAS 2.0. Cada capa se vuelve un intervalo
+// de profundidad (desde la primera capa de Data, según Litologia.Height) y sus
+// atributos curvas discretas. Los textos se codifican y su leyenda va en ~Other
+func WriteLAS(w io.Writer, p *models.Project, opts LASOptions) error {
+	if opts.Step <= 0 {
+		opts.Step = 1
+	}
+	if opts.Unit == "" {
+		opts.Unit = "M"
+	}
+
+	tops, bases := Depths(p)
+	total := TotalHeight(p)
+
+	steps := math.Floor(total/opts.Step + 1e-9)
+	if steps > maxLASSteps {
+		return ErrTooLarge
+	}
+	curves := lasCurves(p, opts.Unit)
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "~Version Information\n")
+	fmt.Fprintf(bw, " VERS.                 2.0 : CWLS LOG ASCII STANDARD - VERSION 2.0\n")
+	fmt.Fprintf(bw, " WRAP.                  NO : ONE LINE PER DEPTH STEP\n")
+
+	fmt.Fprintf(bw, "~Well Information\n")
+	fmt.Fprintf(bw, " STRT.%-4s %14.4f : START DEPTH\n", opts.Unit, 0.0)
+	fmt.Fprintf(bw, " STOP.%-4s %14.4f : STOP DEPTH\n", opts.Unit, total)
+	fmt.Fprintf(bw, " STEP.%-4s %14.4f : STEP\n", opts.Unit, opts.Step)
+	fmt.Fprintf(bw, " NULL.     %14.2f : NULL VALUE\n", LASNull)
+	fmt.Fprintf(bw, " WELL.     %14s : WELL\n", lasText(p.ProjectInfo.Name))
+	fmt.Fprintf(bw, " LOC.      %14s : LOCATION\n", lasText(p.ProjectInfo.Location))
+	fmt.Fprintf(bw, " LATI.DEG  %14.6f : LATITUDE\n", p.ProjectInfo.Lat)
+	fmt.Fprintf(bw, " LONG.DEG  %14.6f : LONGITUDE\n", p.ProjectInfo.Long)
+	fmt.Fprintf(bw, " SRVC.     %14s : SERVICE COMPANY\n", lasText(p.ProjectInfo.Owner))
+	fmt.Fprintf(bw, " DATE.     %14s : LOG DATE\n", time.Now().Format("2006-01-02"))
+
+	fmt.Fprintf(bw, "~Curve Information\n")
+	fmt.Fprintf(bw, " DEPT.%-4s              : DEPTH\n", opts.Unit)
+	for _, c := range curves {
+		fmt.Fprintf(bw, " %s.%-4s              : %s\n", c.mnemonic, c.unit, lasText(c.description))
+	}
+
+	fmt.Fprintf(bw, "~Other\n")
+	for _, c := range curves {
+		for i, value := range c.legend {
+			fmt.Fprintf(bw, " %s %d = %s\n", c.mnemonic, i+1, lasText(value))
+		}
+	}
+
+	fmt.Fprintf(bw, "~A  DEPT")
+	for _, c := range curves {
+		fmt.Fprintf(bw, " %11s", c.mnemonic)
+	}
+	fmt.Fprintf(bw, "\n")
+
+	layer := 0
+	for n := 0; n <= int(steps); n++ {
+		depth := float64(n) * opts.Step
+
+		// Se avanza a la capa que contiene la profundidad; en un contacto manda la capa inferior
+		for layer < len(p.Data)-1 && depth >= bases[layer] {
+			layer++
+		}
+
+		fmt.Fprintf(bw, "%10.4f", depth)
+		for _, c := range curves {
+			value := LASNull
+			if len(p.Data) > 0 {
+				h := bases[layer] - tops[layer]
+				rel := 0.0
+				if h > 0 {
+					rel = math.Min(1, (depth-tops[layer])/h)
+				}
+				value = c.value(layer, rel)
+			}
+			fmt.Fprintf(bw, " %11.4f", value)
+		}
+		fmt.Fprintf(bw, "\n")
+	}
+
+	return bw.Flush()
+}
+
+// lasCurves arma las curvas discretas del proyecto
+func lasCurves(p *models.Project, unit string) []lasCurve {
+	curves := []lasCurve{
+		{
+			mnemonic:    "LAYER",
+			description: "LAYER NUMBER",
+			value:       func(layer int, _ float64) float64 { return float64(layer + 1) },
+		},
+		{
+			mnemonic:    "THK",
+			unit:        unit,
+			description: "LAYER THICKNESS",
+			value:       func(layer int, _ float64) float64 { return f32(p.Data[layer].Litologia.Height) },
+		},
+		{
+			mnemonic:    "GRAIN",
+			description: "GRAIN SIZE PROFILE (0-1)",
+			value: func(layer int, rel float64) float64 {
+				return grainSize(p.Data[layer].Litologia, rel)
+			},
+		},
+	}
+
+	curves = append(curves, codedCurve("LITH", "LITHOLOGY", p, func(row models.DataInfo) string { return row.Litologia.File }))
+
+	used := map[string]bool{"DEPT": true, "LAYER": true, "THK": true, "GRAIN": true, "LITH": true}
+	for _, col := range p.Config.Columns {
+		switch col.Name {
+		case ColumnEspesor, ColumnLitologia, ColumnFosil, ColumnFacie, ColumnMuestras:
+			continue
+		}
+
+		name := col.Name
+		curve := codedCurve(lasMnemonic(name, used), name, p, func(row models.DataInfo) string { return CellText(row, name) })
+		if len(curve.legend) > 0 {
+			curves = append(curves, curve)
+		}
+	}
+
+	return curves
+}
+
+// codedCurve codifica los valores de texto de cada capa como 1..n (0 si está vacío)
+func codedCurve(mnemonic string, description string, p *models.Project, text func(models.DataInfo) string) lasCurve {
+	codes := map[string]int{}
+	var legend []string
+
+	for _, row := range p.Data {
+		value := strings.TrimSpace(text(row))
+		if value == "" {
+			continue
+		}
+		if _, ok := codes[value]; !ok {
+			legend = append(legend, value)
+			codes[value] = len(legend)
+		}
+	}
+
+	return lasCurve{
+		mnemonic:    mnemonic,
+		description: description,
+		legend:      legend,
+		value: func(layer int, _ float64) float64 {
+			return float64(codes[strings.TrimSpace(text(p.Data[layer]))])
+		},
+	}
+}
+
+// grainSize interpola el borde derecho del polígono (los puntos movibles) a
+// la altura relativa rel de la capa
+func grainSize(lit models.LitologiaStruc, rel float64) float64 {
+	var points []models.CircleStruc
+	for _, c := range lit.Circles {
+		if c.Movable {
+			points = append(points, c)
+		}
+	}
+	if len(points) == 0 {
+		return LASNull
+	}
+
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Y < points[j].Y })
+
+	if rel <= float64(points[0].Y) {
+		return f32(points[0].X)
+	}
+	for i := 1; i < len(points); i++ {
+		y0, y1 := float64(points[i-1].Y), float64(points[i].Y)
+		if rel <= y1 {
+			if y1 == y0 {
+				return f32(points[i].X)
+			}
+			t := (rel - y0) / (y1 - y0)
+			return f32(points[i-1].X) + t*(f32(points[i].X)-f32(points[i-1].X))
+		}
+	}
+	return f32(points[len(points)-1].X)
+}
+
+// lasMnemonic arma un mnemónico único en mayúsculas y sin caracteres especiales
+func lasMnemonic(name string, used map[string]bool) string {
+	base := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return -1
+		}
+	}, strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ñ", "n").Replace(strings.ToLower(name)))
+
+	if len(base) > 8 {
+		base = base[:8]
+	}
+	if base == "" {
+		base = "COL"
+	}
+
+	mnemonic := base
+	for i := 2; used[mnemonic]; i++ {
+		mnemonic = fmt.Sprintf("%s%d", base, i)
+	}
+	used[mnemonic] = true
+
+	return mnemonic
+}
+
+// lasText limpia un texto para que no rompa el formato de línea de LAS
+func lasText(s string) string {
+	s = strings.NewReplacer("\n", " ", "\r", " ", ":", " ", "~", "-").Replace(s)
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,64 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteLASSteps(t *testing.T) {
+	tests := []struct {
+		name    string
+		heights []float32
+		step    float64
+		samples int
+		err     error
+	}{
+		{"default step", []float32{2, 3}, 0, 6, nil},
+		{"half step", []float32{2, 3}, 0.5, 11, nil},
+		{"empty project", nil, 1, 1, nil},
+		{"step larger than the profile", []float32{2}, 10, 1, nil},
+		{"too many steps", []float32{2000}, MinLASStep, 0, ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteLAS(&buf, testProject(tt.heights...), LASOptions{Step: tt.step})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("WriteLAS() = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			_, data, ok := strings.Cut(buf.String(), "~A")
+			if !ok {
+				t.Fatal("missing ~A section")
+			}
+			// La primera línea de ~A son los nombres de las curvas
+			lines := strings.Split(strings.TrimSpace(data), "\n")
+			if got := len(lines) - 1; got != tt.samples {
+				t.Errorf("samples = %d, want %d", got, tt.samples)
+			}
+		})
+	}
+}
+
+func TestWriteLASNull(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteLAS(&buf, testProject(), LASOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sin capas todas las curvas quedan en el valor nulo
+	_, data, _ := strings.Cut(buf.String(), "~A")
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	for _, f := range fields[1:] {
+		if f != "-999.2500" {
+			t.Errorf("value = %s, want the null value", f)
+		}
+	}
+}
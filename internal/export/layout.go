@@ -0,0 +1,207 @@
+package export
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// Columnas de la tabla que no son texto libre sino pistas dibujadas
+const (
+	ColumnEspesor   = "Espesor"
+	ColumnLitologia = "Litologia"
+	ColumnFosil     = "Estructura fosil"
+	ColumnFacie     = "Facie"
+	ColumnMuestras  = "Muestras"
+)
+
+// Layer es una capa ya ubicada verticalmente, en el orden en que se dibuja
+type Layer struct {
+	Index   int     // posición de la capa en Project.Data
+	Top     float64 // borde superior en unidades del proyecto
+	Base    float64 // borde inferior en unidades del proyecto
+	Flipped bool    // los puntos de la litología se dibujan de abajo hacia arriba
+	Data    models.DataInfo
+}
+
+// Height devuelve el espesor de la capa
+func (l Layer) Height() float64 {
+	return l.Base - l.Top
+}
+
+// Layers ubica cada capa a partir de Litologia.Height. Si el proyecto está
+// invertido la primera capa queda abajo, igual que en el editor
+func Layers(p *models.Project) []Layer {
+	layers := make([]Layer, 0, len(p.Data))
+
+	order := make([]int, len(p.Data))
+	for i := range p.Data {
+		order[i] = i
+		if p.Config.IsInverted {
+			order[i] = len(p.Data) - 1 - i
+		}
+	}
+
+	var y float64
+	for _, i := range order {
+		h := float64(p.Data[i].Litologia.Height)
+		layers = append(layers, Layer{Index: i, Top: y, Base: y + h, Flipped: p.Config.IsInverted, Data: p.Data[i]})
+		y += h
+	}
+
+	return layers
+}
+
+// Depths devuelve techo y base de cada capa medidos desde la primera capa de
+// Data, sin importar la orientación en que se dibuja
+func Depths(p *models.Project) (tops []float64, bases []float64) {
+	tops = make([]float64, len(p.Data))
+	bases = make([]float64, len(p.Data))
+
+	var y float64
+	for i, row := range p.Data {
+		tops[i] = y
+		y += f32(row.Litologia.Height)
+		bases[i] = y
+	}
+
+	return tops, bases
+}
+
+// TotalHeight devuelve la suma de los espesores de todas las capas
+func TotalHeight(p *models.Project) float64 {
+	var h float64
+	for _, row := range p.Data {
+		h += float64(row.Litologia.Height)
+	}
+	return h
+}
+
+// VisibleColumns devuelve las columnas visibles en el orden de Config.Columns
+func VisibleColumns(cfg models.Config) []models.Column {
+	columns := make([]models.Column, 0, len(cfg.Columns))
+	for _, col := range cfg.Columns {
+		if col.Visible {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+// CellText devuelve el contenido de una columna de texto de la capa
+func CellText(row models.DataInfo, column string) string {
+	if column == ColumnEspesor {
+		return formatNumber(float64(row.Litologia.Height))
+	}
+
+	value, ok := row.Columns[column]
+	if !ok || value == nil {
+		return ""
+	}
+
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(value)
+}
+
+// SortedFosils devuelve los fósiles ordenados por id para que la salida sea estable
+func SortedFosils(p *models.Project) []string {
+	ids := make([]string, 0, len(p.Fosil))
+	for id := range p.Fosil {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SortedMuestras devuelve las muestras ordenadas por id
+func SortedMuestras(p *models.Project) []string {
+	ids := make([]string, 0, len(p.Muestras))
+	for id := range p.Muestras {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SortedFacies devuelve los nombres de las facies ordenados
+func SortedFacies(p *models.Project) []string {
+	names := make([]string, 0, len(p.Facies))
+	for name := range p.Facies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// wrapText corta un texto en líneas de a lo más maxChars caracteres
+func wrapText(text string, maxChars int) []string {
+	if maxChars < 1 {
+		maxChars = 1
+	}
+
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			for len([]rune(word)) > maxChars {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				r := []rune(word)
+				lines = append(lines, string(r[:maxChars]))
+				word = string(r[maxChars:])
+			}
+
+			switch {
+			case line == "":
+				line = word
+			case len([]rune(line))+1+len([]rune(word)) <= maxChars:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// tickStep elige un intervalo "redondo" (1, 2 o 5 por potencia de 10) para la escala
+func tickStep(total float64, maxTicks int) float64 {
+	if total <= 0 || maxTicks < 1 {
+		return 1
+	}
+
+	raw := total / float64(maxTicks)
+	pow := math.Pow(10, math.Floor(math.Log10(raw)))
+
+	for _, m := range []float64{1, 2, 5, 10} {
+		if m*pow >= raw {
+			return m * pow
+		}
+	}
+	return 10 * pow
+}
+
+func formatNumber(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+}
+
+// f32 convierte sin arrastrar el ruido de float32 (0.1 y no 0.10000000149)
+func f32(v float32) float64 {
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+	return f
+}
@@ -0,0 +1,433 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/go-pdf/fpdf"
+)
+
+// PDFOptions controla el formato de la exportación a PDF
+type PDFOptions struct {
+	Scale     float64 // milímetros de papel por unidad de espesor del proyecto, 0.25 por defecto
+	PageSize  string  // A4, A3, Letter o Legal
+	Landscape bool
+}
+
+const (
+	pdfMargin       = 10.0
+	pdfTitleHeight  = 32.0
+	pdfHeaderHeight = 12.0
+	pdfFooterHeight = 8.0
+	pdfRulerWidth   = 14.0
+	pdfFontSize     = 7.0
+)
+
+// Límites de la exportación a PDF
+const (
+	MinPDFScale = 0.01 // milímetros por unidad
+	MaxPDFScale = 20   // milímetros por unidad
+	maxPDFPages = 300
+)
+
+// pdfPage guarda la geometría común a todas las páginas de una exportación
+type pdfPage struct {
+	pdf    *fpdf.Fpdf
+	tr     func(string) string
+	scale  float64
+	factor float64 // mm por pixel del ancho de columna
+	start  float64 // primera unidad del perfil visible en la página
+	top    float64 // borde superior de la zona de dibujo
+	height float64 // alto de la zona de dibujo
+}
+
+func (pg *pdfPage) y(v float64) float64 {
+	return pg.top + (v-pg.start)*pg.scale
+}
+
+func (pg *pdfPage) end() float64 {
+	return pg.start + pg.height/pg.scale
+}
+
+// PDF genera un documento imprimible con la tabla, la litología, los fósiles,
+// las muestras y las facies. Los perfiles largos se reparten en varias páginas
+// a la escala vertical indicada, repitiendo el encabezado de columnas
+func PDF(w io.Writer, p *models.Project, opts PDFOptions) error {
+	if opts.Scale <= 0 {
+		opts.Scale = 0.25
+	}
+	if opts.PageSize == "" {
+		opts.PageSize = "A4"
+	}
+
+	orientation := "P"
+	if opts.Landscape {
+		orientation = "L"
+	}
+
+	pdf := fpdf.New(orientation, "mm", opts.PageSize, "")
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle(p.ProjectInfo.Name, true)
+	pdf.SetCreator("StrataScope", true)
+	pdf.AliasNbPages("{nb}")
+	pdf.SetFont("Helvetica", "", pdfFontSize)
+
+	pageW, pageH := pdf.GetPageSize()
+
+	columns := VisibleColumns(p.Config)
+	var columnsPx float64
+	for _, col := range columns {
+		columnsPx += columnWidth(p, col)
+	}
+
+	pg := &pdfPage{
+		pdf:    pdf,
+		tr:     pdf.UnicodeTranslatorFromDescriptor(""),
+		scale:  opts.Scale,
+		factor: 0.26, // ~ 1px a 96 dpi
+	}
+	if columnsPx > 0 {
+		pg.factor = math.Min(pg.factor, (pageW-2*pdfMargin-pdfRulerWidth)/columnsPx)
+	}
+
+	layers := Layers(p)
+	total := TotalHeight(p)
+
+	for first := true; first || pg.start < total-1e-6; first = false {
+		if pdf.PageNo() >= maxPDFPages {
+			return ErrTooLarge
+		}
+		pdf.AddPage()
+
+		top := pdfMargin
+		if first {
+			writePDFTitle(pg, p, pageW)
+			top += pdfTitleHeight + 4
+		}
+
+		pg.top = top + pdfHeaderHeight
+		pg.height = pageH - pdfMargin - pdfFooterHeight - pg.top
+
+		writePDFHeaders(pg, p, columns, top)
+		writePDFRuler(pg, p, total)
+
+		x := pdfMargin + pdfRulerWidth
+		for _, col := range columns {
+			cw := columnWidth(p, col) * pg.factor
+
+			pdf.ClipRect(x, pg.top, cw, pg.height, false)
+			switch col.Name {
+			case ColumnLitologia:
+				writePDFLithology(pg, layers, x, cw)
+			case ColumnFosil:
+				writePDFFosils(pg, p, x, cw)
+			case ColumnMuestras:
+				writePDFMuestras(pg, p, x, cw)
+			case ColumnFacie:
+				writePDFFacies(pg, p, x, cw)
+			default:
+				writePDFTextColumn(pg, layers, col.Name, x, cw)
+			}
+			pdf.ClipEnd()
+
+			bottom := math.Min(pg.y(total), pg.top+pg.height)
+			pdf.SetDrawColor(0, 0, 0)
+			pdf.Rect(x, pg.top, cw, bottom-pg.top, "D")
+			x += cw
+		}
+
+		pdf.SetFont("Helvetica", "", pdfFontSize)
+		pdf.Text(pageW-pdfMargin-20, pageH-pdfMargin, pg.tr(fmt.Sprintf("Página %d de {nb}", pdf.PageNo())))
+
+		pg.start = pg.end()
+	}
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+
+	return pdf.Output(w)
+}
+
+func writePDFTitle(pg *pdfPage, p *models.Project, pageW float64) {
+	pdf := pg.pdf
+	info := p.ProjectInfo
+	w := pageW - 2*pdfMargin
+
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.Rect(pdfMargin, pdfMargin, w, pdfTitleHeight, "D")
+
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.Text(pdfMargin+3, pdfMargin+8, pg.tr(info.Name))
+
+	pdf.SetFont("Helvetica", "", 9)
+	lines := []string{
+		"Localidad: " + info.Location,
+		fmt.Sprintf("Latitud: %s   Longitud: %s", strconv.FormatFloat(info.Lat, 'f', 6, 64), strconv.FormatFloat(info.Long, 'f', 6, 64)),
+		"Autor: " + info.Owner,
+		"Fecha de creación: " + info.CreationDate,
+	}
+	for i, line := range lines {
+		pdf.Text(pdfMargin+3, pdfMargin+14+float64(i)*4.5, pg.tr(line))
+	}
+
+	pdf.Text(pdfMargin+w/2+3, pdfMargin+14, pg.tr(fmt.Sprintf("Escala vertical: 1 unidad = %s mm", formatNumber(pg.scale))))
+	pdf.Text(pdfMargin+w/2+3, pdfMargin+18.5, pg.tr(fmt.Sprintf("Espesor total: %s", formatNumber(TotalHeight(p)))))
+
+	pdf.SetFont("Helvetica", "", pdfFontSize)
+}
+
+func writePDFHeaders(pg *pdfPage, p *models.Project, columns []models.Column, top float64) {
+	pdf := pg.pdf
+
+	pdf.SetFont("Helvetica", "B", pdfFontSize)
+	pdf.SetFillColor(242, 242, 242)
+	pdf.SetDrawColor(0, 0, 0)
+
+	x := pdfMargin + pdfRulerWidth
+	for _, col := range columns {
+		cw := columnWidth(p, col) * pg.factor
+		pdf.Rect(x, top, cw, pdfHeaderHeight, "FD")
+		writePDFText(pg, col.Name, x, top, cw, pdfHeaderHeight)
+		x += cw
+	}
+
+	pdf.SetFont("Helvetica", "", pdfFontSize)
+}
+
+func writePDFRuler(pg *pdfPage, p *models.Project, total float64) {
+	pdf := pg.pdf
+	x := pdfMargin + pdfRulerWidth
+
+	end := math.Min(pg.end(), total)
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.Line(x, pg.y(pg.start), x, pg.y(end))
+
+	step := tickStep(pg.height/pg.scale, int(pg.height/15)+1)
+	for v := math.Ceil(pg.start/step) * step; v <= end+1e-6; v += step {
+		label := v
+		if p.Config.IsInverted {
+			label = total - v
+		}
+
+		y := pg.y(v)
+		pdf.Line(x-2, y, x, y)
+		text := formatNumber(label)
+		pdf.Text(x-3-pdf.GetStringWidth(text), y+1, text)
+	}
+}
+
+func writePDFLithology(pg *pdfPage, layers []Layer, x, cw float64) {
+	pdf := pg.pdf
+
+	for _, l := range layers {
+		if l.Base < pg.start || l.Top > pg.end() {
+			continue
+		}
+
+		lit := l.Data.Litologia
+		top := pg.y(l.Top)
+		h := l.Height() * pg.scale
+
+		points := make([][2]float64, 0, len(lit.Circles))
+		for _, c := range lit.Circles {
+			cy := float64(c.Y)
+			if l.Flipped {
+				cy = 1 - cy
+			}
+			points = append(points, [2]float64{x + float64(c.X)*cw, top + cy*h})
+		}
+
+		if len(points) > 2 {
+			setFill(pdf, lit.ColorFill, "#ffffff")
+			setDraw(pdf, lit.ColorStroke, "#000000")
+
+			pdf.MoveTo(points[0][0], points[0][1])
+			for _, s := range outline(points, float64(lit.Tension)) {
+				if s.Curve {
+					pdf.CurveBezierCubicTo(s.C1[0], s.C1[1], s.C2[0], s.C2[1], s.To[0], s.To[1])
+				} else {
+					pdf.LineTo(s.To[0], s.To[1])
+				}
+			}
+			pdf.ClosePath()
+			pdf.DrawPath("FD")
+
+			if hasPattern(lit) && h > 4 {
+				pdf.SetTextColor(80, 80, 80)
+				pdf.Text(x+1, top+3, pg.tr(lit.File))
+				pdf.SetTextColor(0, 0, 0)
+			}
+		}
+
+		// Contacto basal: 1 neto, 2 transicional, 3 erosivo
+		pdf.SetDrawColor(0, 0, 0)
+		switch {
+		case strings.HasPrefix(lit.Contact, "2"):
+			pdf.SetDashPattern([]float64{1.5, 1}, 0)
+		case strings.HasPrefix(lit.Contact, "3"):
+			pdf.SetDashPattern([]float64{0.5, 0.8}, 0)
+		}
+		pdf.Line(x, top+h, x+cw, top+h)
+		pdf.SetDashPattern([]float64{}, 0)
+	}
+}
+
+func writePDFTextColumn(pg *pdfPage, layers []Layer, column string, x, cw float64) {
+	pdf := pg.pdf
+	pdf.SetDrawColor(0, 0, 0)
+
+	for _, l := range layers {
+		if l.Base < pg.start || l.Top > pg.end() {
+			continue
+		}
+
+		top := pg.y(l.Top)
+		h := l.Height() * pg.scale
+		pdf.Rect(x, top, cw, h, "D")
+
+		// Si la capa viene de la página anterior el texto parte en el borde visible
+		textTop := math.Max(top, pg.top)
+		writePDFText(pg, CellText(l.Data, column), x, textTop, cw, top+h-textTop)
+	}
+}
+
+func writePDFFosils(pg *pdfPage, p *models.Project, x, cw float64) {
+	pdf := pg.pdf
+	pdf.SetDrawColor(0, 0, 0)
+
+	for _, id := range SortedFosils(p) {
+		f := p.Fosil[id]
+		cx := x + float64(f.X)*cw
+		upper, lower := pg.y(float64(f.Upper)), pg.y(float64(f.Lower))
+
+		pdf.Line(cx, upper, cx, lower)
+		pdf.Text(cx+1, (upper+lower)/2+1, pg.tr(f.FosilImg))
+	}
+}
+
+func writePDFMuestras(pg *pdfPage, p *models.Project, x, cw float64) {
+	pdf := pg.pdf
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetFillColor(0, 0, 0)
+
+	for _, id := range SortedMuestras(p) {
+		m := p.Muestras[id]
+		cx := x + float64(m.X)*cw
+		upper, lower := pg.y(float64(m.Upper)), pg.y(float64(m.Lower))
+		cy := (upper + lower) / 2
+
+		pdf.Line(cx, upper, cx, lower)
+		pdf.Circle(cx, cy, 0.8, "F")
+		pdf.Text(cx+1.5, cy+1, pg.tr(m.MuestraText))
+	}
+}
+
+func writePDFFacies(pg *pdfPage, p *models.Project, x, cw float64) {
+	pdf := pg.pdf
+	names := SortedFacies(p)
+	if len(names) == 0 {
+		return
+	}
+
+	lane := cw / float64(len(names))
+	for i, name := range names {
+		lx := x + float64(i)*lane
+		setFill(pdf, faciesColors[i%len(faciesColors)], "#ffffff")
+		pdf.SetDrawColor(0, 0, 0)
+
+		for _, s := range p.Facies[name] {
+			y1, y2 := pg.y(float64(s.Y1)), pg.y(float64(s.Y2))
+			if y2 < y1 {
+				y1, y2 = y2, y1
+			}
+			pdf.Rect(lx, y1, lane, y2-y1, "FD")
+
+			pdf.TransformBegin()
+			pdf.TransformRotate(90, lx+lane/2+1, (y1+y2)/2)
+			pdf.Text(lx+lane/2+1-pdf.GetStringWidth(name)/2, (y1+y2)/2, pg.tr(name))
+			pdf.TransformEnd()
+		}
+	}
+}
+
+// writePDFText escribe texto multilínea dentro de una celda, cortando lo que no cabe
+func writePDFText(pg *pdfPage, text string, x, y, cw, h float64) {
+	if strings.TrimSpace(text) == "" || h <= 0 {
+		return
+	}
+
+	pdf := pg.pdf
+	lineHeight := pdfFontSize * 0.45
+
+	pdf.ClipRect(x, y, cw, h, false)
+	for i, line := range splitPDFText(pg, text, cw-2) {
+		ly := y + lineHeight*float64(i+1)
+		if ly > y+h+lineHeight {
+			break
+		}
+		pdf.Text(x+1, ly, line)
+	}
+	pdf.ClipEnd()
+}
+
+// splitPDFText corta el texto por palabras según el ancho real en la fuente
+// actual. Las líneas se devuelven ya traducidas a la codificación del PDF
+func splitPDFText(pg *pdfPage, text string, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(pg.tr(paragraph)) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+
+			if line != "" && pg.pdf.GetStringWidth(candidate) > width {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func setFill(pdf *fpdf.Fpdf, color string, fallback string) {
+	r, g, b, ok := parseHexColor(color)
+	if !ok {
+		r, g, b, _ = parseHexColor(fallback)
+	}
+	pdf.SetFillColor(r, g, b)
+}
+
+func setDraw(pdf *fpdf.Fpdf, color string, fallback string) {
+	r, g, b, ok := parseHexColor(color)
+	if !ok {
+		r, g, b, _ = parseHexColor(fallback)
+	}
+	pdf.SetDrawColor(r, g, b)
+}
+
+// parseHexColor convierte "#rrggbb" o "#rgb" a sus componentes
+func parseHexColor(s string) (r, g, b int, ok bool) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return 0, 0, 0, false
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+
+	return int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff), true
+}
@@ -0,0 +1,34 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPDFPageLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		heights []float32
+		opts    PDFOptions
+		err     error
+	}{
+		{"empty", nil, PDFOptions{}, nil},
+		{"one page", []float32{10, 20}, PDFOptions{Scale: 1}, nil},
+		{"several pages", []float32{500, 500}, PDFOptions{Scale: 1, PageSize: "A3", Landscape: true}, nil},
+		{"too many pages", []float32{100000}, PDFOptions{Scale: 1}, ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := PDF(&buf, testProject(tt.heights...), tt.opts)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("PDF() = %v, want %v", err, tt.err)
+			}
+			if err == nil && !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+				t.Errorf("the output is not a PDF document")
+			}
+		})
+	}
+}
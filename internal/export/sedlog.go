@@ -0,0 +1,220 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// Encabezados del CSV de intercambio de SedLog, en el orden en que los escribe SedLog
+var SedLogHeaders = []string{
+	"THICKNESS (CM)",
+	"BASE BOUNDARY",
+	"LITHOLOGY",
+	"LITHOLOGY %",
+	"GRAIN SIZE BASE",
+	"GRAIN SIZE TOP",
+	"SYMBOLS IN BED",
+	"NOTES COLUMN",
+}
+
+// SedLogGrainSizes es la escala granulométrica de SedLog, de fino a grueso.
+// Cada clase ocupa una posición X entre 0 y 1 del borde derecho del polígono
+var SedLogGrainSizes = []string{"clay", "silt", "vf", "f", "m", "c", "vc", "granule", "pebble", "cobble", "boulder"}
+
+// SedLogLithology es la equivalencia entre una litología de SedLog y el patrón y color de StrataScope
+type SedLogLithology struct {
+	Name      string
+	File      string
+	ColorFill string
+}
+
+// SedLogLithologies son las litologías de SedLog que tienen un patrón propio.
+// Las que no están aquí se copian tal cual a Litologia.File
+var SedLogLithologies = []SedLogLithology{
+	{Name: "sandstone", File: "Arenisca", ColorFill: "#f5e08c"},
+	{Name: "siltstone", File: "Limolita", ColorFill: "#c9b47a"},
+	{Name: "mudstone", File: "Fangolita", ColorFill: "#9e9e7a"},
+	{Name: "claystone", File: "Arcillolita", ColorFill: "#a3a38f"},
+	{Name: "shale", File: "Lutita", ColorFill: "#7f8c6e"},
+	{Name: "conglomerate", File: "Conglomerado", ColorFill: "#e8b06a"},
+	{Name: "breccia", File: "Brecha", ColorFill: "#d9925a"},
+	{Name: "limestone", File: "Caliza", ColorFill: "#9ec9e8"},
+	{Name: "dolomite", File: "Dolomita", ColorFill: "#b7a6d9"},
+	{Name: "marl", File: "Marga", ColorFill: "#b5c9b0"},
+	{Name: "chert", File: "Chert", ColorFill: "#d4d4d4"},
+	{Name: "evaporite", File: "Evaporita", ColorFill: "#f0c2e0"},
+	{Name: "coal", File: "Carbon", ColorFill: "#333333"},
+	{Name: "tuff", File: "Toba", ColorFill: "#e0b8b8"},
+}
+
+// SedLogLithologyByName busca una litología por su nombre en SedLog o por su patrón
+func SedLogLithologyByName(name string) (SedLogLithology, bool) {
+	name = strings.TrimSpace(name)
+	for _, l := range SedLogLithologies {
+		if strings.EqualFold(l.Name, name) || strings.EqualFold(l.File, name) {
+			return l, true
+		}
+	}
+	return SedLogLithology{}, false
+}
+
+// SedLogGrainX devuelve la posición X de una clase granulométrica
+func SedLogGrainX(index int) float32 {
+	return float32(index+1) / float32(len(SedLogGrainSizes)+1)
+}
+
+// sedLogGrain devuelve la clase granulométrica más cercana a la posición X
+func sedLogGrain(x float64) string {
+	best := 0
+	for i := range SedLogGrainSizes {
+		if math.Abs(float64(SedLogGrainX(i))-x) < math.Abs(float64(SedLogGrainX(best))-x) {
+			best = i
+		}
+	}
+	return SedLogGrainSizes[best]
+}
+
+// sedLogBoundary traduce el tipo de contacto (primer dígito de Contact) al límite basal de SedLog
+func sedLogBoundary(contact string) string {
+	switch {
+	case strings.HasPrefix(contact, "2"), strings.HasPrefix(contact, "3"):
+		return "gradational"
+	default:
+		return "sharp"
+	}
+}
+
+// SedLogRows arma las capas con los campos de SedLog. SedLog registra desde la
+// base hacia arriba, así que la primera fila es la capa inferior. Los fósiles
+// se asignan a la capa que contiene su punto medio
+func SedLogRows(p *models.Project) [][]string {
+	layers := Layers(p)
+
+	symbols := make([][]string, len(layers))
+	for _, id := range SortedFosils(p) {
+		f := p.Fosil[id]
+		mid := (f32(f.Upper) + f32(f.Lower)) / 2
+		for i, layer := range layers {
+			if mid >= layer.Top && (mid < layer.Base || i == len(layers)-1) {
+				symbols[i] = append(symbols[i], f.FosilImg)
+				break
+			}
+		}
+	}
+
+	rows := [][]string{SedLogHeaders}
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		lit := layers[i].Data.Litologia
+
+		lithology := ""
+		if hasPattern(lit) {
+			lithology = lit.File
+			if l, ok := SedLogLithologyByName(lit.File); ok {
+				lithology = l.Name
+			}
+		}
+
+		base, top := sedLogGrainProfile(lit, layers[i].Flipped)
+
+		rows = append(rows, []string{
+			formatNumber(f32(lit.Height)),
+			sedLogBoundary(lit.Contact),
+			lithology,
+			"100",
+			base,
+			top,
+			strings.Join(symbols[i], ", "),
+			CellText(layers[i].Data, "Descripcion"),
+		})
+	}
+
+	return rows
+}
+
+// sedLogGrainProfile devuelve la granulometría en la base y el techo de la
+// capa a partir de los puntos movibles del polígono
+func sedLogGrainProfile(lit models.LitologiaStruc, flipped bool) (base string, top string) {
+	var points []models.CircleStruc
+	for _, c := range lit.Circles {
+		if c.Movable {
+			points = append(points, c)
+		}
+	}
+	if len(points) == 0 {
+		return "", ""
+	}
+
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Y < points[j].Y })
+
+	first, last := points[0], points[len(points)-1]
+	if flipped {
+		first, last = last, first
+	}
+
+	return sedLogGrain(f32(last.X)), sedLogGrain(f32(first.X))
+}
+
+// WriteSedLogCSV escribe el proyecto en el CSV de intercambio de SedLog
+func WriteSedLogCSV(w io.Writer, p *models.Project) error {
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(SedLogRows(p)); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+type sedLogXML struct {
+	XMLName xml.Name       `xml:"sedlog"`
+	Name    string         `xml:"name,attr,omitempty"`
+	Beds    []sedLogXMLBed `xml:"bed"`
+}
+
+type sedLogXMLBed struct {
+	Thickness     string `xml:"thickness"`
+	BaseBoundary  string `xml:"base_boundary"`
+	Lithology     string `xml:"lithology"`
+	LithologyPct  string `xml:"lithology_percent"`
+	GrainSizeBase string `xml:"grain_size_base"`
+	GrainSizeTop  string `xml:"grain_size_top"`
+	Symbols       string `xml:"symbols_in_bed"`
+	Notes         string `xml:"notes_column"`
+}
+
+// WriteSedLogXML escribe el proyecto como XML con un elemento <bed> por capa y
+// los mismos campos que el CSV, desde la base hacia arriba
+func WriteSedLogXML(w io.Writer, p *models.Project) error {
+	doc := sedLogXML{Name: p.ProjectInfo.Name}
+
+	for _, row := range SedLogRows(p)[1:] {
+		doc.Beds = append(doc.Beds, sedLogXMLBed{
+			Thickness:     row[0],
+			BaseBoundary:  row[1],
+			Lithology:     row[2],
+			LithologyPct:  row[3],
+			GrainSizeBase: row[4],
+			GrainSizeTop:  row[5],
+			Symbols:       row[6],
+			Notes:         row[7],
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,397 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+// Options controla el dibujo de la columna estratigráfica
+type Options struct {
+	Scale       float64 // pixeles por unidad de espesor del proyecto, 1 por defecto
+	PatternBase string  // URL base de los patrones de litología (<base><File>.svg)
+	FosilBase   string  // URL base de los íconos de fósiles (<base><FosilImg>.svg)
+}
+
+const (
+	headerHeight = 60.0
+	rulerWidth   = 50.0
+	fontSize     = 11.0
+	charWidth    = 6.5 // ancho aproximado de un caracter a fontSize
+	patternTile  = 100.0
+)
+
+// Límites del dibujo. Sin ellos una escala muy grande genera un documento
+// (y una regla) de tamaño arbitrario
+const (
+	MinScale     = 0.01   // pixeles por unidad
+	MaxScale     = 50     // pixeles por unidad
+	maxSVGHeight = 500000 // pixeles
+)
+
+// ErrTooLarge indica que el documento pedido supera el tamaño máximo
+var ErrTooLarge = errors.New("the requested document is too large")
+
+var faciesColors = []string{"#8dd3c7", "#ffffb3", "#bebada", "#fb8072", "#80b1d3", "#fdb462", "#b3de69", "#fccde5"}
+
+// columnWidth devuelve el ancho de dibujo de cada tipo de columna
+func columnWidth(p *models.Project, col models.Column) float64 {
+	switch col.Name {
+	case ColumnLitologia:
+		return 240
+	case ColumnEspesor:
+		return 70
+	case ColumnFosil, ColumnMuestras:
+		return 110
+	case ColumnFacie:
+		if w := float64(len(p.Facies)) * 24; w > 80 {
+			return w
+		}
+		return 80
+	default:
+		return 130
+	}
+}
+
+// SVG dibuja el proyecto como un documento SVG independiente
+func SVG(w io.Writer, p *models.Project, opts Options) error {
+	if opts.Scale <= 0 {
+		opts.Scale = 1
+	}
+
+	columns := VisibleColumns(p.Config)
+	layers := Layers(p)
+	total := TotalHeight(p) * opts.Scale
+	if total > maxSVGHeight {
+		return ErrTooLarge
+	}
+	// Capas sin espesor (o negativo) dejan solo el encabezado, como en el PDF
+	if total < 0 {
+		total = 0
+	}
+
+	width := rulerWidth
+	for _, col := range columns {
+		width += columnWidth(p, col)
+	}
+	height := headerHeight + total + 1
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%s" height="%s" viewBox="0 0 %s %s" font-family="Helvetica, Arial, sans-serif" font-size="%s">`+"\n",
+		num(width), num(height), num(width), num(height), num(fontSize))
+	fmt.Fprintf(bw, "<title>%s</title>\n", esc(p.ProjectInfo.Name))
+
+	writePatterns(bw, layers, opts)
+
+	fmt.Fprintf(bw, `<rect x="0" y="0" width="%s" height="%s" fill="#ffffff"/>`+"\n", num(width), num(height))
+
+	writeRuler(bw, p, total, opts)
+
+	x := rulerWidth
+	for _, col := range columns {
+		cw := columnWidth(p, col)
+
+		fmt.Fprintf(bw, `<g class="column" data-column="%s">`+"\n", esc(col.Name))
+		writeHeader(bw, col.Name, x, cw)
+
+		switch col.Name {
+		case ColumnLitologia:
+			writeLithology(bw, layers, x, cw, opts)
+		case ColumnFosil:
+			writeFosils(bw, p, x, cw, opts)
+		case ColumnMuestras:
+			writeMuestras(bw, p, x, cw, opts)
+		case ColumnFacie:
+			writeFacies(bw, p, x, cw, opts)
+		default:
+			writeTextColumn(bw, layers, col.Name, x, cw, opts)
+		}
+
+		fmt.Fprintf(bw, `<rect x="%s" y="0" width="%s" height="%s" fill="none" stroke="#000000"/>`+"\n", num(x), num(cw), num(height-1))
+		fmt.Fprintf(bw, "</g>\n")
+		x += cw
+	}
+
+	fmt.Fprintf(bw, "</svg>\n")
+
+	return bw.Flush()
+}
+
+func writePatterns(w io.Writer, layers []Layer, opts Options) {
+	if opts.PatternBase == "" {
+		return
+	}
+
+	fmt.Fprintf(w, "<defs>\n")
+	for _, l := range layers {
+		lit := l.Data.Litologia
+		if !hasPattern(lit) {
+			continue
+		}
+
+		zoom := float64(lit.Zoom) / 100
+		if zoom <= 0 {
+			zoom = 1
+		}
+
+		fmt.Fprintf(w, `<pattern id="%s" patternUnits="userSpaceOnUse" width="%s" height="%s" patternTransform="rotate(%d) scale(%s)">`,
+			patternID(l.Index), num(patternTile), num(patternTile), lit.Rotation, num(zoom*opts.Scale))
+		fmt.Fprintf(w, `<rect width="%s" height="%s" fill="%s"/>`, num(patternTile), num(patternTile), esc(lit.ColorFill))
+		fmt.Fprintf(w, `<image href="%s" xlink:href="%s" width="%s" height="%s"/>`,
+			esc(assetURL(opts.PatternBase, lit.File)), esc(assetURL(opts.PatternBase, lit.File)), num(patternTile), num(patternTile))
+		fmt.Fprintf(w, "</pattern>\n")
+	}
+	fmt.Fprintf(w, "</defs>\n")
+}
+
+func writeHeader(w io.Writer, name string, x, cw float64) {
+	fmt.Fprintf(w, `<rect x="%s" y="0" width="%s" height="%s" fill="#f2f2f2" stroke="#000000"/>`+"\n", num(x), num(cw), num(headerHeight))
+	writeTextBlock(w, name, x, 0, cw, headerHeight, true)
+}
+
+func writeRuler(w io.Writer, p *models.Project, total float64, opts Options) {
+	fmt.Fprintf(w, `<g class="ruler">`+"\n")
+	fmt.Fprintf(w, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="#000000"/>`+"\n",
+		num(rulerWidth-1), num(headerHeight), num(rulerWidth-1), num(headerHeight+total))
+
+	raw := TotalHeight(p)
+	step := tickStep(raw, int(total/50)+1)
+	for v := 0.0; v <= raw+1e-6; v += step {
+		y := headerHeight + v*opts.Scale
+
+		// En un perfil invertido la escala crece desde abajo
+		label := v
+		if p.Config.IsInverted {
+			label = raw - v
+		}
+
+		fmt.Fprintf(w, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="#000000"/>`+"\n", num(rulerWidth-8), num(y), num(rulerWidth-1), num(y))
+		fmt.Fprintf(w, `<text x="%s" y="%s" text-anchor="end" dominant-baseline="middle">%s</text>`+"\n", num(rulerWidth-10), num(y), formatNumber(label))
+	}
+	fmt.Fprintf(w, "</g>\n")
+}
+
+func writeLithology(w io.Writer, layers []Layer, x, cw float64, opts Options) {
+	for _, l := range layers {
+		lit := l.Data.Litologia
+		top := headerHeight + l.Top*opts.Scale
+		h := l.Height() * opts.Scale
+
+		fill := lit.ColorFill
+		if opts.PatternBase != "" && hasPattern(lit) {
+			fill = "url(#" + patternID(l.Index) + ")"
+		}
+		if fill == "" {
+			fill = "#ffffff"
+		}
+
+		stroke := lit.ColorStroke
+		if stroke == "" {
+			stroke = "#000000"
+		}
+
+		points := make([][2]float64, 0, len(lit.Circles))
+		for _, c := range lit.Circles {
+			cy := float64(c.Y)
+			if l.Flipped {
+				cy = 1 - cy
+			}
+			points = append(points, [2]float64{x + float64(c.X)*cw, top + cy*h})
+		}
+
+		fmt.Fprintf(w, `<g class="layer" data-index="%d" data-file="%s" data-contact="%s">`+"\n", l.Index, esc(lit.File), esc(lit.Contact))
+		if len(points) > 2 {
+			fmt.Fprintf(w, `<path d="%s" fill="%s" stroke="%s"/>`+"\n", polygonPath(points, float64(lit.Tension)), esc(fill), esc(stroke))
+		}
+		writeContact(w, lit.Contact, x, top+h, cw)
+		fmt.Fprintf(w, "</g>\n")
+	}
+}
+
+// writeContact dibuja el contacto basal de la capa. El primer dígito del
+// código de contacto indica el tipo de línea: 1 neto, 2 transicional, 3 erosivo
+func writeContact(w io.Writer, contact string, x, y, cw float64) {
+	dash := ""
+	switch {
+	case strings.HasPrefix(contact, "2"):
+		dash = ` stroke-dasharray="6 4"`
+	case strings.HasPrefix(contact, "3"):
+		dash = ` stroke-dasharray="2 3"`
+	}
+
+	fmt.Fprintf(w, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="#000000"%s/>`+"\n", num(x), num(y), num(x+cw), num(y), dash)
+}
+
+func writeTextColumn(w io.Writer, layers []Layer, column string, x, cw float64, opts Options) {
+	for _, l := range layers {
+		top := headerHeight + l.Top*opts.Scale
+		h := l.Height() * opts.Scale
+
+		fmt.Fprintf(w, `<rect x="%s" y="%s" width="%s" height="%s" fill="none" stroke="#000000"/>`+"\n", num(x), num(top), num(cw), num(h))
+		writeTextBlock(w, CellText(l.Data, column), x, top, cw, h, false)
+	}
+}
+
+func writeFosils(w io.Writer, p *models.Project, x, cw float64, opts Options) {
+	for _, id := range SortedFosils(p) {
+		f := p.Fosil[id]
+		upper := headerHeight + float64(f.Upper)*opts.Scale
+		lower := headerHeight + float64(f.Lower)*opts.Scale
+		cx := x + float64(f.X)*cw
+		cy := (upper + lower) / 2
+
+		fmt.Fprintf(w, `<g class="fosil" data-id="%s">`+"\n", esc(id))
+		fmt.Fprintf(w, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="#000000"/>`+"\n", num(cx), num(upper), num(cx), num(lower))
+		if opts.FosilBase != "" {
+			fmt.Fprintf(w, `<image href="%s" xlink:href="%s" x="%s" y="%s" width="20" height="20"/>`+"\n",
+				esc(assetURL(opts.FosilBase, f.FosilImg)), esc(assetURL(opts.FosilBase, f.FosilImg)), num(cx-10), num(cy-10))
+		} else {
+			fmt.Fprintf(w, `<text x="%s" y="%s" dominant-baseline="middle">%s</text>`+"\n", num(cx+4), num(cy), esc(f.FosilImg))
+		}
+		fmt.Fprintf(w, "</g>\n")
+	}
+}
+
+func writeMuestras(w io.Writer, p *models.Project, x, cw float64, opts Options) {
+	for _, id := range SortedMuestras(p) {
+		m := p.Muestras[id]
+		upper := headerHeight + float64(m.Upper)*opts.Scale
+		lower := headerHeight + float64(m.Lower)*opts.Scale
+		cx := x + float64(m.X)*cw
+		cy := (upper + lower) / 2
+
+		fmt.Fprintf(w, `<g class="muestra" data-id="%s">`+"\n", esc(id))
+		fmt.Fprintf(w, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="#000000"/>`+"\n", num(cx), num(upper), num(cx), num(lower))
+		fmt.Fprintf(w, `<circle cx="%s" cy="%s" r="3" fill="#000000"/>`+"\n", num(cx), num(cy))
+		fmt.Fprintf(w, `<text x="%s" y="%s" dominant-baseline="middle">%s</text>`+"\n", num(cx+5), num(cy), esc(m.MuestraText))
+		fmt.Fprintf(w, "</g>\n")
+	}
+}
+
+func writeFacies(w io.Writer, p *models.Project, x, cw float64, opts Options) {
+	names := SortedFacies(p)
+	if len(names) == 0 {
+		return
+	}
+
+	lane := cw / float64(len(names))
+	for i, name := range names {
+		lx := x + float64(i)*lane
+		color := faciesColors[i%len(faciesColors)]
+
+		fmt.Fprintf(w, `<g class="facie" data-facie="%s">`+"\n", esc(name))
+		for _, s := range p.Facies[name] {
+			y1 := headerHeight + float64(s.Y1)*opts.Scale
+			y2 := headerHeight + float64(s.Y2)*opts.Scale
+			if y2 < y1 {
+				y1, y2 = y2, y1
+			}
+			fmt.Fprintf(w, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s" stroke="#000000"/>`+"\n", num(lx), num(y1), num(lane), num(y2-y1), color)
+			fmt.Fprintf(w, `<text transform="translate(%s %s) rotate(-90)" text-anchor="middle" dominant-baseline="middle">%s</text>`+"\n",
+				num(lx+lane/2), num((y1+y2)/2), esc(name))
+		}
+		fmt.Fprintf(w, "</g>\n")
+	}
+}
+
+// writeTextBlock escribe texto multilínea recortado a la celda con un <svg> anidado
+func writeTextBlock(w io.Writer, text string, x, y, cw, h float64, center bool) {
+	if strings.TrimSpace(text) == "" || h <= 0 {
+		return
+	}
+
+	lines := wrapText(text, int((cw-8)/charWidth))
+	lineHeight := fontSize * 1.25
+
+	fmt.Fprintf(w, `<svg x="%s" y="%s" width="%s" height="%s">`, num(x), num(y), num(cw), num(h))
+	anchor, tx := "start", 4.0
+	if center {
+		anchor, tx = "middle", cw/2
+	}
+	for i, line := range lines {
+		fmt.Fprintf(w, `<text x="%s" y="%s" text-anchor="%s">%s</text>`, num(tx), num(4+fontSize+float64(i)*lineHeight), anchor, esc(line))
+	}
+	fmt.Fprintf(w, "</svg>\n")
+}
+
+// polygonPath arma el contorno cerrado de la capa como atributo d de un <path>
+func polygonPath(points [][2]float64, tension float64) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "M%s %s", num(points[0][0]), num(points[0][1]))
+	for _, s := range outline(points, tension) {
+		if !s.Curve {
+			fmt.Fprintf(&b, " L%s %s", num(s.To[0]), num(s.To[1]))
+			continue
+		}
+		fmt.Fprintf(&b, " C%s %s %s %s %s %s", num(s.C1[0]), num(s.C1[1]), num(s.C2[0]), num(s.C2[1]), num(s.To[0]), num(s.To[1]))
+	}
+	b.WriteString(" Z")
+
+	return b.String()
+}
+
+// segment es un tramo del contorno de una capa, recto o curva de Bézier cúbica
+type segment struct {
+	C1, C2, To [2]float64
+	Curve      bool
+}
+
+// outline recorre el polígono cerrado desde points[0]. Con tensión mayor a 0
+// se suaviza con una spline cardinal, igual que las líneas del editor
+func outline(points [][2]float64, tension float64) []segment {
+	n := len(points)
+	segments := make([]segment, 0, n)
+
+	for i := 0; i < n; i++ {
+		p1 := points[i]
+		p2 := points[(i+1)%n]
+
+		if tension == 0 {
+			segments = append(segments, segment{To: p2})
+			continue
+		}
+
+		p0 := points[(i-1+n)%n]
+		p3 := points[(i+2)%n]
+
+		segments = append(segments, segment{
+			C1:    [2]float64{p1[0] + (p2[0]-p0[0])*tension/3, p1[1] + (p2[1]-p0[1])*tension/3},
+			C2:    [2]float64{p2[0] - (p3[0]-p1[0])*tension/3, p2[1] - (p3[1]-p1[1])*tension/3},
+			To:    p2,
+			Curve: true,
+		})
+	}
+
+	return segments
+}
+
+func hasPattern(lit models.LitologiaStruc) bool {
+	return lit.File != "" && lit.File != "Sin Pattern"
+}
+
+func patternID(index int) string {
+	return fmt.Sprintf("pattern-%d", index)
+}
+
+func assetURL(base string, name string) string {
+	return base + url.PathEscape(name) + ".svg"
+}
+
+func esc(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func num(v float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+}
@@ -0,0 +1,85 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+func testProject(heights ...float32) *models.Project {
+	p := &models.Project{
+		Config: models.Config{Columns: []models.Column{
+			{Name: ColumnLitologia, Visible: true},
+			{Name: ColumnEspesor, Visible: true},
+		}},
+	}
+	for _, h := range heights {
+		row := models.NewShape()
+		row.Litologia.Height = h
+		p.Data = append(p.Data, row)
+	}
+	return p
+}
+
+// svgHeight devuelve el alto y el alto del viewBox del documento
+func svgHeight(t *testing.T, doc []byte) (float64, float64) {
+	t.Helper()
+
+	var svg struct {
+		Height  string `xml:"height,attr"`
+		ViewBox string `xml:"viewBox,attr"`
+	}
+	if err := xml.Unmarshal(doc, &svg); err != nil {
+		t.Fatalf("invalid SVG: %v", err)
+	}
+
+	height, err := strconv.ParseFloat(svg.Height, 64)
+	if err != nil {
+		t.Fatalf("height = %q: %v", svg.Height, err)
+	}
+
+	var x, y, w, h float64
+	if _, err := fmt.Sscan(svg.ViewBox, &x, &y, &w, &h); err != nil {
+		t.Fatalf("viewBox = %q: %v", svg.ViewBox, err)
+	}
+	return height, h
+}
+
+func TestSVGHeight(t *testing.T) {
+	tests := []struct {
+		name    string
+		heights []float32
+		scale   float64
+		want    float64
+		err     error
+	}{
+		{"empty", nil, 1, headerHeight + 1, nil},
+		{"layers", []float32{10, 20}, 2, headerHeight + 60 + 1, nil},
+		{"zero heights", []float32{0, 0}, 1, headerHeight + 1, nil},
+		{"negative heights", []float32{-100, -50}, 1, headerHeight + 1, nil},
+		{"too large", []float32{maxSVGHeight}, 2, 0, ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := SVG(&buf, testProject(tt.heights...), Options{Scale: tt.scale})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("SVG() = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			height, viewBox := svgHeight(t, buf.Bytes())
+			if height != tt.want || viewBox != tt.want {
+				t.Errorf("height = %v, viewBox height = %v, want %v", height, viewBox, tt.want)
+			}
+		})
+	}
+}
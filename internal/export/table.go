@@ -0,0 +1,176 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/xuri/excelize/v2"
+)
+
+// Sheet es una tabla plana del proyecto. Las celdas son string o float64
+type Sheet struct {
+	Name string
+	Rows [][]interface{}
+}
+
+// Campos de la litología que se agregan después de las columnas de la tabla.
+// Usan los mismos nombres que reconoce la importación de planillas
+var litologiaFields = []string{"ColorFill", "ColorStroke", "Contact", "Zoom", "Rotation", "Tension"}
+
+// Tables aplana el proyecto en cuatro tablas: capas (con techo y base
+// calculados desde la primera capa), fósiles, muestras y facies
+func Tables(p *models.Project) []Sheet {
+	return []Sheet{
+		LayersSheet(p),
+		FosilsSheet(p),
+		MuestrasSheet(p),
+		FaciesSheet(p),
+	}
+}
+
+// LayersSheet devuelve una fila por capa con las columnas en el orden de Config.Columns
+func LayersSheet(p *models.Project) Sheet {
+	header := []interface{}{"Index", "Top", "Base"}
+
+	var columns []string
+	for _, col := range p.Config.Columns {
+		switch col.Name {
+		case ColumnFosil, ColumnFacie, ColumnMuestras:
+			continue
+		}
+		columns = append(columns, col.Name)
+		header = append(header, col.Name)
+	}
+	for _, field := range litologiaFields {
+		header = append(header, field)
+	}
+
+	rows := [][]interface{}{header}
+	tops, bases := Depths(p)
+
+	for i, layer := range p.Data {
+		lit := layer.Litologia
+		row := []interface{}{float64(i), tops[i], bases[i]}
+
+		for _, name := range columns {
+			switch name {
+			case ColumnEspesor:
+				row = append(row, f32(lit.Height))
+			case ColumnLitologia:
+				row = append(row, lit.File)
+			default:
+				row = append(row, CellText(layer, name))
+			}
+		}
+
+		row = append(row, lit.ColorFill, lit.ColorStroke, lit.Contact, float64(lit.Zoom), float64(lit.Rotation), f32(lit.Tension))
+		rows = append(rows, row)
+	}
+
+	return Sheet{Name: "Capas", Rows: rows}
+}
+
+// FosilsSheet devuelve una fila por fósil
+func FosilsSheet(p *models.Project) Sheet {
+	rows := [][]interface{}{{"Id", "Upper", "Lower", "FosilImg", "X"}}
+	for _, id := range SortedFosils(p) {
+		f := p.Fosil[id]
+		rows = append(rows, []interface{}{id, f32(f.Upper), f32(f.Lower), f.FosilImg, f32(f.X)})
+	}
+	return Sheet{Name: "Fosiles", Rows: rows}
+}
+
+// MuestrasSheet devuelve una fila por muestra
+func MuestrasSheet(p *models.Project) Sheet {
+	rows := [][]interface{}{{"Id", "Upper", "Lower", "MuestraText", "X"}}
+	for _, id := range SortedMuestras(p) {
+		m := p.Muestras[id]
+		rows = append(rows, []interface{}{id, f32(m.Upper), f32(m.Lower), m.MuestraText, f32(m.X)})
+	}
+	return Sheet{Name: "Muestras", Rows: rows}
+}
+
+// FaciesSheet devuelve una fila por sección de facies
+func FaciesSheet(p *models.Project) Sheet {
+	rows := [][]interface{}{{"Facie", "Index", "Y1", "Y2"}}
+	for _, name := range SortedFacies(p) {
+		for i, s := range p.Facies[name] {
+			rows = append(rows, []interface{}{name, float64(i), f32(s.Y1), f32(s.Y2)})
+		}
+	}
+	return Sheet{Name: "Facies", Rows: rows}
+}
+
+// WriteCSV escribe una tabla como CSV
+func WriteCSV(w io.Writer, sheet Sheet) error {
+	writer := csv.NewWriter(w)
+	for _, row := range sheet.Rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = formatCell(cell)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteCSVZip escribe un zip con un CSV por tabla
+func WriteCSVZip(w io.Writer, sheets []Sheet) error {
+	zw := zip.NewWriter(w)
+	for _, sheet := range sheets {
+		f, err := zw.Create(sheet.Name + ".csv")
+		if err != nil {
+			return err
+		}
+		if err := WriteCSV(f, sheet); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// WriteXLSX escribe un libro con una hoja por tabla
+func WriteXLSX(w io.Writer, sheets []Sheet) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	for i, sheet := range sheets {
+		if i == 0 {
+			if err := f.SetSheetName(f.GetSheetName(0), sheet.Name); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(sheet.Name); err != nil {
+			return err
+		}
+
+		for r, row := range sheet.Rows {
+			cell, err := excelize.CoordinatesToCellName(1, r+1)
+			if err != nil {
+				return err
+			}
+			if err := f.SetSheetRow(sheet.Name, cell, &row); err != nil {
+				return err
+			}
+		}
+	}
+
+	return f.Write(w)
+}
+
+func formatCell(cell interface{}) string {
+	switch v := cell.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
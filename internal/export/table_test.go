@@ -0,0 +1,149 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/xuri/excelize/v2"
+)
+
+// tableProject devuelve un proyecto con dos capas, una columna propia y una
+// de facies que no se exporta en la tabla de capas
+func tableProject() *models.Project {
+	p := testProject(2, 3.5)
+	p.Config.Columns = append(p.Config.Columns,
+		models.Column{Name: ColumnFacie, Visible: true},
+		models.Column{Name: "Notas", Visible: true},
+	)
+	p.Data[0].Columns = map[string]interface{}{"Notas": "arenisca"}
+	p.Data[1].Columns = map[string]interface{}{"Notas": "lutita"}
+	p.Data[0].Litologia.File = "Arenisca"
+	p.Data[1].Litologia.File = "Lutita"
+	p.Fosil = map[string]models.Fosil{"f1": {Upper: 1, Lower: 2, FosilImg: "amonite"}}
+	p.Muestras = map[string]models.Muestra{"m1": {Upper: 3, Lower: 4, MuestraText: "M-1"}}
+	p.Facies = map[string][]models.FaciesSection{"A": {{Y1: 0, Y2: 2}}}
+	return p
+}
+
+var sheetNames = []string{"Capas", "Fosiles", "Muestras", "Facies"}
+
+// checkLayers revisa el encabezado y el techo y la base de cada capa
+func checkLayers(t *testing.T, rows [][]string) {
+	t.Helper()
+
+	header := []string{"Index", "Top", "Base", ColumnLitologia, ColumnEspesor, "Notas", "ColorFill", "ColorStroke", "Contact", "Zoom", "Rotation", "Tension"}
+	want := [][]string{
+		{"0", "0", "2", "Arenisca", "2", "arenisca"},
+		{"1", "2", "5.5", "Lutita", "3.5", "lutita"},
+	}
+
+	if len(rows) != len(want)+1 {
+		t.Fatalf("rows = %d, want %d", len(rows), len(want)+1)
+	}
+	if !reflect.DeepEqual(rows[0], header) {
+		t.Errorf("header = %v, want %v", rows[0], header)
+	}
+	for i, row := range want {
+		got := rows[i+1]
+		if len(got) < len(row) || !reflect.DeepEqual(got[:len(row)], row) {
+			t.Errorf("layer %d = %v, want it to start with %v", i, got, row)
+		}
+	}
+}
+
+func readCSV(t *testing.T, data []byte) [][]string {
+	t.Helper()
+
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	return rows
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, LayersSheet(tableProject())); err != nil {
+		t.Fatal(err)
+	}
+	checkLayers(t, readCSV(t, buf.Bytes()))
+}
+
+func TestWriteCSVZip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSVZip(&buf, Tables(tableProject())); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+
+	files := map[string][]byte{}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data bytes.Buffer
+		if _, err := data.ReadFrom(r); err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+		files[f.Name] = data.Bytes()
+	}
+
+	want := []string{"Capas.csv", "Fosiles.csv", "Muestras.csv", "Facies.csv"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("files = %v, want %v", names, want)
+	}
+
+	checkLayers(t, readCSV(t, files["Capas.csv"]))
+
+	fosils := readCSV(t, files["Fosiles.csv"])
+	if len(fosils) != 2 || !reflect.DeepEqual(fosils[1], []string{"f1", "1", "2", "amonite", "0"}) {
+		t.Errorf("fosils = %v", fosils)
+	}
+	facies := readCSV(t, files["Facies.csv"])
+	if len(facies) != 2 || !reflect.DeepEqual(facies[1], []string{"A", "0", "0", "2"}) {
+		t.Errorf("facies = %v", facies)
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, Tables(tableProject())); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("invalid workbook: %v", err)
+	}
+	defer f.Close()
+
+	if got := f.GetSheetList(); !reflect.DeepEqual(got, sheetNames) {
+		t.Fatalf("sheets = %v, want %v", got, sheetNames)
+	}
+
+	rows, err := f.GetRows("Capas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLayers(t, rows)
+
+	muestras, err := f.GetRows("Muestras")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(muestras) != 2 || !reflect.DeepEqual(muestras[1], []string{"m1", "3", "4", "M-1", "0"}) {
+		t.Errorf("muestras = %v", muestras)
+	}
+}
@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/lithammer/shortuuid/v4"
+)
+
+// Tamaño máximo del respaldo, comprimido o no
+const maxArchiveSize = 64 << 20
+
+var (
+	ErrInvalidArchive = errors.New("invalid project archive")
+	ErrArchiveVersion = errors.New("unsupported archive version")
+)
+
+// Archive lee un respaldo (JSON o zip con un JSON) y devuelve el proyecto listo
+// para insertarse como uno nuevo: sin ID, sin enlaces de invitación y con IDs
+// nuevos para capas, fósiles, muestras y registros. Los miembros quedan a cargo de quien importa
+func Archive(r io.Reader) (*models.Project, error) {
+	content, err := io.ReadAll(io.LimitReader(r, maxArchiveSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxArchiveSize {
+		return nil, fmt.Errorf("%w: file too large", ErrInvalidArchive)
+	}
+
+	if bytes.HasPrefix(content, []byte("PK\x03\x04")) {
+		content, err = unzipArchive(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var archive models.Archive
+	if err := json.Unmarshal(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")), &archive); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	if archive.Format != models.ArchiveFormat {
+		return nil, ErrInvalidArchive
+	}
+	if archive.Version < 1 || archive.Version > models.ArchiveVersion {
+		return nil, ErrArchiveVersion
+	}
+
+	src := archive.Project
+
+	project := &models.Project{
+		ProjectInfo: src.ProjectInfo,
+		Data:        src.Data,
+		Config:      src.Config,
+		Fosil:       make(map[string]models.Fosil, len(src.Fosil)),
+		Facies:      src.Facies,
+		Muestras:    make(map[string]models.Muestra, len(src.Muestras)),
+		Logs:        make(map[string]models.WellLog, len(src.Logs)),
+	}
+
+	for _, fosil := range src.Fosil {
+		project.Fosil[shortuuid.New()] = fosil
+	}
+	for _, muestra := range src.Muestras {
+		project.Muestras[shortuuid.New()] = muestra
+	}
+	for _, wellLog := range src.Logs {
+		project.Logs[shortuuid.New()] = wellLog
+	}
+
+	if project.Data == nil {
+		project.Data = []models.DataInfo{}
+	}
+	// Las capas también reciben IDs nuevos: un respaldo editado a mano o
+	// combinado puede repetirlos, y el historial, los bloqueos y las
+	// diferencias ubican las capas por ID
+	for i := range project.Data {
+		project.Data[i].ID = shortuuid.New()
+		if project.Data[i].Columns == nil {
+			project.Data[i].Columns = map[string]interface{}{}
+		}
+	}
+	if len(project.Config.Columns) == 0 {
+		project.Config = models.DefaultConfig()
+	}
+	if project.Facies == nil {
+		project.Facies = map[string][]models.FaciesSection{}
+	}
+
+	return project, nil
+}
+
+// unzipArchive devuelve el contenido del project.json (o del primer .json) del zip
+func unzipArchive(content []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	var entry *zip.File
+	for _, f := range zr.File {
+		if f.Name == "project.json" {
+			entry = f
+			break
+		}
+		if entry == nil && strings.HasSuffix(strings.ToLower(f.Name), ".json") {
+			entry = f
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("%w: no project.json in zip", ErrInvalidArchive)
+	}
+
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxArchiveSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxArchiveSize {
+		return nil, fmt.Errorf("%w: file too large", ErrInvalidArchive)
+	}
+
+	return data, nil
+}
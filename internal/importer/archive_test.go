@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/export"
+	"github.com/ProyectoT/api/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func archiveProject() *models.Project {
+	p := &models.Project{
+		ID: primitive.NewObjectID(),
+		ProjectInfo: models.ProjectInfo{
+			Name:     "Quebrada",
+			Owner:    "a@test.com",
+			Members:  models.Members{Owner: "a@test.com", Editors: []string{"b@test.com"}},
+			Location: "Temuco",
+			Lat:      -38.7,
+			Long:     -72.6,
+		},
+		Config:   models.DefaultConfig(),
+		Fosil:    map[string]models.Fosil{"f1": {Upper: 1, Lower: 2, FosilImg: "amonite"}},
+		Facies:   map[string][]models.FaciesSection{"arena": {{Y1: 0, Y2: 10}}},
+		Muestras: map[string]models.Muestra{"m1": {Upper: 3, Lower: 4, MuestraText: "M-1"}},
+		Logs:     map[string]models.WellLog{"l1": {Well: "P1", Depth: []float64{0, 1}}},
+		Shared:   models.Shared{Pass: "secreto"},
+	}
+	for i, id := range []string{"capa-1", "capa-2"} {
+		row := models.NewShape()
+		row.ID = id
+		row.Columns["Notas"] = string(rune('a' + i))
+		row.Litologia.Height = float32(10 * (i + 1))
+		p.Data = append(p.Data, row)
+	}
+	return p
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	writers := []struct {
+		name  string
+		write func(w *bytes.Buffer, p *models.Project) error
+	}{
+		{"json", func(w *bytes.Buffer, p *models.Project) error { return export.WriteArchive(w, p) }},
+		{"zip", func(w *bytes.Buffer, p *models.Project) error { return export.WriteArchiveZip(w, p) }},
+	}
+
+	for _, tt := range writers {
+		t.Run(tt.name, func(t *testing.T) {
+			src := archiveProject()
+
+			var buf bytes.Buffer
+			if err := tt.write(&buf, src); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Archive(&buf)
+			if err != nil {
+				t.Fatalf("Archive() = %v", err)
+			}
+
+			if !got.ID.IsZero() || got.Shared.Pass != "" {
+				t.Errorf("ID %v and share password %q were kept", got.ID, got.Shared.Pass)
+			}
+			if !reflect.DeepEqual(got.ProjectInfo.Members, models.Members{}) {
+				t.Errorf("members = %+v, want none", got.ProjectInfo.Members)
+			}
+			if got.ProjectInfo.Name != src.ProjectInfo.Name || got.ProjectInfo.Lat != src.ProjectInfo.Lat {
+				t.Errorf("project info = %+v", got.ProjectInfo)
+			}
+			if !reflect.DeepEqual(got.Config, src.Config) || !reflect.DeepEqual(got.Facies, src.Facies) {
+				t.Errorf("config or facies changed")
+			}
+
+			if len(got.Data) != len(src.Data) {
+				t.Fatalf("layers = %d, want %d", len(got.Data), len(src.Data))
+			}
+			for i := range got.Data {
+				if got.Data[i].ID == "" || got.Data[i].ID == src.Data[i].ID {
+					t.Errorf("layer %d kept ID %q", i, got.Data[i].ID)
+				}
+				want := src.Data[i]
+				want.ID = got.Data[i].ID
+				if !reflect.DeepEqual(got.Data[i], want) {
+					t.Errorf("layer %d = %+v, want %+v", i, got.Data[i], want)
+				}
+			}
+
+			checkValues(t, "fossils", got.Fosil, src.Fosil)
+			checkValues(t, "samples", got.Muestras, src.Muestras)
+			checkValues(t, "logs", got.Logs, src.Logs)
+		})
+	}
+}
+
+// checkValues compara dos mapas de un elemento sin sus llaves, que cambian al importar
+func checkValues(t *testing.T, name string, got interface{}, want interface{}) {
+	t.Helper()
+
+	g, w := reflect.ValueOf(got), reflect.ValueOf(want)
+	if g.Len() != 1 || w.Len() != 1 {
+		t.Fatalf("%s = %d, want %d", name, g.Len(), w.Len())
+	}
+
+	gotKey, wantKey := g.MapKeys()[0], w.MapKeys()[0]
+	if gotKey.String() == wantKey.String() {
+		t.Errorf("%s kept ID %q", name, gotKey)
+	}
+	if !reflect.DeepEqual(g.MapIndex(gotKey).Interface(), w.MapIndex(wantKey).Interface()) {
+		t.Errorf("%s = %+v, want %+v", name, g.MapIndex(gotKey), w.MapIndex(wantKey))
+	}
+}
+
+func TestArchiveDuplicateLayerIDs(t *testing.T) {
+	src := archiveProject()
+	src.Data[1].ID = src.Data[0].ID
+
+	var buf bytes.Buffer
+	if err := export.WriteArchive(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Archive(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Data[0].ID == got.Data[1].ID {
+		t.Errorf("layers share ID %q", got.Data[0].ID)
+	}
+}
+
+func TestArchiveRejects(t *testing.T) {
+	valid := export.NewArchive(archiveProject())
+
+	encode := func(change func(a *models.Archive)) []byte {
+		a := valid
+		change(&a)
+		data, _ := json.Marshal(a)
+		return data
+	}
+
+	zipped := func(name string, content []byte) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		f, _ := zw.Create(name)
+		f.Write(content)
+		zw.Close()
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		err     error
+	}{
+		{"not JSON", []byte("hola"), ErrInvalidArchive},
+		{"other format", encode(func(a *models.Archive) { a.Format = "otro" }), ErrInvalidArchive},
+		{"no format", []byte(`{"version": 1}`), ErrInvalidArchive},
+		{"version zero", encode(func(a *models.Archive) { a.Version = 0 }), ErrArchiveVersion},
+		{"newer version", encode(func(a *models.Archive) { a.Version = models.ArchiveVersion + 1 }), ErrArchiveVersion},
+		{"zip without JSON", zipped("notas.txt", []byte("hola")), ErrInvalidArchive},
+		{"zip with other format", zipped("project.json", encode(func(a *models.Archive) { a.Format = "otro" })), ErrInvalidArchive},
+		{"broken zip", []byte("PK\x03\x04roto"), ErrInvalidArchive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Archive(bytes.NewReader(tt.content)); !errors.Is(err, tt.err) {
+				t.Errorf("Archive() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	// Un JSON con BOM y dentro de un zip con otro nombre se acepta
+	if _, err := Archive(bytes.NewReader(zipped("respaldo.json", append([]byte("\xef\xbb\xbf"), encode(func(*models.Archive) {})...)))); err != nil {
+		t.Errorf("Archive() with BOM = %v", err)
+	}
+}
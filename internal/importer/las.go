@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+var ErrInvalidLAS = errors.New("invalid LAS file")
+
+// lasLine es una línea de encabezado LAS: MNEM.UNIT  DATO : DESCRIPCION
+type lasLine struct {
+	mnemonic    string
+	unit        string
+	data        string
+	description string
+}
+
+func parseLASLine(line string) (lasLine, bool) {
+	dot := strings.Index(line, ".")
+	if dot < 0 {
+		return lasLine{}, false
+	}
+
+	l := lasLine{mnemonic: strings.TrimSpace(line[:dot])}
+	rest := line[dot+1:]
+
+	// La unidad va pegada al punto y termina en el primer espacio
+	if sp := strings.IndexAny(rest, " \t"); sp >= 0 {
+		l.unit = rest[:sp]
+		rest = rest[sp:]
+	} else {
+		l.unit = rest
+		rest = ""
+	}
+
+	if colon := strings.LastIndex(rest, ":"); colon >= 0 {
+		l.data = strings.TrimSpace(rest[:colon])
+		l.description = strings.TrimSpace(rest[colon+1:])
+	} else {
+		l.data = strings.TrimSpace(rest)
+	}
+
+	return l, l.mnemonic != ""
+}
+
+// ReadLAS lee un archivo LAS 2.0 (con o sin WRAP). La primera curva debe ser
+// la profundidad; el resto se guarda como curvas indexadas por esa profundidad
+func ReadLAS(r io.Reader) (*models.WellLog, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	log := &models.WellLog{Null: -999.25}
+	var curves []models.LogCurve
+	var values []float64
+	section := byte(0)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "~") {
+			if len(line) < 2 {
+				return nil, ErrInvalidLAS
+			}
+			section = strings.ToUpper(line[1:2])[0]
+			continue
+		}
+
+		switch section {
+		case 'V':
+			l, ok := parseLASLine(line)
+			if ok && strings.EqualFold(l.mnemonic, "VERS") && !strings.HasPrefix(l.data, "2") {
+				return nil, fmt.Errorf("%w: unsupported version %s", ErrInvalidLAS, l.data)
+			}
+		case 'W':
+			l, ok := parseLASLine(line)
+			if !ok {
+				continue
+			}
+			switch strings.ToUpper(l.mnemonic) {
+			case "NULL":
+				if v, err := strconv.ParseFloat(l.data, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+					log.Null = v
+				}
+			case "WELL":
+				log.Well = l.data
+			case "STRT":
+				log.Unit = l.unit
+			}
+		case 'C':
+			l, ok := parseLASLine(line)
+			if !ok {
+				continue
+			}
+			curves = append(curves, models.LogCurve{Mnemonic: l.mnemonic, Unit: l.unit, Description: l.description})
+		case 'A':
+			for _, field := range strings.Fields(line) {
+				v, err := strconv.ParseFloat(field, 64)
+				if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+					return nil, fmt.Errorf("%w: invalid value %q", ErrInvalidLAS, field)
+				}
+				values = append(values, v)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(curves) < 2 {
+		return nil, fmt.Errorf("%w: at least a depth and one curve are required", ErrInvalidLAS)
+	}
+	if len(values)%len(curves) != 0 {
+		return nil, fmt.Errorf("%w: data does not match the %d curves", ErrInvalidLAS, len(curves))
+	}
+	// Los valores se agrupan por registro, lo que sirve igual para WRAP YES o NO
+	samples := len(values) / len(curves)
+	if log.Unit == "" {
+		log.Unit = curves[0].Unit
+	}
+
+	log.Depth = make([]float64, samples)
+	log.Curves = curves[1:]
+	for i := range log.Curves {
+		log.Curves[i].Values = make([]float64, samples)
+	}
+
+	for s := 0; s < samples; s++ {
+		record := values[s*len(curves) : (s+1)*len(curves)]
+		log.Depth[s] = record[0]
+		for i := range log.Curves {
+			log.Curves[i].Values[s] = record[i+1]
+		}
+	}
+
+	return log, nil
+}
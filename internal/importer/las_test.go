@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const lasHeader = `~Version Information
+ VERS.   2.0 : CWLS LOG ASCII STANDARD - VERSION 2.0
+ WRAP.   NO  : ONE LINE PER DEPTH STEP
+~Well Information
+ STRT.M  0.0 : START DEPTH
+ NULL.   %s : NULL VALUE
+ WELL.   POZO 1 : WELL
+~Curve Information
+ DEPT.M      : DEPTH
+ GR.GAPI     : GAMMA RAY
+~A
+`
+
+func TestReadLAS(t *testing.T) {
+	tests := []struct {
+		name  string
+		null  string
+		data  string
+		want  []float64 // valores de GR
+		wantN float64
+		err   bool
+	}{
+		{"values", "-999.25", "0 10\n1 20\n", []float64{10, 20}, -999.25, false},
+		{"custom null", "-9999", "0 -9999\n1 20\n", []float64{-9999, 20}, -9999, false},
+		{"NaN null keeps the default", "NaN", "0 10\n", []float64{10}, -999.25, false},
+		{"infinite null keeps the default", "Inf", "0 10\n", []float64{10}, -999.25, false},
+		{"wrapped records", "-999.25", "0\n10\n1\n20\n", []float64{10, 20}, -999.25, false},
+		{"NaN value", "-999.25", "0 NaN\n", nil, 0, true},
+		{"infinite value", "-999.25", "0 +Inf\n", nil, 0, true},
+		{"incomplete record", "-999.25", "0 10\n1\n", nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := fmt.Sprintf(lasHeader, tt.null) + tt.data
+
+			log, err := ReadLAS(strings.NewReader(src))
+			if tt.err {
+				if !errors.Is(err, ErrInvalidLAS) {
+					t.Fatalf("ReadLAS() = %v, want %v", err, ErrInvalidLAS)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadLAS() = %v", err)
+			}
+
+			if log.Null != tt.wantN {
+				t.Errorf("null = %v, want %v", log.Null, tt.wantN)
+			}
+			if log.Well != "POZO 1" || log.Unit != "M" {
+				t.Errorf("well = %q, unit = %q", log.Well, log.Unit)
+			}
+			if len(log.Curves) != 1 || !reflect.DeepEqual(log.Curves[0].Values, tt.want) {
+				t.Errorf("curves = %+v, want GR %v", log.Curves, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLASVersion(t *testing.T) {
+	src := strings.Replace(fmt.Sprintf(lasHeader, "-999.25"), "2.0 :", "3.0 :", 1) + "0 10\n"
+
+	if _, err := ReadLAS(strings.NewReader(src)); !errors.Is(err, ErrInvalidLAS) {
+		t.Fatalf("ReadLAS() = %v, want %v", err, ErrInvalidLAS)
+	}
+}
@@ -0,0 +1,286 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/ProyectoT/api/internal/export"
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/lithammer/shortuuid/v4"
+)
+
+var ErrInvalidSedLog = errors.New("invalid SedLog file")
+
+// SedLogResult es el resultado de convertir un registro de SedLog en capas
+type SedLogResult struct {
+	Rows     []models.DataInfo       // capas desde el techo hacia abajo, como en StrataScope
+	Fosil    map[string]models.Fosil // símbolos de cada capa
+	Errors   []RowError              // capas descartadas
+	Warnings []RowError              // capas importadas con algún campo ignorado
+}
+
+// Clases granulométricas con los nombres alternativos que aceptamos
+var sedLogGrainAliases = map[string]string{
+	"mud":              "clay",
+	"very fine":        "vf",
+	"very fine sand":   "vf",
+	"fine":             "f",
+	"fine sand":        "f",
+	"medium":           "m",
+	"medium sand":      "m",
+	"coarse":           "c",
+	"coarse sand":      "c",
+	"very coarse":      "vc",
+	"very coarse sand": "vc",
+	"gran":             "granule",
+	"granules":         "granule",
+	"peb":              "pebble",
+	"pebbles":          "pebble",
+	"cob":              "cobble",
+	"cobbles":          "cobble",
+	"boul":             "boulder",
+	"boulders":         "boulder",
+}
+
+// ReadSedLog lee el CSV o XML de intercambio de SedLog. En XML cada elemento
+// cuyos hijos son solo texto (por ejemplo <bed>) es una capa y los nombres de
+// los hijos se leen igual que los encabezados del CSV
+func ReadSedLog(r io.Reader, format string) ([][]string, error) {
+	switch strings.ToLower(format) {
+	case "csv":
+		return readCSV(r)
+	case "xml":
+		return readSedLogXML(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// SedLog convierte las filas de SedLog (desde la base hacia arriba) en capas de StrataScope
+func SedLog(records [][]string) (*SedLogResult, error) {
+	if len(records) == 0 {
+		return nil, ErrEmptyTable
+	}
+
+	fields := map[string]int{}
+	metres := false
+
+	for i, h := range records[0] {
+		key := normalize(h)
+		switch {
+		case strings.HasPrefix(key, "thickness"):
+			if _, ok := fields["thickness"]; !ok {
+				fields["thickness"] = i
+				metres = strings.Contains(key, "(m)")
+			}
+		case key == "base boundary", key == "lithology", key == "grain size base", key == "grain size top", key == "symbols in bed":
+			fields[key] = i
+		case key == "notes column", key == "notes":
+			fields["notes"] = i
+		}
+	}
+
+	if _, ok := fields["thickness"]; !ok {
+		return nil, ErrMissingThickness
+	}
+
+	cell := func(record []string, field string) string {
+		i, ok := fields[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	type bed struct {
+		row     models.DataInfo
+		symbols []string
+	}
+
+	result := &SedLogResult{Fosil: map[string]models.Fosil{}}
+	var beds []bed
+
+	for n, record := range records[1:] {
+		rowNumber := n + 2
+
+		if isBlank(record) {
+			continue
+		}
+
+		height, err := parseNumber(cell(record, "thickness"))
+		if err == nil && metres {
+			height *= 100
+		}
+		if err != nil || height <= 0 || height > math.MaxFloat32 {
+			result.Errors = append(result.Errors, RowError{Row: rowNumber, Message: fmt.Sprintf("invalid thickness %q", cell(record, "thickness"))})
+			continue
+		}
+
+		row := models.NewShape()
+		row.Litologia.Height = float32(height)
+
+		if lithology := cell(record, "lithology"); lithology != "" {
+			if l, ok := export.SedLogLithologyByName(lithology); ok {
+				row.Litologia.File = l.File
+				row.Litologia.ColorFill = l.ColorFill
+			} else {
+				row.Litologia.File = lithology
+			}
+		}
+
+		if strings.EqualFold(cell(record, "base boundary"), "gradational") {
+			row.Litologia.Contact = "2" + row.Litologia.Contact[1:]
+		}
+
+		// En SedLog la base es la parte inferior de la capa: Y = 1 en el polígono
+		for _, g := range []struct {
+			field string
+			y     float32
+		}{{"grain size top", 0}, {"grain size base", 1}} {
+			value := cell(record, g.field)
+			if value == "" {
+				continue
+			}
+			x, ok := sedLogGrainX(value)
+			if !ok {
+				result.Warnings = append(result.Warnings, RowError{Row: rowNumber, Message: fmt.Sprintf("unknown grain size %q", value)})
+				continue
+			}
+			for i := range row.Litologia.Circles {
+				c := &row.Litologia.Circles[i]
+				if c.Movable && c.Y == g.y {
+					c.X = x
+				}
+			}
+		}
+
+		if notes := cell(record, "notes"); notes != "" {
+			row.Columns["Descripcion"] = notes
+		}
+
+		var symbols []string
+		for _, s := range strings.FieldsFunc(cell(record, "symbols in bed"), func(r rune) bool { return r == ',' || r == ';' }) {
+			if s = strings.TrimSpace(s); s != "" {
+				symbols = append(symbols, s)
+			}
+		}
+
+		beds = append(beds, bed{row: row, symbols: symbols})
+	}
+
+	// SedLog va de la base al techo; StrataScope del techo a la base
+	var y float32
+	for i := len(beds) - 1; i >= 0; i-- {
+		b := beds[i]
+		top, base := y, y+b.row.Litologia.Height
+		y = base
+
+		result.Rows = append(result.Rows, b.row)
+		for _, symbol := range b.symbols {
+			result.Fosil[shortuuid.New()] = models.Fosil{Upper: top, Lower: base, FosilImg: symbol, X: 0.5}
+		}
+	}
+
+	return result, nil
+}
+
+// sedLogGrainX traduce una clase granulométrica de SedLog a la posición X del polígono
+func sedLogGrainX(value string) (float32, bool) {
+	key := normalize(value)
+	if alias, ok := sedLogGrainAliases[key]; ok {
+		key = alias
+	}
+
+	for i, size := range export.SedLogGrainSizes {
+		if size == key {
+			return export.SedLogGrainX(i), true
+		}
+	}
+	return 0, false
+}
+
+// readSedLogXML aplana el XML en filas: los nombres de los campos en la primera
+// fila y una fila por cada elemento que solo contiene campos de texto
+func readSedLogXML(r io.Reader) ([][]string, error) {
+	type node struct {
+		name     string
+		text     strings.Builder
+		children []*node
+	}
+
+	var (
+		stack   []*node
+		records []map[string]string
+		header  []string
+		seen    = map[string]bool{}
+	)
+
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSedLog, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{name: t.Name.Local}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			}
+			stack = append(stack, n)
+
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+
+		case xml.EndElement:
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if len(n.children) == 0 {
+				continue
+			}
+
+			record := map[string]string{}
+			for _, child := range n.children {
+				if len(child.children) > 0 {
+					record = nil
+					break
+				}
+				record[child.name] = strings.TrimSpace(child.text.String())
+				if !seen[child.name] {
+					seen[child.name] = true
+					header = append(header, child.name)
+				}
+			}
+			if record != nil {
+				records = append(records, record)
+			}
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, ErrEmptyTable
+	}
+
+	rows := [][]string{header}
+	for _, record := range records {
+		row := make([]string, len(header))
+		for i, name := range header {
+			row[i] = record[name]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
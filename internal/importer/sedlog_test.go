@@ -0,0 +1,76 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSedLogThickness(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		values  []string
+		heights []float32 // desde el techo
+		errors  []int
+	}{
+		{"centimetres", "Thickness (cm)", []string{"10", "2,5"}, []float32{2.5, 10}, nil},
+		{"metres", "Thickness (m)", []string{"1.5", "0.2"}, []float32{20, 150}, nil},
+		{"largest metres", "Thickness (m)", []string{"3e36"}, []float32{3e38}, nil},
+		{"metres overflow", "Thickness (m)", []string{"1", "4e36", "1e38"}, []float32{100}, []int{3, 4}},
+		{"centimetres overflow", "Thickness (cm)", []string{"4e38"}, nil, []int{2}},
+		{"not finite", "Thickness", []string{"NaN", "Inf", "-Inf", "3"}, []float32{3}, []int{2, 3, 4}},
+		{"not positive", "Thickness", []string{"0", "-1", "abc"}, nil, []int{2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := [][]string{{tt.header, "Lithology"}}
+			for _, v := range tt.values {
+				records = append(records, []string{v, ""})
+			}
+
+			result, err := SedLog(records)
+			if err != nil {
+				t.Fatalf("SedLog() = %v", err)
+			}
+
+			var heights []float32
+			for _, row := range result.Rows {
+				heights = append(heights, row.Litologia.Height)
+			}
+			var errors []int
+			for _, e := range result.Errors {
+				errors = append(errors, e.Row)
+			}
+
+			if !reflect.DeepEqual(heights, tt.heights) {
+				t.Errorf("heights = %v, want %v", heights, tt.heights)
+			}
+			if !reflect.DeepEqual(errors, tt.errors) {
+				t.Errorf("rows with errors = %v, want %v", errors, tt.errors)
+			}
+		})
+	}
+}
+
+func TestReadSedLogXML(t *testing.T) {
+	src := `<log><beds>
+		<bed><thickness>10</thickness><lithology>sandstone</lithology></bed>
+		<bed><thickness>5</thickness><notes>top</notes></bed>
+	</beds></log>`
+
+	records, err := ReadSedLog(strings.NewReader(src), "xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"thickness", "lithology", "notes"},
+		{"10", "sandstone", ""},
+		{"5", "", "top"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+}
@@ -0,0 +1,309 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ProyectoT/api/internal/models"
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	ErrUnknownFormat    = errors.New("unknown file format")
+	ErrEmptyTable       = errors.New("the file has no rows")
+	ErrMissingThickness = errors.New("missing thickness column")
+	ErrNotFinite        = errors.New("the number must be finite")
+)
+
+// RowError describe un problema en una fila de la planilla (numerada como en la planilla, con el encabezado en la fila 1)
+type RowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// TableResult es el resultado de convertir una planilla en capas
+type TableResult struct {
+	Rows       []models.DataInfo
+	NewColumns []string   // encabezados que no existen en Config.Columns
+	Ignored    []string   // encabezados que se reconocen pero no se importan
+	Errors     []RowError // filas descartadas
+}
+
+// Encabezados aceptados para el espesor de la capa
+var thicknessHeaders = []string{"espesor", "thickness", "height", "potencia"}
+
+// Encabezados que se calculan al exportar y no se vuelven a leer
+var derivedHeaders = []string{"top", "base", "techo", "index", "indice", "estructura fosil", "facie", "muestras"}
+
+// Campos de LitologiaStruc que se pueden fijar desde la planilla
+var litologiaHeaders = map[string]string{
+	"litologia":   "File",
+	"file":        "File",
+	"pattern":     "File",
+	"colorfill":   "ColorFill",
+	"colorstroke": "ColorStroke",
+	"contact":     "Contact",
+	"contacto":    "Contact",
+	"zoom":        "Zoom",
+	"rotation":    "Rotation",
+	"tension":     "Tension",
+}
+
+// ReadRows lee una planilla CSV o XLSX y devuelve sus celdas. En XLSX se usa
+// la hoja indicada o la primera si sheet es vacío
+func ReadRows(r io.Reader, format string, sheet string) ([][]string, error) {
+	switch strings.ToLower(format) {
+	case "csv":
+		return readCSV(r)
+	case "xlsx":
+		return readXLSX(r, sheet)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = detectDelimiter(content)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	return reader.ReadAll()
+}
+
+// detectDelimiter elige entre coma, punto y coma o tabulación según la primera línea
+func detectDelimiter(content []byte) rune {
+	line := content
+	if i := bytes.IndexByte(content, '\n'); i >= 0 {
+		line = content[:i]
+	}
+
+	best, count := ',', bytes.Count(line, []byte{','})
+	for _, d := range []rune{';', '\t'} {
+		if n := bytes.Count(line, []byte(string(d))); n > count {
+			best, count = d, n
+		}
+	}
+	return best
+}
+
+func readXLSX(r io.Reader, sheet string) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if sheet == "" {
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, ErrEmptyTable
+		}
+		sheet = sheets[0]
+	}
+
+	return f.GetRows(sheet)
+}
+
+// Table convierte las filas de una planilla en capas. La primera fila son los
+// encabezados: se asocian a Config.Columns por nombre, la columna de espesor
+// va a Litologia.Height y los campos de Litologia se reconocen por su nombre
+func Table(records [][]string, columns []models.Column) (*TableResult, error) {
+	if len(records) == 0 {
+		return nil, ErrEmptyTable
+	}
+
+	known := make(map[string]string, len(columns))
+	for _, col := range columns {
+		known[normalize(col.Name)] = col.Name
+	}
+
+	result := &TableResult{}
+
+	type target struct {
+		column    string // columna de texto en DataInfo.Columns
+		litologia string // campo de LitologiaStruc
+		height    bool
+	}
+
+	header := records[0]
+	targets := make([]*target, len(header))
+	hasHeight := false
+
+	for i, h := range header {
+		name := strings.TrimSpace(h)
+		key := normalize(name)
+
+		switch {
+		case key == "":
+			continue
+		case containsString(thicknessHeaders, key):
+			if !hasHeight {
+				targets[i] = &target{height: true}
+				hasHeight = true
+			}
+		case litologiaHeaders[strings.ReplaceAll(key, " ", "")] != "":
+			targets[i] = &target{litologia: litologiaHeaders[strings.ReplaceAll(key, " ", "")]}
+		case containsString(derivedHeaders, key):
+			result.Ignored = append(result.Ignored, name)
+		case known[key] != "":
+			targets[i] = &target{column: known[key]}
+		default:
+			targets[i] = &target{column: name}
+			known[key] = name
+			result.NewColumns = append(result.NewColumns, name)
+		}
+	}
+
+	if !hasHeight {
+		return nil, ErrMissingThickness
+	}
+
+	for n, record := range records[1:] {
+		rowNumber := n + 2
+
+		if isBlank(record) {
+			continue
+		}
+
+		row := models.NewShape()
+		var rowErr error
+		seenHeight := false
+
+		for i, cell := range record {
+			if i >= len(targets) || targets[i] == nil {
+				continue
+			}
+			t := targets[i]
+			cell = strings.TrimSpace(cell)
+
+			switch {
+			case t.height:
+				if cell == "" {
+					continue
+				}
+				seenHeight = true
+				height, err := parseNumber(cell)
+				if err != nil || height <= 0 {
+					rowErr = fmt.Errorf("invalid thickness %q", cell)
+					break
+				}
+				row.Litologia.Height = float32(height)
+			case t.litologia != "":
+				if cell == "" {
+					continue
+				}
+				if err := setLitologia(&row.Litologia, t.litologia, cell); err != nil {
+					rowErr = err
+				}
+			default:
+				row.Columns[t.column] = cell
+			}
+
+			if rowErr != nil {
+				break
+			}
+		}
+
+		if rowErr == nil && !seenHeight {
+			rowErr = errors.New("missing thickness")
+		}
+
+		if rowErr != nil {
+			result.Errors = append(result.Errors, RowError{Row: rowNumber, Message: rowErr.Error()})
+			continue
+		}
+
+		result.Rows = append(result.Rows, row)
+	}
+
+	return result, nil
+}
+
+func setLitologia(lit *models.LitologiaStruc, field string, value string) error {
+	switch field {
+	case "File":
+		lit.File = value
+	case "ColorFill":
+		lit.ColorFill = value
+	case "ColorStroke":
+		lit.ColorStroke = value
+	case "Contact":
+		lit.Contact = value
+	case "Zoom", "Rotation":
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", strings.ToLower(field), value)
+		}
+		if field == "Zoom" {
+			lit.Zoom = v
+		} else {
+			lit.Rotation = v
+		}
+	case "Tension":
+		v, err := parseNumber(value)
+		if err != nil {
+			return fmt.Errorf("invalid tension %q", value)
+		}
+		lit.Tension = float32(v)
+	}
+	return nil
+}
+
+// parseNumber acepta decimales con punto o con coma. NaN, infinito y los
+// valores que no entran en un float32 (así se guardan) no se aceptan porque no
+// se pueden enviar como JSON
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.Abs(v) > math.MaxFloat32 {
+		return 0, ErrNotFinite
+	}
+	return v, nil
+}
+
+var accents = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// normalize compara encabezados sin mayúsculas, tildes ni espacios repetidos
+func normalize(s string) string {
+	s = accents.Replace(strings.ToLower(strings.TrimSpace(s)))
+	s = strings.NewReplacer("_", " ", "-", " ").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func isBlank(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(slice []string, value string) bool {
+	for _, v := range slice {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ProyectoT/api/internal/models"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  error
+	}{
+		{"12", 12, nil},
+		{" 1.5 ", 1.5, nil},
+		{"1,5", 1.5, nil},
+		{"-3.25", -3.25, nil},
+		{"3.4e38", 3.4e38, nil},
+		{"NaN", 0, ErrNotFinite},
+		{"nan", 0, ErrNotFinite},
+		{"Inf", 0, ErrNotFinite},
+		{"-Infinity", 0, ErrNotFinite},
+		{"1e39", 0, ErrNotFinite},
+		{"-1e300", 0, ErrNotFinite},
+	}
+
+	for _, tt := range tests {
+		got, err := parseNumber(tt.in)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("parseNumber(%q) = %v, %v; want %v, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "1.2.3"} {
+		if _, err := parseNumber(in); err == nil {
+			t.Errorf("parseNumber(%q) accepted an invalid number", in)
+		}
+	}
+}
+
+func TestTableThickness(t *testing.T) {
+	records := [][]string{
+		{"Espesor", "Notas", "Tension"},
+		{"10", "arenisca", "0,5"},
+		{"NaN", "nan", ""},
+		{"1e39", "overflow", ""},
+		{"-2", "negativo", ""},
+		{"", "sin espesor", ""},
+		{"3", "tension", "Inf"},
+		{"", "", ""},
+		{"2,5", "coma", ""},
+	}
+	columns := []models.Column{{Name: "Espesor"}, {Name: "Notas"}}
+
+	result, err := Table(records, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Rows) != 2 {
+		t.Fatalf("rows = %d, want 2", len(result.Rows))
+	}
+	if h := result.Rows[0].Litologia.Height; h != 10 {
+		t.Errorf("first height = %v, want 10", h)
+	}
+	if h := result.Rows[1].Litologia.Height; h != 2.5 {
+		t.Errorf("second height = %v, want 2.5", h)
+	}
+
+	var rows []int
+	for _, e := range result.Errors {
+		rows = append(rows, e.Row)
+	}
+	if want := []int{3, 4, 5, 6, 7}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows with errors = %v, want %v", rows, want)
+	}
+}
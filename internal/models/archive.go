@@ -0,0 +1,28 @@
+package models
+
+// ArchiveFormat y ArchiveVersion identifican los respaldos de proyectos
+const (
+	ArchiveFormat  = "stratascope-project"
+	ArchiveVersion = 1
+)
+
+// Archive es el respaldo completo de un proyecto para llevarlo a otra
+// instalación o guardarlo fuera de la base de datos
+type Archive struct {
+	Format     string         `json:"format"`
+	Version    int            `json:"version"`
+	ExportedAt string         `json:"exportedAt"`
+	SourceID   string         `json:"sourceId"`
+	Project    ArchiveProject `json:"project"`
+}
+
+// ArchiveProject es el contenido del proyecto sin su ID ni sus enlaces de invitación
+type ArchiveProject struct {
+	ProjectInfo ProjectInfo                `json:"projectInfo"`
+	Data        []DataInfo                 `json:"data"`
+	Config      Config                     `json:"config"`
+	Fosil       map[string]Fosil           `json:"fosil"`
+	Facies      map[string][]FaciesSection `json:"facies"`
+	Muestras    map[string]Muestra         `json:"muestras"`
+	Logs        map[string]WellLog         `json:"logs,omitempty"`
+}
@@ -1,7 +1,59 @@
 package models
 
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
 type Comment struct {
 	Content   string   `bson:"content"`
 	CreatedAt string   `bson:"createdAt"`
 	Labels    []string `bson:"labels"`
 }
+
+// Lugares del proyecto a los que se puede anclar un hilo de comentarios
+const (
+	AnchorLayer   = "layer"   // una capa (fila de Data)
+	AnchorDepth   = "depth"   // un intervalo de profundidad
+	AnchorFosil   = "fosil"   // un fósil
+	AnchorMuestra = "muestra" // una muestra
+	AnchorFacie   = "facie"   // una sección de facie
+)
+
+// Thread es un hilo de comentarios de un proyecto, guardado en project_comments
+type Thread struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectID  primitive.ObjectID `bson:"projectId" json:"projectId"`
+	Anchor     Anchor             `bson:"anchor" json:"anchor"`
+	Author     string             `bson:"author" json:"author"`
+	Comments   []ThreadComment    `bson:"comments" json:"comments"` // el primero es el que abrió el hilo
+	Resolved   bool               `bson:"resolved" json:"resolved"`
+	ResolvedBy string             `bson:"resolvedBy,omitempty" json:"resolvedBy,omitempty"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt  time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Anchor indica dónde está el hilo. Según Type se usan RowID, Top y Bottom,
+// ID (fósil o muestra) o Facie con Index (las secciones no tienen ID). Y1 y Y2
+// no se guardan: son la posición actual de la sección, que cambia al editar o
+// invertir la columna, y se completan al leer el hilo
+type Anchor struct {
+	Type   string  `bson:"type" json:"type"`
+	RowID  string  `bson:"rowId,omitempty" json:"rowId,omitempty"`
+	Top    float64 `bson:"top,omitempty" json:"top,omitempty"`
+	Bottom float64 `bson:"bottom,omitempty" json:"bottom,omitempty"`
+	ID     string  `bson:"id,omitempty" json:"id,omitempty"`
+	Facie  string  `bson:"facie,omitempty" json:"facie,omitempty"`
+	Index  *int    `bson:"index,omitempty" json:"index,omitempty"`
+	Y1     float32 `bson:"-" json:"y1,omitempty"`
+	Y2     float32 `bson:"-" json:"y2,omitempty"`
+}
+
+type ThreadComment struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	Author    string             `bson:"author" json:"author"`
+	Content   string             `bson:"content" json:"content"`
+	Mentions  []string           `bson:"mentions,omitempty" json:"mentions,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "encoding/json"
+
+// UserHistory es el historial de deshacer/rehacer de un usuario, se guarda con
+// el proyecto para que sobreviva al cierre de la sala
+type UserHistory struct {
+	User string    `bson:"user" json:"user"`
+	Undo []Command `bson:"undo" json:"undo"`
+	Redo []Command `bson:"redo" json:"redo"`
+}
+
+// Command es un paso del historial: la acción y el estado que necesita para
+// aplicarse o revertirse
+type Command struct {
+	Action string          `bson:"action" json:"action"`
+	Data   json.RawMessage `bson:"data" json:"data"`
+}
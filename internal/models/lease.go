@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// RoomLease indica qué instancia de la API tiene abierta una sala. Se guarda
+// en room_leases y vence si la instancia deja de renovarlo. Epoch aumenta cada
+// vez que la sala se vuelve a tomar, para rechazar lo que guarde el dueño anterior
+type RoomLease struct {
+	RoomID    string    `bson:"_id" json:"roomId"`
+	Owner     string    `bson:"owner" json:"owner"`
+	URL       string    `bson:"url" json:"url"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+	Epoch     int64     `bson:"epoch" json:"epoch"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Operation es una acción de edición guardada en el registro project_operations.
+// Seq ordena las operaciones de un proyecto y Project.Seq indica hasta cuál
+// está incluida en un estado guardado
+type Operation struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectID primitive.ObjectID `bson:"projectId" json:"projectId"`
+	Seq       int64              `bson:"seq" json:"seq"`
+	User      string             `bson:"user" json:"user"`
+	Action    string             `bson:"action" json:"action"`
+	Data      json.RawMessage    `bson:"data" json:"data"`
+	Ref       string             `bson:"ref,omitempty" json:"ref,omitempty"` // ID generado al aplicar la acción (fósil, muestra, registro)
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	Conn      string             `bson:"-" json:"-"` // conexión que la envió, para los bloqueos de edición
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Motivos por los que se guarda una revisión
+const (
+	RevisionManual   = "manual"   // acción save del editor
+	RevisionAutosave = "autosave" // guardado automático por cantidad de acciones o por tiempo
+	RevisionClose    = "close"    // guardado al salir el último usuario de la sala
+	RevisionRestore  = "restore"  // estado previo a restaurar otra revisión
+)
+
+// Revision es una copia inmutable del proyecto guardada en project_revisions
+type Revision struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectID primitive.ObjectID `bson:"projectId" json:"projectId"`
+	Author    string             `bson:"author" json:"author"`
+	Reason    string             `bson:"reason" json:"reason"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	Project   Project            `bson:"project" json:"project"`
+}
+
+// RevisionInfo son los datos de una revisión sin la copia del proyecto, para listarlas
+type RevisionInfo struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectID primitive.ObjectID `bson:"projectId" json:"projectId"`
+	Author    string             `bson:"author" json:"author"`
+	Reason    string             `bson:"reason" json:"reason"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	Layers    int                `bson:"layers" json:"layers"`
+}
@@ -21,6 +21,26 @@ type Config struct {
 	IsInverted bool     `bson:"isInverted" json:"IsInverted"`
 }
 
+// DefaultConfig devuelve las columnas con las que parte todo proyecto nuevo
+func DefaultConfig() Config {
+	return Config{
+		Columns: []Column{
+			{Name: "Sistema", Visible: true, Removable: true},
+			{Name: "Edad", Visible: true, Removable: true},
+			{Name: "Formacion", Visible: true, Removable: true},
+			{Name: "Miembro", Visible: true, Removable: true},
+			{Name: "Espesor", Visible: true, Removable: false},
+			{Name: "Litologia", Visible: true, Removable: false},
+			{Name: "Estructura fosil", Visible: true, Removable: false},
+			{Name: "Facie", Visible: true, Removable: false},
+			{Name: "Muestras", Visible: true, Removable: false},
+			{Name: "AmbienteDepositacional", Visible: true, Removable: true},
+			{Name: "Descripcion", Visible: true, Removable: true},
+		},
+		IsInverted: false,
+	}
+}
+
 type Shared struct {
 	Pass string `json:"pass"`
 }
@@ -33,7 +53,11 @@ type Project struct {
 	Fosil       map[string]Fosil           `bson:"fosil"`
 	Facies      map[string][]FaciesSection `bson:"facies"`
 	Muestras    map[string]Muestra         `bson:"muestras"`
+	Logs        map[string]WellLog         `bson:"logs"`
 	Shared      Shared                     `bson:"shared"`
+	Seq         int64                      `bson:"seq"` // última operación incluida en este estado
+	History     []UserHistory              `bson:"history" json:"-"`
+	LeaseEpoch  int64                      `bson:"leaseEpoch,omitempty" json:"-"` // lease con el que se abrió la sala, ver RoomLease
 }
 
 type InfoProject struct {
@@ -42,15 +66,24 @@ type InfoProject struct {
 }
 
 type ProjectInfo struct {
-	Name         string  `bson:"name"`
-	Owner        string  `bson:"owner"`
-	Members      Members `bson:"members"`
-	CreationDate string  `bson:"creationdate"`
-	Description  string  `bson:"description"`
-	Location     string  `bson:"location"`
-	Lat          float64 `bson:"lat"`
-	Long         float64 `bson:"long"`
-	Visible      bool    `bson:"visible"`
+	Name         string   `bson:"name"`
+	Owner        string   `bson:"owner"`
+	Members      Members  `bson:"members"`
+	CreationDate string   `bson:"creationdate"`
+	Description  string   `bson:"description"`
+	Location     string   `bson:"location"`
+	Lat          float64  `bson:"lat"`
+	Long         float64  `bson:"long"`
+	Visible      bool     `bson:"visible"`
+	ForkedFrom   *ForkRef `bson:"forkedFrom,omitempty"`
+}
+
+// ForkRef identifica el proyecto y el estado desde el que se copió un proyecto
+type ForkRef struct {
+	ProjectID string `bson:"projectId" json:"projectId"`
+	Name      string `bson:"name" json:"name"`
+	Seq       int64  `bson:"seq" json:"seq"`
+	Date      string `bson:"date" json:"date"`
 }
 
 type Data_project struct {
@@ -62,6 +95,7 @@ type Data_project struct {
 }
 
 type DataInfo struct {
+	ID        string                 `json:"ID,omitempty" bson:"id,omitempty"` // identificador estable de la capa, no cambia al moverla
 	Columns   map[string]interface{} `json:"Columns"`
 	Litologia LitologiaStruc         `json:"Litologia"`
 }
@@ -104,6 +138,23 @@ type Muestra struct {
 	X           float32 `json:"x"`
 }
 
+// WellLog es un registro de pozo (LAS) asociado al proyecto, indexado por profundidad
+type WellLog struct {
+	Well   string     `json:"well"`
+	Source string     `json:"source"`
+	Unit   string     `json:"unit"`
+	Null   float64    `json:"null"`
+	Depth  []float64  `json:"depth"`
+	Curves []LogCurve `json:"curves"`
+}
+
+type LogCurve struct {
+	Mnemonic    string    `json:"mnemonic"`
+	Unit        string    `json:"unit"`
+	Description string    `json:"description"`
+	Values      []float64 `json:"values"`
+}
+
 func NewFosil(upper float32, lower float32, fosilImg string, x float32) Fosil {
 	return Fosil{
 		Upper:    upper,
@@ -154,3 +205,92 @@ func NewShape() DataInfo {
 		},
 	}
 }
+
+// Clone devuelve una copia profunda del proyecto, para poder leerlo o
+// modificarlo sin tocar el original
+func (p *Project) Clone() *Project {
+	clone := *p
+
+	clone.ProjectInfo.Members = Members{
+		Owner:   p.ProjectInfo.Members.Owner,
+		Editors: append([]string{}, p.ProjectInfo.Members.Editors...),
+		Readers: append([]string{}, p.ProjectInfo.Members.Readers...),
+	}
+
+	if p.ProjectInfo.ForkedFrom != nil {
+		forkedFrom := *p.ProjectInfo.ForkedFrom
+		clone.ProjectInfo.ForkedFrom = &forkedFrom
+	}
+
+	clone.Data = make([]DataInfo, len(p.Data))
+	for i, row := range p.Data {
+		clone.Data[i] = row.Clone()
+	}
+
+	clone.Config.Columns = append([]Column{}, p.Config.Columns...)
+
+	clone.Fosil = make(map[string]Fosil, len(p.Fosil))
+	for k, v := range p.Fosil {
+		clone.Fosil[k] = v
+	}
+
+	clone.Muestras = make(map[string]Muestra, len(p.Muestras))
+	for k, v := range p.Muestras {
+		clone.Muestras[k] = v
+	}
+
+	clone.Facies = make(map[string][]FaciesSection, len(p.Facies))
+	for k, v := range p.Facies {
+		clone.Facies[k] = append([]FaciesSection{}, v...)
+	}
+
+	if p.Logs != nil {
+		clone.Logs = make(map[string]WellLog, len(p.Logs))
+		for k, v := range p.Logs {
+			clone.Logs[k] = v.Clone()
+		}
+	}
+
+	// Los comandos no se modifican una vez codificados, basta con copiar las listas
+	if p.History != nil {
+		clone.History = make([]UserHistory, len(p.History))
+		for i, h := range p.History {
+			clone.History[i] = UserHistory{
+				User: h.User,
+				Undo: append([]Command{}, h.Undo...),
+				Redo: append([]Command{}, h.Redo...),
+			}
+		}
+	}
+
+	return &clone
+}
+
+// Clone devuelve una copia profunda del registro
+func (l WellLog) Clone() WellLog {
+	clone := l
+	clone.Depth = append([]float64{}, l.Depth...)
+	clone.Curves = make([]LogCurve, len(l.Curves))
+	for i, c := range l.Curves {
+		c.Values = append([]float64{}, c.Values...)
+		clone.Curves[i] = c
+	}
+	return clone
+}
+
+// Clone devuelve una copia profunda de una capa
+func (d DataInfo) Clone() DataInfo {
+	columns := make(map[string]interface{}, len(d.Columns))
+	for k, v := range d.Columns {
+		columns[k] = v
+	}
+
+	litologia := d.Litologia
+	litologia.Circles = append([]CircleStruc{}, d.Litologia.Circles...)
+
+	return DataInfo{
+		ID:        d.ID,
+		Columns:   columns,
+		Litologia: litologia,
+	}
+}
@@ -0,0 +1,116 @@
+package pubsub
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Mongo reparte los mensajes entre instancias con la colección room_events:
+// Publish inserta un documento y cada instancia lo recibe con un change stream
+// y lo entrega a sus suscriptores. Necesita que la base de datos sea un replica
+// set (Atlas lo es). Los documentos se borran solos después de eventTTL
+type Mongo struct {
+	events *mongo.Collection
+	local  *Local
+	cancel context.CancelFunc
+}
+
+var (
+	eventTTL    = time.Minute
+	rewatchWait = time.Second // espera antes de reabrir el change stream
+)
+
+type event struct {
+	Topic     string    `bson:"topic"`
+	Message   []byte    `bson:"message"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+var insertsOnly = mongo.Pipeline{{{Key: "$match", Value: bson.D{{Key: "operationType", Value: "insert"}}}}}
+
+func NewMongo(ctx context.Context, db *mongo.Database) (*Mongo, error) {
+	events := db.Collection("room_events")
+
+	_, err := events.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "createdAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(eventTTL / time.Second)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Se abre acá para que un error de configuración se vea al iniciar
+	stream, err := events.Watch(ctx, insertsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	m := &Mongo{events: events, local: NewLocal(), cancel: cancel}
+
+	go m.run(runCtx, stream)
+
+	return m, nil
+}
+
+func (m *Mongo) Publish(ctx context.Context, topic string, msg []byte) error {
+	_, err := m.events.InsertOne(ctx, event{Topic: topic, Message: msg, CreatedAt: time.Now().UTC()})
+	return err
+}
+
+func (m *Mongo) Subscribe(topic string, handler func(msg []byte)) (func(), error) {
+	return m.local.Subscribe(topic, handler)
+}
+
+// Close deja de recibir mensajes
+func (m *Mongo) Close() {
+	m.cancel()
+}
+
+// run entrega los mensajes del change stream. Si se corta, lo vuelve a abrir
+// desde el último mensaje recibido
+func (m *Mongo) run(ctx context.Context, stream *mongo.ChangeStream) {
+	for {
+		for stream.Next(ctx) {
+			var change struct {
+				Event event `bson:"fullDocument"`
+			}
+			if err := stream.Decode(&change); err != nil {
+				log.Println("Error leyendo el evento: ", err)
+				continue
+			}
+
+			m.local.Publish(ctx, change.Event.Topic, change.Event.Message)
+		}
+
+		if err := stream.Err(); err != nil && ctx.Err() == nil {
+			log.Println("Error en el change stream de eventos: ", err)
+		}
+		resume := stream.ResumeToken()
+		stream.Close(context.Background())
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(rewatchWait):
+			}
+
+			opts := options.ChangeStream()
+			if resume != nil {
+				opts.SetResumeAfter(resume)
+			}
+
+			var err error
+			if stream, err = m.events.Watch(ctx, insertsOnly, opts); err == nil {
+				break
+			}
+			log.Println("Error abriendo el change stream de eventos: ", err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ProyectoT/api/settings"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Broker reparte mensajes entre las instancias de la API. Cada sala tiene un
+// tema y la instancia dueña de la sala se suscribe a él (ver api/cluster.go).
+// Local sirve para una sola instancia y para pruebas; con varias instancias se
+// usa Mongo (BROKER=mongo), que comparte los mensajes por la base de datos
+type Broker interface {
+	Publish(ctx context.Context, topic string, msg []byte) error
+	// Subscribe llama a handler con cada mensaje del tema hasta que se llame a la
+	// función devuelta. handler no debe bloquearse
+	Subscribe(topic string, handler func(msg []byte)) (func(), error)
+}
+
+// New devuelve el broker indicado en la configuración
+func New(ctx context.Context, s *settings.Settings, db *mongo.Database) (Broker, error) {
+	switch s.Broker {
+	case "", "local":
+		return NewLocal(), nil
+	case "mongo":
+		return NewMongo(ctx, db)
+	default:
+		return nil, fmt.Errorf("unknown broker %q", s.Broker)
+	}
+}
+
+// Local es un broker dentro del proceso. Entrega los mensajes en orden y en
+// la goroutine que publica
+type Local struct {
+	mu     sync.RWMutex
+	nextID int
+	topics map[string]map[int]func([]byte)
+}
+
+func NewLocal() *Local {
+	return &Local{topics: make(map[string]map[int]func([]byte))}
+}
+
+func (l *Local) Publish(ctx context.Context, topic string, msg []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.mu.RLock()
+	handlers := make([]func([]byte), 0, len(l.topics[topic]))
+	for _, h := range l.topics[topic] {
+		handlers = append(handlers, h)
+	}
+	l.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(msg)
+	}
+	return nil
+}
+
+func (l *Local) Subscribe(topic string, handler func(msg []byte)) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextID++
+	id := l.nextID
+
+	if l.topics[topic] == nil {
+		l.topics[topic] = make(map[int]func([]byte))
+	}
+	l.topics[topic][id] = handler
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			delete(l.topics[topic], id)
+			if len(l.topics[topic]) == 0 {
+				delete(l.topics, topic)
+			}
+		})
+	}, nil
+}
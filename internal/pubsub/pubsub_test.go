@@ -0,0 +1,91 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ProyectoT/api/settings"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		broker string
+		local  bool
+		err    bool
+	}{
+		{"", true, false},
+		{"local", true, false},
+		{"redis", false, true},
+	}
+
+	for _, tt := range tests {
+		b, err := New(context.Background(), &settings.Settings{Broker: tt.broker}, nil)
+		if (err != nil) != tt.err {
+			t.Errorf("New(%q) error = %v, want error %v", tt.broker, err, tt.err)
+		}
+		if _, ok := b.(*Local); ok != tt.local {
+			t.Errorf("New(%q) = %T", tt.broker, b)
+		}
+	}
+}
+
+func TestLocal(t *testing.T) {
+	l := NewLocal()
+	ctx := context.Background()
+
+	var a1, a2, b []string
+	unsubA1, _ := l.Subscribe("a", func(msg []byte) { a1 = append(a1, string(msg)) })
+	unsubA2, _ := l.Subscribe("a", func(msg []byte) { a2 = append(a2, string(msg)) })
+	unsubB, _ := l.Subscribe("b", func(msg []byte) { b = append(b, string(msg)) })
+
+	l.Publish(ctx, "a", []byte("1"))
+	l.Publish(ctx, "b", []byte("2"))
+	l.Publish(ctx, "a", []byte("3"))
+	l.Publish(ctx, "c", []byte("nadie"))
+
+	unsubA1()
+	unsubA1() // se puede llamar más de una vez
+	l.Publish(ctx, "a", []byte("4"))
+
+	unsubA2()
+	unsubB()
+	l.Publish(ctx, "a", []byte("5"))
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"first subscriber", a1, []string{"1", "3"}},
+		{"second subscriber", a2, []string{"1", "3", "4"}},
+		{"other topic", b, []string{"2"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if len(l.topics) != 0 {
+		t.Errorf("topics without subscribers were kept: %v", l.topics)
+	}
+}
+
+func TestLocalCanceledContext(t *testing.T) {
+	l := NewLocal()
+
+	called := false
+	l.Subscribe("a", func(msg []byte) { called = true })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := l.Publish(ctx, "a", []byte("1")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Publish() = %v, want %v", err, context.Canceled)
+	}
+	if called {
+		t.Error("the message was delivered with a canceled context")
+	}
+}
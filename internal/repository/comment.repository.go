@@ -2,8 +2,12 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/ProyectoT/api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func (r *repo) HandleAddComment(ctx context.Context, comment models.Comment) error {
@@ -19,3 +23,115 @@ func (r *repo) HandleAddComment(ctx context.Context, comment models.Comment) err
 	return nil
 
 }
+
+// Crea un hilo de comentarios de un proyecto
+func (r *repo) CreateThread(ctx context.Context, thread models.Thread) (string, error) {
+	threads := r.db.Collection("project_comments")
+
+	thread.ID = primitive.NilObjectID
+
+	result, err := threads.InsertOne(ctx, thread)
+	if err != nil {
+		return "", err
+	}
+
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// Obtiene los hilos de un proyecto, del más reciente al más antiguo. Con
+// resolved en nil devuelve todos
+func (r *repo) GetThreads(ctx context.Context, projectID string, resolved *bool) ([]models.Thread, error) {
+	threads := r.db.Collection("project_comments")
+
+	objectID, err := primitive.ObjectIDFromHex(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"projectId": objectID}
+	if resolved != nil {
+		filter["resolved"] = *resolved
+	}
+
+	cursor, err := threads.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "updatedAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	list := []models.Thread{}
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Obtiene un hilo de un proyecto
+func (r *repo) GetThread(ctx context.Context, projectID string, threadID string) (*models.Thread, error) {
+	threads := r.db.Collection("project_comments")
+
+	filter, err := threadFilter(projectID, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	var thread models.Thread
+	if err := threads.FindOne(ctx, filter).Decode(&thread); err != nil {
+		return nil, err
+	}
+
+	return &thread, nil
+}
+
+// Agrega una respuesta al hilo y devuelve el hilo actualizado
+func (r *repo) AddThreadComment(ctx context.Context, projectID string, threadID string, comment models.ThreadComment) (*models.Thread, error) {
+	return r.updateThread(ctx, projectID, threadID, bson.M{
+		"$push": bson.M{"comments": comment},
+		"$set":  bson.M{"updatedAt": comment.CreatedAt},
+	})
+}
+
+// Marca el hilo como resuelto o lo vuelve a abrir y devuelve el hilo actualizado
+func (r *repo) SetThreadResolved(ctx context.Context, projectID string, threadID string, resolved bool, user string) (*models.Thread, error) {
+	update := bson.M{"$set": bson.M{"resolved": resolved, "resolvedBy": user, "updatedAt": time.Now().UTC()}}
+	if !resolved {
+		update = bson.M{
+			"$set":   bson.M{"resolved": false, "updatedAt": time.Now().UTC()},
+			"$unset": bson.M{"resolvedBy": ""},
+		}
+	}
+
+	return r.updateThread(ctx, projectID, threadID, update)
+}
+
+func (r *repo) updateThread(ctx context.Context, projectID string, threadID string, update bson.M) (*models.Thread, error) {
+	threads := r.db.Collection("project_comments")
+
+	filter, err := threadFilter(projectID, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	var thread models.Thread
+	err = threads.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&thread)
+	if err != nil {
+		return nil, err
+	}
+
+	return &thread, nil
+}
+
+func threadFilter(projectID string, threadID string) (bson.M, error) {
+	projectObjectID, err := primitive.ObjectIDFromHex(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	threadObjectID, err := primitive.ObjectIDFromHex(threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.M{"_id": threadObjectID, "projectId": projectObjectID}, nil
+}
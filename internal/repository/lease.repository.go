@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ProyectoT/api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrFenced indica que la sala se guardó con un lease que ya no es el vigente
+var ErrFenced = errors.New("the room was taken by another instance")
+
+// Toma o renueva el lease de la sala para owner. Si lo tiene otra instancia y
+// no venció, no lo cambia. Al tomarlo (no al renovarlo) aumenta la época y se
+// anota en el proyecto, así SaveRoom rechaza lo que guarde el dueño anterior.
+// Devuelve el lease vigente
+func (r *repo) AcquireRoomLease(ctx context.Context, roomID string, owner string, url string, ttl time.Duration) (*models.RoomLease, error) {
+	leases := r.db.Collection("room_leases")
+
+	now := time.Now().UTC()
+	expires := now.Add(ttl)
+
+	// Renovación: sigue siendo de owner y no venció
+	renewed, err := leases.UpdateOne(ctx,
+		bson.M{"_id": roomID, "owner": owner, "expiresAt": bson.M{"$gte": now}},
+		bson.M{"$set": bson.M{"url": url, "expiresAt": expires}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if renewed.MatchedCount == 0 {
+		filter := bson.M{"_id": roomID, "expiresAt": bson.M{"$lt": now}}
+		update := bson.M{
+			"$set": bson.M{"owner": owner, "url": url, "expiresAt": expires},
+			"$inc": bson.M{"epoch": 1},
+		}
+
+		// Con error de clave duplicada el lease es de otra instancia
+		_, err := leases.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+	}
+
+	var lease models.RoomLease
+	if err := leases.FindOne(ctx, bson.M{"_id": roomID}).Decode(&lease); err != nil {
+		return nil, err
+	}
+
+	if renewed.MatchedCount == 0 && lease.Owner == owner {
+		if err := r.fenceRoom(ctx, roomID, lease.Epoch); err != nil {
+			return nil, err
+		}
+	}
+
+	return &lease, nil
+}
+
+// fenceRoom anota en el proyecto la época del lease vigente
+func (r *repo) fenceRoom(ctx context.Context, roomID string, epoch int64) error {
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil // no es un proyecto, no hay nada que proteger
+	}
+
+	_, err = r.db.Collection("projects").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$max": bson.M{"leaseEpoch": epoch}})
+	return err
+}
+
+// Suelta el lease de la sala si todavía es de owner. Se marca vencido en vez de
+// borrarlo para no perder la época
+func (r *repo) ReleaseRoomLease(ctx context.Context, roomID string, owner string) error {
+	leases := r.db.Collection("room_leases")
+
+	_, err := leases.UpdateOne(ctx, bson.M{"_id": roomID, "owner": owner}, bson.M{"$set": bson.M{"expiresAt": time.Time{}}})
+	return err
+}
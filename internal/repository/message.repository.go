@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ProyectoT/api/internal/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Guarda un mensaje del chat de una sala
+func (r *repo) SaveMessage(ctx context.Context, message entity.Message) error {
+	messages := r.db.Collection("messages")
+
+	_, err := messages.InsertOne(ctx, message)
+	return err
+}
+
+// Obtiene los mensajes de una sala anteriores a before, o los últimos si
+// before es "". Se devuelven del más antiguo al más reciente
+func (r *repo) GetMessages(ctx context.Context, roomID string, before string, limit int) ([]entity.Message, error) {
+	messages := r.db.Collection("messages")
+
+	filter := bson.M{"room": roomID}
+	if before != "" {
+		beforeID, err := primitive.ObjectIDFromHex(before)
+		if err != nil {
+			return nil, err
+		}
+		filter["_id"] = bson.M{"$lt": beforeID}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit))
+
+	cursor, err := messages.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	list := []entity.Message{}
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+
+	return list, nil
+}
+
+// Guarda hasta qué mensaje leyó el usuario en la sala. Nunca retrocede
+func (r *repo) MarkMessagesRead(ctx context.Context, roomID string, user string, messageID string) error {
+	reads := r.db.Collection("message_reads")
+
+	objectID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return err
+	}
+
+	_, err = reads.UpdateOne(ctx,
+		bson.M{"room": roomID, "user": user},
+		bson.M{"$max": bson.M{"lastRead": objectID}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// Cuenta los mensajes de otros usuarios que el usuario todavía no leyó en la sala
+func (r *repo) CountUnreadMessages(ctx context.Context, roomID string, user string) (int64, error) {
+	reads := r.db.Collection("message_reads")
+	messages := r.db.Collection("messages")
+
+	filter := bson.M{"room": roomID, "author": bson.M{"$ne": user}}
+
+	var read struct {
+		LastRead primitive.ObjectID `bson:"lastRead"`
+	}
+
+	err := reads.FindOne(ctx, bson.M{"room": roomID, "user": user}).Decode(&read)
+	switch {
+	case err == nil:
+		filter["_id"] = bson.M{"$gt": read.LastRead}
+	case err != mongo.ErrNoDocuments:
+		return 0, err
+	}
+
+	return messages.CountDocuments(ctx, filter)
+}
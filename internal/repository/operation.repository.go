@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ProyectoT/api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Agrega una operación al registro del proyecto
+func (r *repo) SaveOperation(ctx context.Context, operation models.Operation) error {
+	operations := r.db.Collection("project_operations")
+
+	operation.ID = primitive.NilObjectID
+
+	_, err := operations.InsertOne(ctx, operation)
+	return err
+}
+
+// Obtiene las operaciones de un proyecto con seq mayor a afterSeq, en orden.
+// untilSeq y limit en 0 no ponen tope
+func (r *repo) GetOperations(ctx context.Context, projectID string, afterSeq int64, untilSeq int64, limit int) ([]models.Operation, error) {
+	operations := r.db.Collection("project_operations")
+
+	objectID, err := primitive.ObjectIDFromHex(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := bson.M{"$gt": afterSeq}
+	if untilSeq > 0 {
+		seq["$lte"] = untilSeq
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := operations.Find(ctx, bson.M{"projectId": objectID, "seq": seq}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	list := []models.Operation{}
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
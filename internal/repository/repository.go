@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/ProyectoT/api/internal/entity"
 	"github.com/ProyectoT/api/internal/models"
@@ -19,11 +20,31 @@ type Repository interface { //comunicaciones con la base de datos
 	GetMembers(ctx context.Context, roomID string) (*models.Members, error)                                                                                   // Devuelve los miembros de una sala
 	GetMembersAndPass(ctx context.Context, roomID string) (*models.Members, string, error)                                                                    // Devuelve los miembros y la contraseña de una sala
 	CreateRoom(ctx context.Context, roomName string, name string, correo string, desc string, location string, lat float64, long float64, visible bool) error // Crea una sala                                                                                                       // Guarda un nuevo proyecto en la base de datos
-	SaveRoom(ctx context.Context, data models.Project) error                                                                                                  // Actualiza un proyecto en MongoDB
+	SaveRoom(ctx context.Context, data models.Project) error                                                                                                  // Actualiza un proyecto en MongoDB, ErrFenced si lo tomó otra instancia
+	CreateProject(ctx context.Context, project models.Project) (string, error)                                                                                // Inserta un proyecto completo y devuelve su id
 	AddUserToProject(ctx context.Context, email string, role string, roomID string) error                                                                     // Guarda los usuarios en una sala en la base de datos
 	UpdateMembers(ctx context.Context, roomID string, members models.Members) error                                                                           // Actualiza los miembros de una sala en la base de datos
 	DeleteProject(ctx context.Context, roomID string) error                                                                                                   // Elimina un proyecto                                                                                                // Elimina un proyecto                                                                                                    // Elimina una sala de la base de datos
 
+	// Revisions - revision.repository.go
+	SaveRevision(ctx context.Context, revision models.Revision) (string, error)                                       // Guarda una copia del proyecto en el historial
+	GetRevisions(ctx context.Context, projectID string, page int, limit int) ([]models.RevisionInfo, int, int, error) // Devuelve las revisiones de un proyecto, de la más reciente a la más antigua
+	GetRevision(ctx context.Context, projectID string, revisionID string) (*models.Revision, error)                   // Devuelve una revisión completa
+
+	// Operations - operation.repository.go
+	SaveOperation(ctx context.Context, operation models.Operation) error                                                        // Agrega una operación al registro del proyecto
+	GetOperations(ctx context.Context, projectID string, afterSeq int64, untilSeq int64, limit int) ([]models.Operation, error) // Devuelve las operaciones de un proyecto en orden
+
+	// Messages - message.repository.go
+	SaveMessage(ctx context.Context, message entity.Message) error                                      // Guarda un mensaje del chat de una sala
+	GetMessages(ctx context.Context, roomID string, before string, limit int) ([]entity.Message, error) // Devuelve los mensajes de una sala anteriores a before
+	MarkMessagesRead(ctx context.Context, roomID string, user string, messageID string) error           // Guarda el último mensaje leído por el usuario
+	CountUnreadMessages(ctx context.Context, roomID string, user string) (int64, error)                 // Cuenta los mensajes que el usuario no leyó
+
+	// Leases - lease.repository.go
+	AcquireRoomLease(ctx context.Context, roomID string, owner string, url string, ttl time.Duration) (*models.RoomLease, error) // Toma o renueva la sala para una instancia
+	ReleaseRoomLease(ctx context.Context, roomID string, owner string) error                                                     // Suelta la sala
+
 	// Profile - profile.repository.go
 	GetProyects(ctx context.Context, email string, page int, limit int) ([]models.InfoProject, int, int, error) // Devuelve los proyectos de un usuario
 	//GetPermission(ctx context.Context, correo string, proyectID string) (int, error)
@@ -38,6 +59,11 @@ type Repository interface { //comunicaciones con la base de datos
 
 	// Comments - comments.repository.go
 	HandleAddComment(ctx context.Context, comment models.Comment) error
+	CreateThread(ctx context.Context, thread models.Thread) (string, error)                                                        // Crea un hilo de comentarios de un proyecto
+	GetThreads(ctx context.Context, projectID string, resolved *bool) ([]models.Thread, error)                                     // Devuelve los hilos de un proyecto
+	GetThread(ctx context.Context, projectID string, threadID string) (*models.Thread, error)                                      // Devuelve un hilo
+	AddThreadComment(ctx context.Context, projectID string, threadID string, comment models.ThreadComment) (*models.Thread, error) // Agrega una respuesta a un hilo
+	SetThreadResolved(ctx context.Context, projectID string, threadID string, resolved bool, user string) (*models.Thread, error)  // Resuelve o vuelve a abrir un hilo
 }
 type repo struct {
 	db *mongo.Database
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ProyectoT/api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Guarda una copia del proyecto en el historial
+func (r *repo) SaveRevision(ctx context.Context, revision models.Revision) (string, error) {
+	revisions := r.db.Collection("project_revisions")
+
+	revision.ID = primitive.NilObjectID
+
+	result, err := revisions.InsertOne(ctx, revision)
+	if err != nil {
+		return "", err
+	}
+
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// Obtiene las revisiones de un proyecto, de la más reciente a la más antigua
+func (r *repo) GetRevisions(ctx context.Context, projectID string, page int, limit int) ([]models.RevisionInfo, int, int, error) {
+	revisions := r.db.Collection("project_revisions")
+
+	objectID, err := primitive.ObjectIDFromHex(projectID)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	filter := bson.M{"projectId": objectID}
+
+	totalCount, err := revisions.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$sort": bson.M{"createdAt": -1, "_id": -1}},
+		{"$skip": int64((page - 1) * limit)},
+		{"$limit": int64(limit)},
+		{"$project": bson.M{
+			"projectId": 1,
+			"author":    1,
+			"reason":    1,
+			"createdAt": 1,
+			"layers":    bson.M{"$size": bson.M{"$ifNull": []interface{}{"$project.data", []interface{}{}}}},
+		}},
+	}
+
+	cursor, err := revisions.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	list := []models.RevisionInfo{}
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, 0, 0, err
+	}
+
+	totalPages := int((totalCount + int64(limit) - 1) / int64(limit))
+
+	return list, page, totalPages, nil
+}
+
+// Obtiene una revisión completa de un proyecto
+func (r *repo) GetRevision(ctx context.Context, projectID string, revisionID string) (*models.Revision, error) {
+	revisions := r.db.Collection("project_revisions")
+
+	projectObjectID, err := primitive.ObjectIDFromHex(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisionObjectID, err := primitive.ObjectIDFromHex(revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var revision models.Revision
+
+	err = revisions.FindOne(ctx, bson.M{"_id": revisionObjectID, "projectId": projectObjectID}, options.FindOne()).Decode(&revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
@@ -80,22 +80,7 @@ func (r *repo) CreateRoom(ctx context.Context, roomName string, name string, cor
 		Fosil:    map[string]models.Fosil{},
 		Muestras: map[string]models.Muestra{},
 		Facies:   map[string][]models.FaciesSection{},
-		Config: models.Config{
-			Columns: []models.Column{
-				{Name: "Sistema", Visible: true, Removable: true},
-				{Name: "Edad", Visible: true, Removable: true},
-				{Name: "Formacion", Visible: true, Removable: true},
-				{Name: "Miembro", Visible: true, Removable: true},
-				{Name: "Espesor", Visible: true, Removable: false},
-				{Name: "Litologia", Visible: true, Removable: false},
-				{Name: "Estructura fosil", Visible: true, Removable: false},
-				{Name: "Facie", Visible: true, Removable: false},
-				{Name: "Muestras", Visible: true, Removable: false},
-				{Name: "AmbienteDepositacional", Visible: true, Removable: true},
-				{Name: "Descripcion", Visible: true, Removable: true},
-			},
-			IsInverted: false,
-		},
+		Config:   models.DefaultConfig(),
 	}
 
 	count, err := rooms.CountDocuments(ctx, bson.M{"name": roomName, "members.0": correo})
@@ -117,6 +102,26 @@ func (r *repo) CreateRoom(ctx context.Context, roomName string, name string, cor
 	return nil
 }
 
+// Inserta un proyecto ya armado (importaciones, copias) y devuelve su id
+func (r *repo) CreateProject(ctx context.Context, project models.Project) (string, error) {
+	rooms := r.db.Collection("projects")
+
+	project.ID = primitive.NilObjectID
+
+	res, err := rooms.InsertOne(ctx, project)
+	if err != nil {
+		log.Println("Error creating project:", err)
+		return "", err
+	}
+
+	id, ok := res.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return "", fmt.Errorf("unexpected inserted id %v", res.InsertedID)
+	}
+
+	return id.Hex(), nil
+}
+
 // Elimina una sala
 func (r *repo) DeleteProject(ctx context.Context, roomID string) error {
 	dbProject := r.db.Collection("projects")
@@ -142,7 +147,12 @@ func (r *repo) DeleteProject(ctx context.Context, roomID string) error {
 func (r *repo) SaveRoom(ctx context.Context, data models.Project) error {
 	rooms := r.db.Collection("projects")
 
-	filter := bson.M{"_id": data.ID}
+	// Solo si el proyecto no se volvió a abrir con un lease más nuevo, ver
+	// AcquireRoomLease
+	filter := bson.M{"_id": data.ID, "$or": []bson.M{
+		{"leaseEpoch": bson.M{"$lte": data.LeaseEpoch}},
+		{"leaseEpoch": bson.M{"$exists": false}},
+	}}
 	update := bson.M{"$set": bson.M{
 		"projectinfo": data.ProjectInfo,
 		"data":        data.Data,
@@ -150,12 +160,19 @@ func (r *repo) SaveRoom(ctx context.Context, data models.Project) error {
 		"fosil":       data.Fosil,
 		"facies":      data.Facies,
 		"muestras":    data.Muestras,
+		"logs":        data.Logs,
 		"shared":      data.Shared,
+		"seq":         data.Seq,
+		"history":     data.History,
 	}}
 
 	opts := options.Update().SetUpsert(true)
 
 	_, err := rooms.UpdateOne(ctx, filter, update, opts)
+	if mongo.IsDuplicateKeyError(err) {
+		// El proyecto existe pero no coincidió la época
+		return ErrFenced
+	}
 	if err != nil {
 		log.Println("Error updating room:", err)
 		return err
@@ -7,6 +7,7 @@ import (
 
 	"github.com/ProyectoT/api/database"
 	"github.com/ProyectoT/api/internal/api"
+	"github.com/ProyectoT/api/internal/pubsub"
 	"github.com/ProyectoT/api/internal/repository"
 	"github.com/ProyectoT/api/internal/service"
 	"github.com/ProyectoT/api/settings"
@@ -32,6 +33,7 @@ func main() {
 			database.New,
 			repository.New,
 			service.New,
+			pubsub.New,
 			api.New,
 			echo.New,
 		),
@@ -50,7 +52,8 @@ func setLifeCycle(lc fx.Lifecycle, a *api.API, s *settings.Settings, e *echo.Ech
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
-			return nil
+			// Guarda las salas abiertas y cierra el servidor
+			return a.Shutdown(ctx, e)
 		},
 	})
 }
@@ -2,6 +2,8 @@ package settings
 
 import (
 	"os"
+
+	"github.com/lithammer/shortuuid/v4"
 )
 
 type Settings struct {
@@ -9,6 +11,11 @@ type Settings struct {
 	DB   string `yaml:"database"`
 	Name string `yaml:"dbname"`
 	Key  string `yaml:"key"`
+
+	// Identificación de esta instancia cuando hay varias, ver api/cluster.go
+	InstanceID  string `yaml:"instanceId"`
+	InstanceURL string `yaml:"instanceUrl"`
+	Broker      string `yaml:"broker"` // "local" (una instancia) o "mongo", ver pubsub
 }
 
 func New() (*Settings, error) {
@@ -19,11 +26,21 @@ func New() (*Settings, error) {
 	dbname := os.Getenv("DBNAME")
 	key := os.Getenv("KEYPWD")
 
+	instanceID := os.Getenv("INSTANCE_ID")
+	if instanceID == "" {
+		hostname, _ := os.Hostname()
+		instanceID = hostname + "-" + shortuuid.New()
+	}
+
 	s = Settings{
 		Port: port,
 		DB:   db,
 		Name: dbname,
 		Key:  key,
+
+		InstanceID:  instanceID,
+		InstanceURL: os.Getenv("INSTANCE_URL"),
+		Broker:      os.Getenv("BROKER"),
 	}
 
 	return &s, nil